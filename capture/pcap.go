@@ -0,0 +1,320 @@
+package capture
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+	"time"
+)
+
+// pcap file format, draft-ietf-opsawg-pcap
+/*
+                           1                   2                   3
+       0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+    0 |                          Magic Number                         |
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+    4 |          Major Version        |         Minor Version         |
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+    8 |                           Reserved1                           |
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   12 |                           Reserved2                           |
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   16 |                            SnapLen                            |
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   20 | FCS |f|                   LinkType                            |
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+   Packet Record:
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+    0 |                      Timestamp (Seconds)                      |
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+    4 |            Timestamp (Microseconds or nanoseconds)            |
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+    8 |                    Captured Packet Length                     |
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   12 |                    Original Packet Length                     |
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   16 /                          Packet Data                          /
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+const (
+	PcapMagicMicroseconds = 0xA1B2C3D4
+	PcapMagicNanoseconds  = 0xA1B23C4D
+	PcapFileHeaderLength  = 24
+	PcapRecordLength      = 16
+	PcapMaxSnapLen        = 262144
+
+	// pcapng, draft-ietf-opsawg-pcapng
+	PcapngBlockSHB       = 0x0A0D0D0A // Section Header Block
+	PcapngBlockIDB       = 0x00000001 // Interface Description Block
+	PcapngBlockSPB       = 0x00000003 // Simple Packet Block
+	PcapngBlockEPB       = 0x00000006 // Enhanced Packet Block
+	PcapngByteOrderMagic = 0x1A2B3C4D
+	pcapngOptionEnd      = 0
+	pcapngOptionTsresol  = 9 // if_tsresol
+)
+
+// http://www.tcpdump.org/linktypes.html
+const (
+	PCAP_LINKTYPE_NULL      = 0   // BSD loopback, 4-byte host byte order protocol family
+	PCAP_LINKTYPE_ETHERNET  = 1   // IEEE 802.3 Ethernet
+	PCAP_LINKTYPE_RAW       = 101 // raw IPv4/IPv6
+	PCAP_LINKTYPE_LINUX_SLL = 113 // Linux "cooked" capture
+)
+
+type pcapInterface struct {
+	linktype int
+	tsresol  time.Duration // timestamp unit, 0 if finer than 1ns
+	tsdiv    uint64        // timestamp units per second when tsresol is 0
+}
+
+// PcapReader read link-layer frames from pcap or pcapng file
+type PcapReader struct {
+	r          io.Reader
+	order      binary.ByteOrder
+	ng         bool
+	interfaces []pcapInterface // pcap: interfaces[0] from file header
+	buf        []byte
+}
+
+// NewPcapReader detect pcap/pcapng by magic number and read file header
+func NewPcapReader(r io.Reader) (*PcapReader, error) {
+	reader := &PcapReader{r: r}
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, errors.New("pcap file header error.")
+	}
+
+	if binary.BigEndian.Uint32(magic[:]) == PcapngBlockSHB {
+		reader.ng = true
+		var length [4]byte
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			return nil, errors.New("pcapng section header error.")
+		}
+		if err := reader.readSection(length[:]); err != nil {
+			return nil, err
+		}
+		return reader, nil
+	}
+
+	var hdr [PcapFileHeaderLength]byte
+	copy(hdr[:], magic[:])
+	if _, err := io.ReadFull(r, hdr[4:]); err != nil {
+		return nil, errors.New("pcap file header error.")
+	}
+	iface := pcapInterface{tsresol: time.Microsecond}
+	switch {
+	case binary.LittleEndian.Uint32(hdr[:]) == PcapMagicMicroseconds:
+		reader.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(hdr[:]) == PcapMagicMicroseconds:
+		reader.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(hdr[:]) == PcapMagicNanoseconds:
+		reader.order, iface.tsresol = binary.LittleEndian, time.Nanosecond
+	case binary.BigEndian.Uint32(hdr[:]) == PcapMagicNanoseconds:
+		reader.order, iface.tsresol = binary.BigEndian, time.Nanosecond
+	default:
+		return nil, errors.New("pcap magic error.")
+	}
+	iface.linktype = int(reader.order.Uint32(hdr[20:]) & 0x0FFFFFFF)
+	reader.interfaces = append(reader.interfaces, iface)
+	return reader, nil
+}
+
+// ReadFrame read next link-layer frame
+// @return linktype PCAP_LINKTYPE_XXX
+// @return data frame data, valid until next ReadFrame call, io.EOF-end of file
+func (r *PcapReader) ReadFrame() (linktype int, timestamp time.Time, data []byte, err error) {
+	if r.ng {
+		return r.readBlock()
+	}
+
+	var hdr [PcapRecordLength]byte
+	if _, err = io.ReadFull(r.r, hdr[:]); err != nil {
+		return 0, timestamp, nil, err
+	}
+	caplen := r.order.Uint32(hdr[8:])
+	if caplen > PcapMaxSnapLen {
+		return 0, timestamp, nil, errors.New("pcap packet length error.")
+	}
+	if data, err = r.read(int(caplen)); err != nil {
+		return 0, timestamp, nil, err
+	}
+	iface := &r.interfaces[0]
+	timestamp = time.Unix(int64(r.order.Uint32(hdr[0:])), int64(r.order.Uint32(hdr[4:]))*int64(iface.tsresol))
+	return iface.linktype, timestamp, data, nil
+}
+
+// pcapng 4.1. Section Header Block, after Block Type
+/*
+                           1                   2                   3
+       0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+      +---------------------------------------------------------------+
+    0 |                   Block Type = 0x0A0D0D0A                     |
+      +---------------------------------------------------------------+
+    4 |                      Block Total Length                       |
+      +---------------------------------------------------------------+
+    8 |                      Byte-Order Magic                         |
+      +---------------------------------------------------------------+
+   12 |          Major Version        |         Minor Version         |
+      +---------------------------------------------------------------+
+   16 |                                                               |
+      |                          Section Length                       |
+      |                                                               |
+      +---------------------------------------------------------------+
+   24 /                                                               /
+      /                      Options (variable)                       /
+      /                                                               /
+      +---------------------------------------------------------------+
+      |                      Block Total Length                       |
+      +---------------------------------------------------------------+
+*/
+// @param[in] total Block Total Length field, byte order unknown before Byte-Order Magic
+func (r *PcapReader) readSection(total []byte) error {
+	var magic [4]byte
+	if _, err := io.ReadFull(r.r, magic[:]); err != nil {
+		return errors.New("pcapng section header error.")
+	}
+	switch {
+	case binary.LittleEndian.Uint32(magic[:]) == PcapngByteOrderMagic:
+		r.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(magic[:]) == PcapngByteOrderMagic:
+		r.order = binary.BigEndian
+	default:
+		return errors.New("pcapng byte-order magic error.")
+	}
+
+	length := r.order.Uint32(total)
+	if length < 28 || length%4 != 0 || length > PcapMaxSnapLen {
+		return errors.New("pcapng section header error.")
+	}
+	if _, err := r.read(int(length) - 12); err != nil {
+		return err
+	}
+	r.interfaces = r.interfaces[:0] // interface id scope is section
+	return nil
+}
+
+func (r *PcapReader) readBlock() (int, time.Time, []byte, error) {
+	var timestamp time.Time
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r.r, hdr[:]); err != nil {
+			return 0, timestamp, nil, err
+		}
+		if binary.BigEndian.Uint32(hdr[:4]) == PcapngBlockSHB {
+			// new section, may change byte order
+			if err := r.readSection(hdr[4:]); err != nil {
+				return 0, timestamp, nil, err
+			}
+			continue
+		}
+
+		typ := r.order.Uint32(hdr[:4])
+		length := r.order.Uint32(hdr[4:])
+		if length < 12 || length%4 != 0 || length > PcapMaxSnapLen {
+			return 0, timestamp, nil, errors.New("pcapng block length error.")
+		}
+		body, err := r.read(int(length) - 8)
+		if err != nil {
+			return 0, timestamp, nil, err
+		}
+		body = body[:len(body)-4] // trailing Block Total Length
+
+		switch typ {
+		case PcapngBlockIDB:
+			if len(body) < 8 {
+				return 0, timestamp, nil, errors.New("pcapng interface block error.")
+			}
+			iface, err := r.parseInterface(body)
+			if err != nil {
+				return 0, timestamp, nil, err
+			}
+			r.interfaces = append(r.interfaces, iface)
+
+		case PcapngBlockEPB:
+			// Interface ID, Timestamp (High), Timestamp (Low), Captured Packet Length, Original Packet Length
+			if len(body) < 20 {
+				return 0, timestamp, nil, errors.New("pcapng packet block error.")
+			}
+			id := int(r.order.Uint32(body[0:]))
+			caplen := int(r.order.Uint32(body[12:]))
+			if id >= len(r.interfaces) || 20+caplen > len(body) {
+				return 0, timestamp, nil, errors.New("pcapng packet block error.")
+			}
+			iface := &r.interfaces[id]
+			ts := uint64(r.order.Uint32(body[4:]))<<32 | uint64(r.order.Uint32(body[8:]))
+			if iface.tsresol > 0 {
+				timestamp = time.Unix(0, 0).Add(time.Duration(ts) * iface.tsresol)
+			} else {
+				// (ts % tsdiv) * 1e9 overflow 64 bits if tsdiv > 2^34
+				hi, lo := bits.Mul64(ts%iface.tsdiv, uint64(time.Second))
+				nsec, _ := bits.Div64(hi, lo, iface.tsdiv)
+				timestamp = time.Unix(int64(ts/iface.tsdiv), int64(nsec))
+			}
+			return iface.linktype, timestamp, body[20 : 20+caplen], nil
+
+		case PcapngBlockSPB:
+			// Original Packet Length, no timestamp, interface 0
+			if len(r.interfaces) < 1 || len(body) < 4 {
+				return 0, timestamp, nil, errors.New("pcapng packet block error.")
+			}
+			caplen := int(r.order.Uint32(body[0:]))
+			if caplen > len(body)-4 {
+				caplen = len(body) - 4
+			}
+			return r.interfaces[0].linktype, timestamp, body[4 : 4+caplen], nil
+		}
+		// skip other blocks: Name Resolution, Interface Statistics, ...
+	}
+}
+
+// parseInterface LinkType, Reserved, SnapLen, Options
+func (r *PcapReader) parseInterface(body []byte) (pcapInterface, error) {
+	iface := pcapInterface{linktype: int(r.order.Uint16(body)), tsresol: time.Microsecond}
+	for opts := body[8:]; len(opts) >= 4; {
+		code := r.order.Uint16(opts)
+		n := int(r.order.Uint16(opts[2:]))
+		if code == pcapngOptionEnd || 4+n > len(opts) {
+			break
+		}
+		if code == pcapngOptionTsresol && n >= 1 {
+			// MSB 0: 10^-v, MSB 1: 2^-v
+			v := opts[4]
+			div := uint64(1)
+			if v&0x80 != 0 {
+				if v&0x7F > 63 {
+					return iface, errors.New("pcapng interface block error.")
+				}
+				div <<= v & 0x7F
+			} else {
+				if v > 19 {
+					return iface, errors.New("pcapng interface block error.")
+				}
+				for i := byte(0); i < v; i++ {
+					div *= 10
+				}
+			}
+			iface.tsresol, iface.tsdiv = 0, div
+			if uint64(time.Second)%div == 0 {
+				iface.tsresol = time.Second / time.Duration(div)
+			}
+		}
+		opts = opts[4+(n+3)/4*4:]
+	}
+	return iface, nil
+}
+
+func (r *PcapReader) read(n int) ([]byte, error) {
+	if cap(r.buf) < n {
+		r.buf = make([]byte, n)
+	}
+	if _, err := io.ReadFull(r.r, r.buf[:n]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return r.buf[:n], nil
+}
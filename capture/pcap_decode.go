@@ -0,0 +1,237 @@
+package capture
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/services-go/librtp/rtp"
+	"github.com/services-go/librtp/transport"
+	"net"
+	"time"
+)
+
+const (
+	pcapEthernetHeaderLength = 14
+	pcapSllHeaderLength      = 16
+	pcapIPv4HeaderLength     = 20
+	pcapIPv6HeaderLength     = 40
+	pcapUdpHeaderLength      = 8
+
+	pcapEtherTypeIPv4 = 0x0800
+	pcapEtherTypeIPv6 = 0x86DD
+	pcapEtherTypeVlan = 0x8100
+	pcapEtherTypeQinQ = 0x88A8
+	pcapProtocolUdp   = 17
+)
+
+var errPcapNotUdp = errors.New("pcap frame not udp.")
+
+// PcapPacket UDP datagram from capture
+type PcapPacket struct {
+	Timestamp time.Time // capture time
+	Src       *net.UDPAddr
+	Dst       *net.UDPAddr
+	Data      []byte // UDP payload, valid until next read
+}
+
+// PcapFilter match UDP 5-tuple and RTP SSRC, zero value match all
+type PcapFilter struct {
+	Src  *net.UDPAddr // nil-any, IP nil-any address, Port 0-any port
+	Dst  *net.UDPAddr
+	SSRC []uint32 // RTP SSRC, empty-any
+}
+
+func (f *PcapFilter) Match(pkt *PcapPacket) bool {
+	if !pcapMatchAddr(f.Src, pkt.Src) || !pcapMatchAddr(f.Dst, pkt.Dst) {
+		return false
+	}
+	if len(f.SSRC) == 0 {
+		return true
+	}
+	if len(pkt.Data) < rtp.RtpFixedHeader {
+		return false
+	}
+	ssrc := rtp.RtpReadUint32(pkt.Data[8:])
+	for _, v := range f.SSRC {
+		if v == ssrc {
+			return true
+		}
+	}
+	return false
+}
+
+func pcapMatchAddr(filter, addr *net.UDPAddr) bool {
+	if filter == nil {
+		return true
+	}
+	if filter.Port != 0 && filter.Port != addr.Port {
+		return false
+	}
+	return filter.IP == nil || filter.IP.Equal(addr.IP)
+}
+
+// ReadPacket read next UDP datagram, skip non-UDP frames and IP fragments
+// @return io.EOF-end of file
+func (r *PcapReader) ReadPacket() (*PcapPacket, error) {
+	for {
+		linktype, timestamp, frame, err := r.ReadFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		src, dst, data, err := PcapDecodeUdp(linktype, frame)
+		if err == nil {
+			return &PcapPacket{Timestamp: timestamp, Src: src, Dst: dst, Data: data}, nil
+		}
+	}
+}
+
+// ReadRtp read next RTP packet(RTP version 2, not RTCP) matched filter
+// @param[in] filter nil-any RTP packet
+// @return io.EOF-end of file
+func (r *PcapReader) ReadRtp(filter *PcapFilter) (*PcapPacket, error) {
+	for {
+		pkt, err := r.ReadPacket()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(pkt.Data) < rtp.RtpFixedHeader || rtp.RTP_V(rtp.RtpReadUint32(pkt.Data)) != rtp.RtpVersion ||
+			transport.RtcpMuxIsRtcp(pkt.Data, len(pkt.Data)) {
+			continue
+		}
+		if filter == nil || filter.Match(pkt) {
+			return pkt, nil
+		}
+	}
+}
+
+// PcapDecodeUdp decode link-layer frame to UDP datagram
+// @param[in] linktype PCAP_LINKTYPE_XXX
+// @param[in] frame link-layer frame
+// @return src/dst address and UDP payload
+func PcapDecodeUdp(linktype int, frame []byte) (src, dst *net.UDPAddr, data []byte, err error) {
+	var ethertype uint16
+	switch linktype {
+	case PCAP_LINKTYPE_ETHERNET:
+		if len(frame) < pcapEthernetHeaderLength {
+			return nil, nil, nil, errPcapNotUdp
+		}
+		ethertype = rtp.RtpReadUint16(frame[12:])
+		frame = frame[pcapEthernetHeaderLength:]
+		for (ethertype == pcapEtherTypeVlan || ethertype == pcapEtherTypeQinQ) && len(frame) >= 4 {
+			ethertype = rtp.RtpReadUint16(frame[2:])
+			frame = frame[4:]
+		}
+
+	case PCAP_LINKTYPE_LINUX_SLL:
+		if len(frame) < pcapSllHeaderLength {
+			return nil, nil, nil, errPcapNotUdp
+		}
+		ethertype = rtp.RtpReadUint16(frame[14:])
+		frame = frame[pcapSllHeaderLength:]
+
+	case PCAP_LINKTYPE_NULL:
+		// protocol family in capture host byte order, AF_INET=2, AF_INET6=24/28/30
+		if len(frame) < 4 {
+			return nil, nil, nil, errPcapNotUdp
+		}
+		family := binary.LittleEndian.Uint32(frame)
+		if family > 0xFFFF {
+			family = binary.BigEndian.Uint32(frame)
+		}
+		ethertype = pcapEtherTypeIPv6
+		if family == 2 {
+			ethertype = pcapEtherTypeIPv4
+		}
+		frame = frame[4:]
+
+	case PCAP_LINKTYPE_RAW:
+		if len(frame) < 1 {
+			return nil, nil, nil, errPcapNotUdp
+		}
+		ethertype = pcapEtherTypeIPv6
+		if frame[0]>>4 == 4 {
+			ethertype = pcapEtherTypeIPv4
+		}
+
+	default:
+		return nil, nil, nil, errors.New("pcap linktype unsupported.")
+	}
+
+	var srcIP, dstIP net.IP
+	var udp []byte
+	switch ethertype {
+	case pcapEtherTypeIPv4:
+		srcIP, dstIP, udp, err = pcapDecodeIPv4(frame)
+	case pcapEtherTypeIPv6:
+		srcIP, dstIP, udp, err = pcapDecodeIPv6(frame)
+	default:
+		err = errPcapNotUdp
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// RFC768 User Datagram Protocol
+	if len(udp) < pcapUdpHeaderLength {
+		return nil, nil, nil, errPcapNotUdp
+	}
+	n := int(rtp.RtpReadUint16(udp[4:]))
+	if n < pcapUdpHeaderLength {
+		return nil, nil, nil, errPcapNotUdp
+	}
+	if n > len(udp) {
+		n = len(udp) // snaplen truncated
+	}
+	src = &net.UDPAddr{IP: srcIP, Port: int(rtp.RtpReadUint16(udp))}
+	dst = &net.UDPAddr{IP: dstIP, Port: int(rtp.RtpReadUint16(udp[2:]))}
+	return src, dst, udp[pcapUdpHeaderLength:n], nil
+}
+
+// RFC791 3.1. Internet Header Format
+func pcapDecodeIPv4(ip []byte) (src, dst net.IP, payload []byte, err error) {
+	if len(ip) < pcapIPv4HeaderLength || ip[0]>>4 != 4 {
+		return nil, nil, nil, errPcapNotUdp
+	}
+	ihl := int(ip[0]&0x0F) * 4
+	total := int(rtp.RtpReadUint16(ip[2:]))
+	if ihl < pcapIPv4HeaderLength || total < ihl || len(ip) < ihl {
+		return nil, nil, nil, errPcapNotUdp
+	}
+	if ip[9] != pcapProtocolUdp || rtp.RtpReadUint16(ip[6:])&0x3FFF != 0 {
+		return nil, nil, nil, errPcapNotUdp // MF flag or fragment offset
+	}
+	if total > len(ip) {
+		total = len(ip)
+	}
+	return pcapCopyIP(ip[12:16]), pcapCopyIP(ip[16:20]), ip[ihl:total], nil
+}
+
+// RFC8200 3. IPv6 Header Format, 4. IPv6 Extension Headers
+func pcapDecodeIPv6(ip []byte) (src, dst net.IP, payload []byte, err error) {
+	if len(ip) < pcapIPv6HeaderLength || ip[0]>>4 != 6 {
+		return nil, nil, nil, errPcapNotUdp
+	}
+	next := ip[6]
+	payload = ip[pcapIPv6HeaderLength:]
+	if n := int(rtp.RtpReadUint16(ip[4:])); n < len(payload) {
+		payload = payload[:n]
+	}
+	for next != pcapProtocolUdp {
+		switch next {
+		case 0, 43, 60: // Hop-by-Hop Options, Routing, Destination Options
+			if len(payload) < 8 || len(payload) < (int(payload[1])+1)*8 {
+				return nil, nil, nil, errPcapNotUdp
+			}
+			next = payload[0]
+			payload = payload[(int(payload[1])+1)*8:]
+		default: // Fragment, no next header, other protocols
+			return nil, nil, nil, errPcapNotUdp
+		}
+	}
+	return pcapCopyIP(ip[8:24]), pcapCopyIP(ip[24:40]), payload, nil
+}
+
+func pcapCopyIP(ip []byte) net.IP {
+	return append(net.IP(nil), ip...)
+}
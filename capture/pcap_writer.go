@@ -0,0 +1,173 @@
+package capture
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/services-go/librtp/rtp"
+	"io"
+	"net"
+	"time"
+)
+
+const pcapWriterSnapLen = 65535
+
+// PcapWriter write UDP datagrams as Ethernet frames to pcap file, microsecond timestamp
+type PcapWriter struct {
+	w   io.Writer
+	buf []byte
+	id  uint16 // IPv4 identification
+}
+
+// NewPcapWriter write pcap file header, little-endian, PCAP_LINKTYPE_ETHERNET
+func NewPcapWriter(w io.Writer) (*PcapWriter, error) {
+	var hdr [PcapFileHeaderLength]byte
+	binary.LittleEndian.PutUint32(hdr[0:], PcapMagicMicroseconds)
+	binary.LittleEndian.PutUint16(hdr[4:], 2) // version 2.4
+	binary.LittleEndian.PutUint16(hdr[6:], 4)
+	binary.LittleEndian.PutUint32(hdr[16:], pcapWriterSnapLen)
+	binary.LittleEndian.PutUint32(hdr[20:], PCAP_LINKTYPE_ETHERNET)
+	if _, err := w.Write(hdr[:]); err != nil {
+		return nil, err
+	}
+	return &PcapWriter{w: w}, nil
+}
+
+// WritePacket write UDP datagram with synthesized Ethernet/IP/UDP headers
+// @param[in] timestamp capture time
+// @param[in] src/dst UDP address, both IPv4 or both IPv6
+// @param[in] data UDP payload, e.g. RTP/RTCP packet
+// @param[in] bytes payload length in bytes
+func (w *PcapWriter) WritePacket(timestamp time.Time, src, dst *net.UDPAddr, data []byte, bytes int) error {
+	if bytes < 0 || bytes > len(data) {
+		return errors.New("pcap packet length error.")
+	}
+
+	src4, dst4 := src.IP.To4(), dst.IP.To4()
+	ipv4 := src4 != nil && dst4 != nil
+	if !ipv4 && (src4 != nil || dst4 != nil || len(src.IP) != net.IPv6len || len(dst.IP) != net.IPv6len) {
+		return errors.New("pcap address family error.")
+	}
+	udplen := pcapUdpHeaderLength + bytes
+	iplen := pcapIPv6HeaderLength
+	if ipv4 {
+		iplen = pcapIPv4HeaderLength
+	}
+	n := pcapEthernetHeaderLength + iplen + udplen
+	if n > pcapWriterSnapLen {
+		return errors.New("pcap packet too large.")
+	}
+
+	if cap(w.buf) < PcapRecordLength+n {
+		w.buf = make([]byte, PcapRecordLength+n)
+	}
+	w.buf = w.buf[:PcapRecordLength+n]
+	for i := range w.buf[:PcapRecordLength+n-bytes] {
+		w.buf[i] = 0
+	}
+	rec := w.buf[:PcapRecordLength]
+	binary.LittleEndian.PutUint32(rec[0:], uint32(timestamp.Unix()))
+	binary.LittleEndian.PutUint32(rec[4:], uint32(timestamp.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(rec[8:], uint32(n))
+	binary.LittleEndian.PutUint32(rec[12:], uint32(n))
+
+	// Ethernet, locally administered MAC addresses
+	eth := w.buf[PcapRecordLength:]
+	copy(eth[0:6], []byte{0x02, 0, 0, 0, 0, 0x02})
+	copy(eth[6:12], []byte{0x02, 0, 0, 0, 0, 0x01})
+	ip := eth[pcapEthernetHeaderLength:]
+	udp := ip[iplen:]
+	if ipv4 {
+		rtp.RtpWriteUint16(eth[12:], pcapEtherTypeIPv4)
+		ip[0] = 0x45
+		rtp.RtpWriteUint16(ip[2:], uint16(iplen+udplen))
+		rtp.RtpWriteUint16(ip[4:], w.id)
+		rtp.RtpWriteUint16(ip[6:], 0x4000) // don't fragment
+		ip[8] = 64
+		ip[9] = pcapProtocolUdp
+		copy(ip[12:16], src4)
+		copy(ip[16:20], dst4)
+		rtp.RtpWriteUint16(ip[10:], ^uint16(pcapChecksum(0, ip[:pcapIPv4HeaderLength])))
+		w.id++
+	} else {
+		rtp.RtpWriteUint16(eth[12:], pcapEtherTypeIPv6)
+		ip[0] = 0x60
+		rtp.RtpWriteUint16(ip[4:], uint16(udplen))
+		ip[6] = pcapProtocolUdp
+		ip[7] = 64
+		copy(ip[8:24], src.IP)
+		copy(ip[24:40], dst.IP)
+	}
+
+	// RFC768 User Datagram Protocol, checksum with pseudo header
+	rtp.RtpWriteUint16(udp[0:], uint16(src.Port))
+	rtp.RtpWriteUint16(udp[2:], uint16(dst.Port))
+	rtp.RtpWriteUint16(udp[4:], uint16(udplen))
+	copy(udp[pcapUdpHeaderLength:], data[:bytes])
+
+	var pseudo [4]byte
+	rtp.RtpWriteUint16(pseudo[0:], pcapProtocolUdp)
+	rtp.RtpWriteUint16(pseudo[2:], uint16(udplen))
+	var sum uint32
+	if ipv4 {
+		sum = pcapChecksum(pcapChecksum(0, ip[12:20]), pseudo[:])
+	} else {
+		sum = pcapChecksum(pcapChecksum(0, ip[8:40]), pseudo[:])
+	}
+	checksum := ^uint16(pcapChecksum(sum, udp[:udplen]))
+	if checksum == 0 {
+		checksum = 0xFFFF
+	}
+	rtp.RtpWriteUint16(udp[6:], checksum)
+
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+// pcapChecksum RFC1071 one's complement sum, folded to 16 bits
+func pcapChecksum(sum uint32, data []byte) uint32 {
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(data[i])<<8 | uint32(data[i+1])
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum > 0xFFFF {
+		sum = sum&0xFFFF + sum>>16
+	}
+	return sum
+}
+
+// PcapRtpHandler RtpPayload packer handler, write RTP packets to pcap file
+// capture time from RTP timestamp: Start + (timestamp - first timestamp) / Frequency
+type PcapRtpHandler struct {
+	Err error // first write error
+
+	writer    *PcapWriter
+	src, dst  *net.UDPAddr
+	frequency int
+	start     time.Time
+	first     uint32
+	started   bool
+}
+
+func NewPcapRtpHandler(writer *PcapWriter, src, dst *net.UDPAddr, frequency int, start time.Time) *PcapRtpHandler {
+	return &PcapRtpHandler{writer: writer, src: src, dst: dst, frequency: frequency, start: start}
+}
+
+func (h *PcapRtpHandler) Alloc(param interface{}, bytes int) []byte {
+	return make([]byte, bytes)
+}
+
+func (h *PcapRtpHandler) Free(param interface{}, packet []byte) {
+}
+
+func (h *PcapRtpHandler) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
+	if !h.started {
+		h.started = true
+		h.first = timestamp
+	}
+	ts := h.start.Add(rtp.RtpTimestampToDuration(int64(int32(timestamp-h.first)), h.frequency))
+	if err := h.writer.WritePacket(ts, h.src, h.dst, packet, bytes); err != nil && h.Err == nil {
+		h.Err = err
+	}
+}
@@ -0,0 +1,182 @@
+package capture
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/services-go/librtp/rtp"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rtptools rtpdump file format
+/*
+   #!rtpplay1.0 address/port\n
+
+   typedef struct {
+     struct timeval start;  // start of recording (GMT)
+     uint32_t source;       // network source (multicast address)
+     uint16_t port;         // UDP port
+     uint16_t padding;      // padding
+   } RD_hdr_t;
+
+   typedef struct {
+     uint16_t length;       // length of packet, including this header (may be smaller than plen if not whole packet recorded)
+     uint16_t plen;         // actual header+payload length for RTP, 0 for RTCP
+     uint32_t offset;       // milliseconds since the start of recording
+   } RD_packet_t;
+*/
+const (
+	RtpDumpMagic              = "#!rtpplay1.0"
+	RtpDumpFileHeaderLength   = 16
+	RtpDumpPacketHeaderLength = 8
+)
+
+type RtpDumpHeader struct {
+	Start   time.Time // start of recording
+	Address net.IP    // network source
+	Port    uint16    // UDP port
+}
+
+type RtpDumpPacket struct {
+	Offset time.Duration // since the start of recording, millisecond precision
+	Rtcp   bool          // plen == 0
+	Length int           // original RTP packet length, Length > len(Data) if not whole packet recorded
+	Data   []byte
+}
+
+type RtpDumpReader struct {
+	r      *bufio.Reader
+	header RtpDumpHeader
+	buf    []byte
+}
+
+// NewRtpDumpReader read text line and RD_hdr_t file header
+func NewRtpDumpReader(r io.Reader) (*RtpDumpReader, error) {
+	reader := &RtpDumpReader{r: bufio.NewReader(r), buf: make([]byte, 0xFFFF)}
+	line, err := reader.r.ReadString('\n')
+	if err != nil {
+		return nil, errors.New("rtpdump file header error.")
+	}
+
+	// #!rtpplay1.0 224.2.0.1/3456
+	fields := strings.Fields(line)
+	if len(fields) != 2 || fields[0] != RtpDumpMagic {
+		return nil, errors.New("rtpdump magic error.")
+	}
+	i := strings.LastIndexByte(fields[1], '/')
+	if i < 0 {
+		return nil, errors.New("rtpdump address error.")
+	}
+	port, err := strconv.ParseUint(fields[1][i+1:], 10, 16)
+	if err != nil {
+		return nil, errors.New("rtpdump port error.")
+	}
+	reader.header.Address = net.ParseIP(fields[1][:i])
+	reader.header.Port = uint16(port)
+
+	var hdr [RtpDumpFileHeaderLength]byte
+	if _, err = io.ReadFull(reader.r, hdr[:]); err != nil {
+		return nil, errors.New("rtpdump file header error.")
+	}
+	reader.header.Start = time.Unix(int64(rtp.RtpReadUint32(hdr[0:])), int64(rtp.RtpReadUint32(hdr[4:]))*1000)
+	if reader.header.Address == nil {
+		reader.header.Address = net.IP(append([]byte(nil), hdr[8:12]...))
+	}
+	return reader, nil
+}
+
+func (r *RtpDumpReader) Header() *RtpDumpHeader {
+	return &r.header
+}
+
+// ReadPacket read next packet
+// @return packet, Data valid until next ReadPacket call, io.EOF-end of file
+func (r *RtpDumpReader) ReadPacket() (*RtpDumpPacket, error) {
+	var hdr [RtpDumpPacketHeaderLength]byte
+	if _, err := io.ReadFull(r.r, hdr[:]); err != nil {
+		return nil, err
+	}
+
+	length := int(rtp.RtpReadUint16(hdr[0:]))
+	plen := int(rtp.RtpReadUint16(hdr[2:]))
+	if length < RtpDumpPacketHeaderLength {
+		return nil, errors.New("rtpdump packet length error.")
+	}
+	data := r.buf[:length-RtpDumpPacketHeaderLength]
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	pkt := &RtpDumpPacket{
+		Offset: time.Duration(rtp.RtpReadUint32(hdr[4:])) * time.Millisecond,
+		Rtcp:   plen == 0,
+		Length: plen,
+		Data:   data,
+	}
+	if pkt.Rtcp {
+		pkt.Length = len(data)
+	}
+	return pkt, nil
+}
+
+type RtpDumpWriter struct {
+	w      io.Writer
+	header RtpDumpHeader
+	buf    []byte
+}
+
+// NewRtpDumpWriter write text line and RD_hdr_t file header
+// @param[in] header recording start time, source address(IPv4) and port
+func NewRtpDumpWriter(w io.Writer, header *RtpDumpHeader) (*RtpDumpWriter, error) {
+	writer := &RtpDumpWriter{w: w, header: *header}
+	address := header.Address
+	if address == nil {
+		address = net.IPv4zero
+	}
+
+	writer.buf = append(writer.buf[:0], fmt.Sprintf("%s %s/%d\n", RtpDumpMagic, address, header.Port)...)
+	var hdr [RtpDumpFileHeaderLength]byte
+	rtp.RtpWriteUint32(hdr[0:], uint32(header.Start.Unix()))
+	rtp.RtpWriteUint32(hdr[4:], uint32(header.Start.Nanosecond()/1000))
+	if ip4 := address.To4(); ip4 != nil {
+		copy(hdr[8:12], ip4)
+	}
+	rtp.RtpWriteUint16(hdr[12:], header.Port)
+	writer.buf = append(writer.buf, hdr[:]...)
+	if _, err := w.Write(writer.buf); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// WritePacket write RTP/RTCP packet received at now
+// @param[in] data RTP/RTCP packet
+// @param[in] bytes packet length in bytes
+// @param[in] rtcp true if RTCP packet
+// @param[in] now receive time, not before recording start time
+func (w *RtpDumpWriter) WritePacket(data []byte, bytes int, rtcp bool, now time.Time) error {
+	if bytes < 0 || bytes > len(data) || bytes+RtpDumpPacketHeaderLength > 0xFFFF {
+		return errors.New("rtpdump packet length error.")
+	}
+	offset := now.Sub(w.header.Start)
+	if offset < 0 {
+		return errors.New("rtpdump packet before start.")
+	}
+
+	var hdr [RtpDumpPacketHeaderLength]byte
+	rtp.RtpWriteUint16(hdr[0:], uint16(bytes+RtpDumpPacketHeaderLength))
+	if !rtcp {
+		rtp.RtpWriteUint16(hdr[2:], uint16(bytes))
+	}
+	rtp.RtpWriteUint32(hdr[4:], uint32(offset/time.Millisecond))
+	w.buf = append(append(w.buf[:0], hdr[:]...), data[:bytes]...)
+	_, err := w.w.Write(w.buf)
+	return err
+}
@@ -10,9 +10,11 @@ type RtpCommPack struct {
 	cbparam interface{}
 	pkt     rtp.RtpPacket
 	size    int
+	padding int // padding block size
 }
 
 func (p *RtpCommPack) Init(size int, payload uint8, seq uint16, ssrc uint32, handler RtpPayload, cbparam interface{}) {
+	p.handler = handler
 	p.cbparam = cbparam
 	p.size = size
 
@@ -30,6 +32,18 @@ func (p *RtpCommPack) GetInfo() (seq uint16, timestamp uint32) {
 	return p.pkt.Header.SequenceNumber, p.pkt.Header.Timestamp
 }
 
+func (p *RtpCommPack) SetPadding(block int) error {
+	if err := rtpPayloadCheckPadding(block); err != nil {
+		return err
+	}
+	p.padding = block
+	return nil
+}
+
+func (p *RtpCommPack) Probe(bytes int) error {
+	return rtpPayloadProbe(&p.pkt, p.handler, p.cbparam, p.size, bytes)
+}
+
 // PS/H.264 Elementary Stream to RTP Packet
 // @param[in] packer
 // @param[in] data stream data
@@ -40,39 +54,47 @@ func (p *RtpCommPack) Input(data []byte, bytes int, timestamp uint32) error {
 	if p.pkt.Payload != nil {
 		return errors.New("not first packet.")
 	}
+	defer func() { p.pkt.Payload = nil }() // ready for next frame
 
 	if p.pkt.Header.Timestamp == timestamp {
 		return errors.New("error timestamp.")
 	}
 
-	p.pkt.Header.Timestamp = timestamp // (uint32_t)time * packer->frequency / 1000; // ms -> 8KHZ
+	p.pkt.Header.Timestamp = timestamp // capture time to RTP timestamp by rtp.RtpClock
 	p.pkt.Header.Marker = 0            // marker bit alway 0
 
-	var n int
+	var n, padlen int
 	var err error
 	var rtpb []byte
+	size := p.size - rtpPayloadPaddingMax(p.padding)
 	for ptr := data; bytes > 0; p.pkt.Header.SequenceNumber++ {
 		p.pkt.Payload = ptr[:]
-		p.pkt.PayloadLen = p.size - rtp.RtpFixedHeader
-		if (bytes + rtp.RtpFixedHeader) <= p.size {
+		p.pkt.PayloadLen = size - rtp.RtpFixedHeader
+		if (bytes + rtp.RtpFixedHeader) <= size {
 			p.pkt.PayloadLen = bytes
 		}
 
 		ptr = ptr[p.pkt.PayloadLen:]
 		bytes -= p.pkt.PayloadLen
 		n = rtp.RtpFixedHeader + p.pkt.PayloadLen
-		rtpb = p.handler.Alloc(p.cbparam, n)
+		padlen = rtp.RtpPaddingSize(n, p.padding)
+		rtpb = p.handler.Alloc(p.cbparam, n+padlen)
 		if rtpb == nil {
 			return errors.New("rtp_pack alloc failed.")
 		}
 
-		n, err = rtp.RtpPacketSerialize(&p.pkt, rtpb, n)
+		n, err = rtp.RtpPacketSerialize(&p.pkt, rtpb, n+padlen)
 		if err != nil {
 			return err
 		}
 		if n != rtp.RtpFixedHeader+p.pkt.PayloadLen {
 			return errors.New("rtp packet serialize failed.")
 		}
+		if padlen > 0 {
+			if n, err = rtp.RtpPacketPadding(rtpb, n, padlen); err != nil {
+				return err
+			}
+		}
 
 		p.handler.Handle(p.cbparam, rtpb, n, p.pkt.Header.Timestamp, 0)
 		p.handler.Free(p.cbparam, rtpb)
@@ -16,14 +16,26 @@ func (up *RtpCommUnpack) Init(h RtpPayload, cbparam interface{}) {
 }
 
 func (up *RtpCommUnpack) Input(packet []byte, bytes int) (int, error) {
-	var pkt rtp.RtpPacket
-	err := rtp.RtpPacketDeserialize(&pkt, packet, bytes)
+	var pkt rtp.RtpPacketView
+	err := rtp.RtpPacketViewParse(&pkt, packet, bytes)
 	if err != nil {
 		return -1, err
 	}
-	if pkt.PayloadLen < 1 {
+	if len(pkt.Payload()) < 1 && pkt.Padding() == 0 {
 		return -1, errors.New("rtp payload len < 1.")
 	}
-	up.handler.Handle(up.cbparam, pkt.Payload, pkt.PayloadLen, pkt.Header.Timestamp, 0)
+
+	switch up.RtpPayloadSequence(&pkt) {
+	case rtp.RTP_SEQ_PROBATION, rtp.RTP_SEQ_LATE, rtp.RTP_SEQ_BAD:
+		return 0, nil // packet discard
+	case rtp.RTP_SEQ_LOST, rtp.RTP_SEQ_RESTART:
+		up.flags |= RTP_PAYLOAD_FLAG_PACKET_LOST
+	}
+	if len(pkt.Payload()) < 1 {
+		return 0, nil // padding-only packet(e.g. probe), sequence number consumed
+	}
+
+	up.handler.Handle(up.cbparam, pkt.Payload(), len(pkt.Payload()), pkt.Timestamp(), up.flags)
+	up.flags = 0
 	return 1, nil
 }
@@ -22,8 +22,12 @@
 package payload
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"github.com/services-go/librtp/rtp"
+	"github.com/services-go/librtp/sdp"
+	"strconv"
 )
 
 const (
@@ -31,6 +35,11 @@ const (
 	FU_END_264   = 0x40
 
 	N_FU_HEADER_264 = 2
+
+	// SDP fmtp packetization-mode
+	H264_PACKETIZATION_MODE_SINGLE_NAL     = 0
+	H264_PACKETIZATION_MODE_NON_INTERLEAVE = 1
+	H264_PACKETIZATION_MODE_INTERLEAVE     = 2
 )
 
 type RtpPackH264 struct {
@@ -38,6 +47,10 @@ type RtpPackH264 struct {
 	handler RtpPayload
 	cbparam interface{}
 	size    int
+	padding int    // padding block size
+	mode    int    // packetization-mode
+	sps     []byte // last SPS seen in the input, for SDP
+	pps     []byte // last PPS seen in the input, for SDP
 }
 
 // create RTP packer
@@ -57,6 +70,18 @@ func (p *RtpPackH264) Init(size int, payload uint8, seq uint16, ssrc uint32, han
 	p.pkt.Header.PayloadType = payload
 	p.pkt.Header.SequenceNumber = seq
 	p.pkt.Header.SSRC = ssrc
+	p.mode = H264_PACKETIZATION_MODE_NON_INTERLEAVE
+}
+
+// SetPacketizationMode set SDP fmtp packetization-mode, default 1
+// single NAL unit mode can't fragment, NAL unit larger than packet size is an error
+// interleaved mode is not supported
+func (p *RtpPackH264) SetPacketizationMode(mode int) error {
+	if mode != H264_PACKETIZATION_MODE_SINGLE_NAL && mode != H264_PACKETIZATION_MODE_NON_INTERLEAVE {
+		return errors.New("h264 packetization-mode not supported.")
+	}
+	p.mode = mode
+	return nil
 }
 
 // destroy RTP Packer
@@ -68,6 +93,18 @@ func (p *RtpPackH264) GetInfo() (seq uint16, timestamp uint32) {
 	return p.pkt.Header.SequenceNumber, p.pkt.Header.Timestamp
 }
 
+func (p *RtpPackH264) SetPadding(block int) error {
+	if err := rtpPayloadCheckPadding(block); err != nil {
+		return err
+	}
+	p.padding = block
+	return nil
+}
+
+func (p *RtpPackH264) Probe(bytes int) error {
+	return rtpPayloadProbe(&p.pkt, p.handler, p.cbparam, p.size, bytes)
+}
+
 // PS/H.264 Elementary Stream to RTP Packet
 // @param[in] packer
 // @param[in] data stream data
@@ -75,7 +112,7 @@ func (p *RtpPackH264) GetInfo() (seq uint16, timestamp uint32) {
 // @param[in] time stream UTC time
 // @return 0-ok, ENOMEM-alloc failed, <0-failed
 func (p *RtpPackH264) Input(data []byte, bytes int, timestamp uint32) error {
-	p.pkt.Header.Timestamp = timestamp //(uint32_t)time * KHz; // ms -> 90KHZ
+	p.pkt.Header.Timestamp = timestamp // capture time to RTP timestamp by rtp.RtpClock
 	var err error
 	var p1, p2 []byte
 	for p1 = h264NaluFind(data, len(data)); len(p1) > 0 && err == nil; p1 = p2 {
@@ -91,15 +128,42 @@ func (p *RtpPackH264) Input(data []byte, bytes int, timestamp uint32) error {
 			naluSize--
 		}
 
-		if naluSize+rtp.RtpFixedHeader <= p.size {
+		switch p1[0] & 0x1f {
+		case 7: // SPS
+			p.sps = append(p.sps[:0], p1[:naluSize]...)
+		case 8: // PPS
+			p.pps = append(p.pps[:0], p1[:naluSize]...)
+		}
+
+		if naluSize+rtp.RtpFixedHeader <= p.size-rtpPayloadPaddingMax(p.padding) {
 			err = p.rtpH264PackNalu(p1, naluSize)
+		} else if p.mode == H264_PACKETIZATION_MODE_SINGLE_NAL {
+			err = errors.New("h264 nalu too large for single nal unit mode.")
 		} else {
 			err = p.rtpH264PackFuA(p1, naluSize)
 		}
 
 	}
 
-	return nil
+	return err
+}
+
+// Fmtp describe the packer as SDP fmtp
+// RFC6184 8.1. Media Type Registration (p74)
+// profile-level-id and sprop-parameter-sets are present after SPS/PPS input
+// e.g. a=fmtp:96 packetization-mode=1;profile-level-id=42e01f;sprop-parameter-sets=Z0LgH9oFB+Q=,aM4wpIA=
+func (p *RtpPackH264) Fmtp() *sdp.SdpFmtp {
+	fmtp := &sdp.SdpFmtp{Payload: int(p.pkt.Header.PayloadType)}
+	fmtp.Params = append(fmtp.Params, sdp.SdpParam{Name: "packetization-mode", Value: strconv.Itoa(p.mode)})
+	if len(p.sps) >= 4 {
+		// profile_idc + constraint_set flags + level_idc
+		fmtp.Params = append(fmtp.Params, sdp.SdpParam{Name: "profile-level-id", Value: hex.EncodeToString(p.sps[1:4])})
+	}
+	if len(p.sps) > 0 && len(p.pps) > 0 {
+		sets := base64.StdEncoding.EncodeToString(p.sps) + "," + base64.StdEncoding.EncodeToString(p.pps)
+		fmtp.Params = append(fmtp.Params, sdp.SdpParam{Name: "sprop-parameter-sets", Value: sets})
+	}
+	return fmtp
 }
 
 func h264NaluFind(data []byte, bytes int) []byte {
@@ -116,7 +180,8 @@ func (p *RtpPackH264) rtpH264PackNalu(nalu []byte, bytes int) error {
 	p.pkt.Payload = nalu
 	p.pkt.PayloadLen = bytes
 	n := rtp.RtpFixedHeader + p.pkt.PayloadLen
-	rtpb := p.handler.Alloc(p.cbparam, n)
+	padlen := rtp.RtpPaddingSize(n, p.padding)
+	rtpb := p.handler.Alloc(p.cbparam, n+padlen)
 	if rtpb == nil {
 		return errors.New("alloc rtp buffer failed.")
 	}
@@ -127,13 +192,18 @@ func (p *RtpPackH264) rtpH264PackNalu(nalu []byte, bytes int) error {
 	}
 
 	var err error
-	n, err = rtp.RtpPacketSerialize(&p.pkt, rtpb, n)
+	n, err = rtp.RtpPacketSerialize(&p.pkt, rtpb, n+padlen)
 	if err != nil {
 		return err
 	}
 	if n != rtp.RtpFixedHeader+p.pkt.PayloadLen {
 		return errors.New("rtp packet serailize failed.")
 	}
+	if padlen > 0 {
+		if n, err = rtp.RtpPacketPadding(rtpb, n, padlen); err != nil {
+			return err
+		}
+	}
 
 	p.pkt.Header.SequenceNumber++
 	p.handler.Handle(p.cbparam, rtpb, n, p.pkt.Header.Timestamp, 0)
@@ -154,11 +224,12 @@ func (p *RtpPackH264) rtpH264PackFuA(nalu []byte, bytes int) error {
 	}
 
 	// FU-A start
-	var n int
+	var n, padlen int
 	var err error
+	size := p.size - rtpPayloadPaddingMax(p.padding)
 	for fuHeader |= FU_START_264; bytes > 0; p.pkt.Header.SequenceNumber++ {
-		p.pkt.PayloadLen = p.size - rtp.RtpFixedHeader - N_FU_HEADER_264
-		if bytes+rtp.RtpFixedHeader <= p.size-N_FU_HEADER_264 {
+		p.pkt.PayloadLen = size - rtp.RtpFixedHeader - N_FU_HEADER_264
+		if bytes+rtp.RtpFixedHeader <= size-N_FU_HEADER_264 {
 			if (fuHeader & FU_START_264) != 0 {
 				return errors.New("fuHeader & 0x80 not equal 0.")
 			}
@@ -168,7 +239,8 @@ func (p *RtpPackH264) rtpH264PackFuA(nalu []byte, bytes int) error {
 
 		p.pkt.Payload = nalu
 		n = rtp.RtpFixedHeader + N_FU_HEADER_264 + p.pkt.PayloadLen
-		rtpb := p.handler.Alloc(p.cbparam, n)
+		padlen = rtp.RtpPaddingSize(n, p.padding)
+		rtpb := p.handler.Alloc(p.cbparam, n+padlen)
 		if rtpb == nil {
 			return errors.New("alloc rtpb failed.")
 		}
@@ -189,9 +261,15 @@ func (p *RtpPackH264) rtpH264PackFuA(nalu []byte, bytes int) error {
 		}
 
 		rtpb[n] = fuIndicator
-		rtpb[1] = fuHeader
+		rtpb[n+1] = fuHeader
 		copy(rtpb[n+N_FU_HEADER_264:], p.pkt.Payload[:p.pkt.PayloadLen])
-		p.handler.Handle(p.cbparam, rtpb, n+N_FU_HEADER_264+p.pkt.PayloadLen, p.pkt.Header.Timestamp, 0)
+		n += N_FU_HEADER_264 + p.pkt.PayloadLen
+		if padlen > 0 {
+			if n, err = rtp.RtpPacketPadding(rtpb, n, padlen); err != nil {
+				return err
+			}
+		}
+		p.handler.Handle(p.cbparam, rtpb, n, p.pkt.Header.Timestamp, 0)
 		p.handler.Free(p.cbparam, rtpb)
 		bytes -= p.pkt.PayloadLen
 		nalu = nalu[p.pkt.PayloadLen:]
@@ -8,7 +8,7 @@ import (
 type RtpUnpackH264 struct {
 	handler  RtpPayload
 	cbparam  interface{}
-	seq      uint16
+	source   rtp.RtpSource
 	ptr      []byte
 	size     int
 	capacity int
@@ -27,46 +27,57 @@ func (up *RtpUnpackH264) Destroy() {
 	}
 }
 
+// Source return sequence number state of the unpacked stream
+func (up *RtpUnpackH264) Source() *rtp.RtpSource {
+	return &up.source
+}
+
 func (up *RtpUnpackH264) Input(data []byte, bytes int) (int, error) {
-	var pkt rtp.RtpPacket
-	err := rtp.RtpPacketDeserialize(&pkt, data, bytes)
+	var pkt rtp.RtpPacketView
+	err := rtp.RtpPacketViewParse(&pkt, data, bytes)
 	if err != nil {
 		return -1, err
 	}
-	if pkt.PayloadLen < 1 {
+	payload := pkt.Payload()
+	if len(payload) < 1 && pkt.Padding() == 0 {
 		return -1, errors.New("payload len < 1.")
 	}
 
 	if up.flags == -1 {
 		up.flags = 0
-		up.seq = pkt.Header.SequenceNumber - 1 // disable packet lost
+		up.source.Start(pkt.SequenceNumber(), 0) // disable packet lost
 	}
 
-	if pkt.Header.SequenceNumber != up.seq+1 {
+	switch up.source.Update(pkt.SequenceNumber()) {
+	case rtp.RTP_SEQ_PROBATION, rtp.RTP_SEQ_LATE, rtp.RTP_SEQ_BAD:
+		return 0, nil // packet discard
+	case rtp.RTP_SEQ_LOST, rtp.RTP_SEQ_RESTART:
 		up.flags = RTP_PAYLOAD_FLAG_PACKET_LOST
 		up.size = 0 // discard previous packets
 	}
-	up.seq = pkt.Header.SequenceNumber
+	if len(payload) < 1 {
+		return 0, nil // padding-only packet(e.g. probe), sequence number consumed
+	}
 
-	nal := pkt.Payload[0]
+	nal := payload[0]
 	switch nal & 0x1F {
 	case 0: // reserved
 	case 31: // reserved
 		return 0, nil // packet discard
 	case 24: // STAP-A
-		return up.rtpH264UnpackStap(pkt.Payload, pkt.PayloadLen, pkt.Header.Timestamp, 0)
+		return up.rtpH264UnpackStap(payload, len(payload), pkt.Timestamp(), 0)
 	case 25: // STAP-B
-		return up.rtpH264UnpackStap(pkt.Payload, pkt.PayloadLen, pkt.Header.Timestamp, 1)
+		return up.rtpH264UnpackStap(payload, len(payload), pkt.Timestamp(), 1)
 	case 26: // MTAP16
-		return up.rtpH264UnpackMtap(pkt.Payload, pkt.PayloadLen, pkt.Header.Timestamp, 2)
+		return up.rtpH264UnpackMtap(payload, len(payload), pkt.Timestamp(), 2)
 	case 27: // MTAP24
-		return up.rtpH264UnpackMtap(pkt.Payload, pkt.PayloadLen, pkt.Header.Timestamp, 3)
+		return up.rtpH264UnpackMtap(payload, len(payload), pkt.Timestamp(), 3)
 	case 28: // FU-A
-		return up.rtpH264UnpackFu(pkt.Payload, pkt.PayloadLen, pkt.Header.Timestamp, 0)
+		return up.rtpH264UnpackFu(payload, len(payload), pkt.Timestamp(), 0)
 	case 29: // FU-B
-		return up.rtpH264UnpackFu(pkt.Payload, pkt.PayloadLen, pkt.Header.Timestamp, 1)
+		return up.rtpH264UnpackFu(payload, len(payload), pkt.Timestamp(), 1)
 	default: // 1-23 NAL unit
-		up.handler.Handle(up.cbparam, pkt.Payload, pkt.PayloadLen, pkt.Header.Timestamp, up.flags)
+		up.handler.Handle(up.cbparam, payload, len(payload), pkt.Timestamp(), up.flags)
 		up.flags = 0
 		up.size = 0
 	}
@@ -1,8 +1,11 @@
 package payload
 
 import (
+	"encoding/hex"
 	"errors"
 	"github.com/services-go/librtp/rtp"
+	"github.com/services-go/librtp/sdp"
+	"strconv"
 )
 
 const (
@@ -14,6 +17,11 @@ type RtpPackMpeg4Generic struct {
 	handler RtpPayload
 	cbparam interface{}
 	size    int
+	padding int // padding block size
+
+	sizeLength  int    // AU-size bits
+	indexLength int    // AU-Index bits
+	config      []byte // AudioSpecificConfig, for SDP
 }
 
 func (p *RtpPackMpeg4Generic) Init(size int, payload uint8, seq uint16, ssrc uint32, handler RtpPayload, cbparam interface{}) {
@@ -25,6 +33,114 @@ func (p *RtpPackMpeg4Generic) Init(size int, payload uint8, seq uint16, ssrc uin
 	p.pkt.Header.PayloadType = payload
 	p.pkt.Header.SequenceNumber = seq
 	p.pkt.Header.SSRC = ssrc
+
+	// 3.3.6. High Bit-rate AAC
+	// SDP fmtp: sizeLength=13; indexLength=3; indexDeltaLength=3;
+	p.sizeLength = 13
+	p.indexLength = 3
+}
+
+// SetAUHeader set AU-header layout, must match SDP fmtp sizeLength/indexLength
+func (p *RtpPackMpeg4Generic) SetAUHeader(sizeLength, indexLength int) error {
+	if sizeLength < 1 || sizeLength > 16 || indexLength < 0 || indexLength > 8 {
+		return errors.New("mpeg4-generic au header error.")
+	}
+	p.sizeLength = sizeLength
+	p.indexLength = indexLength
+	return nil
+}
+
+// SetConfig set AudioSpecificConfig for SDP fmtp config,
+// derived from ADTS header of the input if not set
+func (p *RtpPackMpeg4Generic) SetConfig(config []byte) {
+	p.config = config
+}
+
+// ISO/IEC 14496-3 Table 1.18 Sampling Frequency Index
+var mpeg4AudioFrequencies = [...]int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+// mpeg4AudioConfig parse AudioSpecificConfig
+// ISO/IEC 14496-3 1.6.2.1 AudioSpecificConfig
+// audioObjectType(5) + samplingFrequencyIndex(4) [+ samplingFrequency(24)] + channelConfiguration(4)
+// @return 0 if unknown
+func mpeg4AudioConfig(config []byte) (objectType, frequency, channels int) {
+	if len(config) < 2 {
+		return 0, 0, 0
+	}
+	objectType = int(config[0] >> 3)
+	if objectType == 31 {
+		return objectType, 0, 0 // audioObjectTypeExt not supported
+	}
+	index := int(config[0]&0x07)<<1 | int(config[1]>>7)
+	channel := int(config[1]>>3) & 0x0F
+	if index == 0x0F {
+		if len(config) < 5 {
+			return objectType, 0, 0
+		}
+		v := uint64(config[1])<<32 | uint64(config[2])<<24 | uint64(config[3])<<16 | uint64(config[4])<<8
+		frequency = int(v >> 15 & 0xFFFFFF)
+		channel = int(v>>11) & 0x0F
+	} else if index < len(mpeg4AudioFrequencies) {
+		frequency = mpeg4AudioFrequencies[index]
+	}
+
+	// Table 1.19 Channel Configuration
+	if channel >= 1 && channel <= 6 {
+		channels = channel
+	} else if channel == 7 {
+		channels = 8
+	}
+	return objectType, frequency, channels
+}
+
+// Rtpmap return clock rate(sampling rate) and channels from AudioSpecificConfig, 0 if unknown
+func (p *RtpPackMpeg4Generic) Rtpmap() (frequency, channels int) {
+	_, frequency, channels = mpeg4AudioConfig(p.config)
+	return frequency, channels
+}
+
+// profileLevelId ISO/IEC 14496-3 Table 1.14 audioProfileLevelIndication of AAC Profile,
+// 0xFE(no audio profile specified) for other object types
+func (p *RtpPackMpeg4Generic) profileLevelId() int {
+	objectType, frequency, channels := mpeg4AudioConfig(p.config)
+	if objectType != 2 || frequency == 0 || channels == 0 {
+		return 0xFE
+	}
+	switch {
+	case frequency <= 24000 && channels <= 2:
+		return 0x28 // AAC Profile L1
+	case frequency <= 48000 && channels <= 2:
+		return 0x29 // AAC Profile L2
+	case frequency <= 48000 && channels <= 6:
+		return 0x2A // AAC Profile L4
+	case frequency <= 96000 && channels <= 6:
+		return 0x2B // AAC Profile L5
+	}
+	return 0xFE
+}
+
+// Fmtp describe the packer as SDP fmtp
+// RFC3640 4.1. Media Type Registration (p27)
+// e.g. a=fmtp:97 streamType=5;profile-level-id=41;mode=AAC-hbr;sizeLength=13;indexLength=3;indexDeltaLength=3;config=1210
+func (p *RtpPackMpeg4Generic) Fmtp() *sdp.SdpFmtp {
+	fmtp := &sdp.SdpFmtp{Payload: int(p.pkt.Header.PayloadType)}
+	fmtp.Params = []sdp.SdpParam{
+		{Name: "streamType", Value: "5"},                                    // audio stream
+		{Name: "profile-level-id", Value: strconv.Itoa(p.profileLevelId())}, // decimal
+		{Name: "mode", Value: "AAC-hbr"},
+		{Name: "sizeLength", Value: strconv.Itoa(p.sizeLength)},
+		{Name: "indexLength", Value: strconv.Itoa(p.indexLength)},
+		{Name: "indexDeltaLength", Value: strconv.Itoa(p.indexLength)}, // one AU per packet, same as indexLength
+	}
+	if len(p.config) > 0 {
+		fmtp.Params = append(fmtp.Params, sdp.SdpParam{Name: "config", Value: hex.EncodeToString(p.config)})
+	}
+	return fmtp
+}
+
+// AU-headers-length(16 bits) + one AU-header padding to byte
+func (p *RtpPackMpeg4Generic) auHeaderBytes() int {
+	return 2 + (p.sizeLength+p.indexLength+7)/8
 }
 
 // destroy RTP Packer
@@ -35,38 +151,69 @@ func (p *RtpPackMpeg4Generic) GetInfo() (seq uint16, timestamp uint32) {
 	return p.pkt.Header.SequenceNumber, p.pkt.Header.Timestamp
 }
 
+func (p *RtpPackMpeg4Generic) SetPadding(block int) error {
+	if err := rtpPayloadCheckPadding(block); err != nil {
+		return err
+	}
+	p.padding = block
+	return nil
+}
+
+func (p *RtpPackMpeg4Generic) Probe(bytes int) error {
+	return rtpPayloadProbe(&p.pkt, p.handler, p.cbparam, p.size, bytes)
+}
+
 func (p *RtpPackMpeg4Generic) Input(data []byte, bytes int, timestamp uint32) error {
-	p.pkt.Header.Timestamp = timestamp //(uint32_t)time * KHz; // ms -> 90KHZ
+	p.pkt.Header.Timestamp = timestamp // capture time to RTP timestamp by rtp.RtpClock
 	ptr := data
 	if ptr[0] == 0xFF && (ptr[1]&0xF0) == 0xF0 && bytes > 7 {
 		// skip ADTS header
 		if (int(ptr[3]&0x03)<<11)|(int(ptr[4])<<3|int((ptr[5]>>5)&0x07)) != bytes {
 			return errors.New("error ADTS header.")
 		}
+		if p.config == nil {
+			// ISO/IEC 14496-3 1.6.2.1 AudioSpecificConfig
+			// audioObjectType(5) + samplingFrequencyIndex(4) + channelConfiguration(4) + GASpecificConfig(3)
+			objectType := (ptr[2] >> 6) + 1
+			frequency := (ptr[2] >> 2) & 0x0F
+			channels := ((ptr[2] & 0x01) << 2) | (ptr[3] >> 6)
+			p.config = []byte{(objectType << 3) | (frequency >> 1), ((frequency & 0x01) << 7) | (channels << 3)}
+		}
 		ptr = ptr[7:]
 		bytes -= 7
 	}
 
-	var header [4]byte
-	var n int
-	for size := bytes; bytes > 0; p.pkt.Header.SequenceNumber++ {
-		// 3.3.6. High Bit-rate AAC
-		// SDP fmtp: sizeLength=13; indexLength=3; indexDeltaLength = 3;
-		header[0] = 0
-		header[1] = 16
-		header[2] = byte(size >> 5)
-		header[3] = byte(size&0x1f) << 3
+	if bytes >= 1<<uint(p.sizeLength) {
+		return errors.New("access unit too large for sizeLength.")
+	}
 
+	// 3.2.1. The AU Header Section, AU-Index always 0
+	// e.g. AAC-hbr: 0x00 0x10 | size(13 bits) + index(3 bits)
+	var header [5]byte
+	bits := p.sizeLength + p.indexLength
+	nheader := p.auHeaderBytes()
+	au := uint32(bytes) << uint(p.indexLength) << uint((nheader-2)*8-bits)
+	header[0] = byte(bits >> 8)
+	header[1] = byte(bits)
+	for i := nheader - 1; i >= 2; i-- {
+		header[i] = byte(au)
+		au >>= 8
+	}
+
+	var n, padlen int
+	limit := p.size - rtpPayloadPaddingMax(p.padding)
+	for bytes > 0 {
 		p.pkt.Payload = ptr
-		p.pkt.PayloadLen = p.size - N_AU_HEADER - rtp.RtpFixedHeader
-		if bytes+N_AU_HEADER+rtp.RtpFixedHeader <= p.size {
+		p.pkt.PayloadLen = limit - nheader - rtp.RtpFixedHeader
+		if bytes+nheader+rtp.RtpFixedHeader <= limit {
 			p.pkt.PayloadLen = bytes
 		}
 		ptr = ptr[p.pkt.PayloadLen:]
 		bytes -= p.pkt.PayloadLen
 
-		n = rtp.RtpFixedHeader + N_AU_HEADER + p.pkt.PayloadLen
-		rtpb := p.handler.Alloc(p.cbparam, n)
+		n = rtp.RtpFixedHeader + nheader + p.pkt.PayloadLen
+		padlen = rtp.RtpPaddingSize(n, p.padding)
+		rtpb := p.handler.Alloc(p.cbparam, n+padlen)
 		if rtpb == nil {
 			return errors.New("alloc rtp buffer failed.")
 		}
@@ -87,10 +234,17 @@ func (p *RtpPackMpeg4Generic) Input(data []byte, bytes int, timestamp uint32) er
 			return errors.New("rtp packet serialize header failed.")
 		}
 
-		copy(rtpb[n:], header[:N_AU_HEADER])
-		copy(rtpb[n+N_AU_HEADER:], p.pkt.Payload[:p.pkt.PayloadLen])
-		p.handler.Handle(p.cbparam, rtpb, n+N_AU_HEADER+p.pkt.PayloadLen, p.pkt.Header.Timestamp, 0)
+		copy(rtpb[n:], header[:nheader])
+		copy(rtpb[n+nheader:], p.pkt.Payload[:p.pkt.PayloadLen])
+		n += nheader + p.pkt.PayloadLen
+		if padlen > 0 {
+			if n, err = rtp.RtpPacketPadding(rtpb, n, padlen); err != nil {
+				return err
+			}
+		}
+		p.handler.Handle(p.cbparam, rtpb, n, p.pkt.Header.Timestamp, 0)
 		p.handler.Free(p.cbparam, rtpb)
+		p.pkt.Header.SequenceNumber++
 	}
 	return nil
 }
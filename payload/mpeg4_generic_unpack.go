@@ -8,48 +8,88 @@ import (
 
 type RtpUnpackMpeg4Generic struct {
 	RtpPayloadHelper
+	sizeLength       int    // AU-size bits
+	indexLength      int    // AU-Index bits of the first AU-header
+	indexDeltaLength int    // AU-Index-delta bits of other AU-headers
+	config           []byte // AudioSpecificConfig from SDP fmtp config
 }
 
 func (up *RtpUnpackMpeg4Generic) Init(h RtpPayload, cbparam interface{}) {
 	up.handler = h
 	up.cbparam = cbparam
 	up.flags = -1
+
+	// 3.3.6. High Bit-rate AAC
+	// SDP fmtp: sizeLength=13; indexLength=3; indexDeltaLength=3;
+	up.sizeLength = 13
+	up.indexLength = 3
+	up.indexDeltaLength = 3
+}
+
+// SetAUHeader set AU-header layout from SDP fmtp sizeLength/indexLength/indexDeltaLength
+// CTS/DTS/RAP/auxiliary data are not supported
+func (up *RtpUnpackMpeg4Generic) SetAUHeader(sizeLength, indexLength, indexDeltaLength int) error {
+	if sizeLength < 1 || sizeLength > 16 || indexLength < 0 || indexLength > 8 || indexDeltaLength < 0 || indexDeltaLength > 8 {
+		return errors.New("mpeg4-generic au header error.")
+	}
+	up.sizeLength = sizeLength
+	up.indexLength = indexLength
+	up.indexDeltaLength = indexDeltaLength
+	return nil
+}
+
+// SetConfig set AudioSpecificConfig from SDP fmtp config
+func (up *RtpUnpackMpeg4Generic) SetConfig(config []byte) {
+	up.config = config
+}
+
+func (up *RtpUnpackMpeg4Generic) Config() []byte {
+	return up.config
 }
 
 func (up *RtpUnpackMpeg4Generic) Input(packet []byte, bytes int) (int, error) {
-	var pkt rtp.RtpPacket
-	err := rtp.RtpPacketDeserialize(&pkt, packet, bytes)
+	var pkt rtp.RtpPacketView
+	err := rtp.RtpPacketViewParse(&pkt, packet, bytes)
 	if err != nil {
 		return -1, err
 	}
-	if pkt.PayloadLen < 4 {
+	if len(pkt.Payload()) < 4 && (len(pkt.Payload()) > 0 || pkt.Padding() == 0) {
 		return -1, errors.New("rtp payload len < 1.")
 	}
 
-	up.RtpPayloadCheck(&pkt)
-	if up.lost > 0 {
+	if !up.RtpPayloadCheck(&pkt) || up.lost > 0 {
 		return 0, nil
 	}
+	if len(pkt.Payload()) < 1 {
+		return 0, nil // padding-only packet(e.g. probe), sequence number consumed
+	}
 
 	// save payload
-	ptr := pkt.Payload
+	ptr := pkt.Payload()
 	// AU-headers-length
-	auHeaderLen := int(ptr[0])<<8 + int(ptr[1])
-	auHeaderLen = (auHeaderLen + 7) / 8 // bit -> byte
+	auHeaderBits := int(ptr[0])<<8 + int(ptr[1])
+	auHeaderLen := (auHeaderBits + 7) / 8 // bit -> byte
 
 	payloadLen := len(ptr)
-	if int(auHeaderLen) > payloadLen || auHeaderLen < 2 {
+	if auHeaderLen+2 > payloadLen || auHeaderBits < up.sizeLength {
 		up.size = 0
 		up.lost = 1
 		up.flags |= RTP_PAYLOAD_FLAG_PACKET_LOST
 		return -1, errors.New("invalid packet.")
 	}
 
-	// 3.3.6. High Bit-rate AAC
-	// SDP fmtp: sizeLength=13; indexLength=3; indexDeltaLength=3;
-	auSize := 2 // only AU-size
-	auNumbers := int(auHeaderLen) / auSize
-	if int(auHeaderLen)%auSize != 0 {
+	// 3.2.1. The AU Header Section
+	// first AU-header: AU-size + AU-Index, others: AU-size + AU-Index-delta
+	auNumbers := 0
+	bits := 0
+	for bits < auHeaderBits {
+		bits += up.sizeLength + up.indexDeltaLength
+		if auNumbers == 0 {
+			bits += up.indexLength - up.indexDeltaLength
+		}
+		auNumbers++
+	}
+	if bits != auHeaderBits {
 		return -1, errors.New("au size error.")
 	}
 
@@ -57,9 +97,12 @@ func (up *RtpUnpackMpeg4Generic) Input(packet []byte, bytes int) (int, error) {
 	pau := ptr[auHeaderLen:] // point to Access Unit
 
 	var size int
-	for i := 0; i < auNumbers; i++ {
-		size = (int(ptr[0]) << 8) | int(ptr[1]&0xF8)
-		size = size >> 3 // bit -> byte
+	for i, offset := 0, 0; i < auNumbers; i++ {
+		size = rtpReadBits(ptr, offset, up.sizeLength)
+		offset += up.sizeLength + up.indexDeltaLength
+		if i == 0 {
+			offset += up.indexLength - up.indexDeltaLength
+		}
 		if size > len(pau) {
 			up.size = 0
 			up.lost = 1
@@ -68,13 +111,10 @@ func (up *RtpUnpackMpeg4Generic) Input(packet []byte, bytes int) (int, error) {
 		}
 
 		// TODO: add ADTS/ASC ???
-		pkt.Payload = pau
-		pkt.PayloadLen = size
-		up.RtpPayloadWrite(&pkt)
+		up.RtpPayloadWrite(pau[:size])
 
-		ptr = ptr[auSize:]
 		pau = pau[size:]
-		if auNumbers > 1 || pkt.Header.Marker > 0 {
+		if auNumbers > 1 || pkt.Marker() > 0 {
 			up.RtpPayloadOnFrame()
 		}
 	}
@@ -84,3 +124,13 @@ func (up *RtpUnpackMpeg4Generic) Input(packet []byte, bytes int) (int, error) {
 	}
 	return 1, nil
 }
+
+// rtpReadBits read n(<= 24) bits from bit offset, MSB first
+func rtpReadBits(data []byte, offset int, n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		bit := (data[(offset+i)/8] >> (7 - uint(offset+i)%8)) & 0x01
+		v = (v << 1) | int(bit)
+	}
+	return v
+}
@@ -0,0 +1,495 @@
+package payload
+
+import (
+	"errors"
+	"github.com/services-go/librtp/rtp"
+	"github.com/services-go/librtp/sdp"
+	"strconv"
+)
+
+// RFC5109 RTP Payload Format for Generic Forward Error Correction
+// RFC8627 RTP Payload Format for Flexible Forward Error Correction (FEC)
+const (
+	RTP_FEC_ULPFEC  = 1 // RFC5109, FEC packets in the media stream(same SSRC), optionally in RFC2198 RED
+	RTP_FEC_FLEXFEC = 2 // RFC8627, FEC packets in a separate stream(own SSRC), flexible mask
+
+	RtpUlpfecHeaderLength     = 10 // FEC header
+	RtpUlpfecLevelShortLength = 4  // ULP level header L=0, 16 bits mask
+	RtpUlpfecLevelLongLength  = 8  // ULP level header L=1, 48 bits mask
+	RtpFlexfecHeaderLength    = 10 // FEC header before the first mask
+	RtpFecMaxMediaPackets     = 48 // media packets protected by one FEC packet group
+
+	RtpUlpfecEncoding  = "ulpfec"
+	RtpFlexfecEncoding = "flexfec"
+)
+
+// RtpFecMaskFunc protection masks of a packet group,
+// bit i of masks[j] set if FEC packet j protect media packet i
+// @param[in] media media packets in group, 1~RtpFecMaxMediaPackets
+// @param[in] fec FEC packets wanted, 1~media
+type RtpFecMaskFunc func(media, fec int) []uint64
+
+// RtpFecMaskInterleaved media packet i protected by FEC packet i%fec, recover random loss
+// e.g. media 4, fec 2: 0101, 1010
+func RtpFecMaskInterleaved(media, fec int) []uint64 {
+	masks := make([]uint64, fec)
+	for i := 0; i < media; i++ {
+		masks[i%fec] |= 1 << uint(i)
+	}
+	return masks
+}
+
+// RtpFecMaskConsecutive FEC packet j protect the j-th block of consecutive media packets,
+// recover one loss per block
+// e.g. media 4, fec 2: 0011, 1100
+func RtpFecMaskConsecutive(media, fec int) []uint64 {
+	masks := make([]uint64, fec)
+	block := (media + fec - 1) / fec
+	for i := 0; i < media; i++ {
+		masks[i/block] |= 1 << uint(i)
+	}
+	return masks
+}
+
+// rtpFecXor FEC bit string of protected packets
+// RFC5109 10.2. FEC Header Generation (p19) / RFC8627 6.3.1 Repair Packet Construction (p32)
+/*
+   o  the first 64 bits of the RTP header, padding bit through timestamp
+   o  unsigned network-ordered 16-bit representation of the length of
+      the packet minus 12 (CSRC list, header extension, payload and padding)
+   o  the bytes following the fixed RTP header
+*/
+type rtpFecXor struct {
+	head    [8]byte // P|X|CC, M|PT, length recovery, TS recovery
+	payload []byte
+}
+
+func (x *rtpFecXor) reset() {
+	x.head = [8]byte{}
+	x.payload = x.payload[:0]
+}
+
+func (x *rtpFecXor) add(packet []byte) {
+	n := len(packet) - rtp.RtpFixedHeader
+	x.head[0] ^= packet[0]
+	x.head[1] ^= packet[1]
+	x.head[2] ^= byte(n >> 8)
+	x.head[3] ^= byte(n)
+	for i := 0; i < 4; i++ {
+		x.head[4+i] ^= packet[rtp.RtpHeader_TimestampOffset+i]
+	}
+
+	if len(x.payload) < n {
+		x.payload = append(x.payload, make([]byte, n-len(x.payload))...)
+	}
+	for i, b := range packet[rtp.RtpFixedHeader:] {
+		x.payload[i] ^= b
+	}
+}
+
+// RtpFecEncoder RtpPayload handler forward media packets to the inner handler
+// and generate FEC packets after each frame(marker bit or timestamp change).
+// Frames are protected only while SetProtection fec > 0, so packers opt in
+// per frame. ULPFEC packets share the media sequence numbers, the encoder
+// renumber all media packets in that case, use GetInfo for RTP-Info, and put
+// RtpPacketHistory after the encoder(packer -> FEC -> history -> network),
+// so the history keep the sequence numbers sent on the wire.
+// e.g. fec := NewRtpUlpfecEncoder(NewRtpPacketHistory(transport, 512), 117, 116)
+// payload.RtpPayloadCreate(96, "H264", seq, ssrc, 1200, fec, nil, nil)
+type RtpFecEncoder struct {
+	Packets int // FEC packets generated
+
+	scheme    int
+	handler   RtpPayload
+	param     interface{}
+	payload   uint8  // FEC payload type
+	red       int    // RED payload type, -1 if not encapsulated
+	ssrc      uint32 // FlexFEC stream SSRC
+	seq       uint16 // ULPFEC: media and FEC stream, FlexFEC: FEC stream
+	timestamp uint32 // last media timestamp
+	started   bool
+	frame     bool // frame in progress
+	window    int  // FlexFEC repair-window(us), 0 if not present
+
+	fec      int // FEC packets per group, from next frame
+	mask     RtpFecMaskFunc
+	frameFec int
+	media    [][]byte // current group
+	n        int
+	xor      rtpFecXor
+	buf      []byte
+}
+
+// NewRtpUlpfecEncoder create RFC5109 ULPFEC generator
+// @param[in] handler inner packer handler(e.g. network sender)
+// @param[in] payload ULPFEC payload type
+// @param[in] red RFC2198 RED payload type, media and FEC packets are encapsulated as primary block, -1 if not used
+func NewRtpUlpfecEncoder(handler RtpPayload, payload uint8, red int) *RtpFecEncoder {
+	return &RtpFecEncoder{scheme: RTP_FEC_ULPFEC, handler: handler, payload: payload, red: red, mask: RtpFecMaskInterleaved}
+}
+
+// NewRtpFlexfecEncoder create RFC8627 FlexFEC generator
+// @param[in] handler inner packer handler(e.g. network sender)
+// @param[in] payload FlexFEC payload type
+// @param[in] seq first FlexFEC sequence number, random
+// @param[in] ssrc FlexFEC stream SSRC
+func NewRtpFlexfecEncoder(handler RtpPayload, payload uint8, seq uint16, ssrc uint32) *RtpFecEncoder {
+	return &RtpFecEncoder{scheme: RTP_FEC_FLEXFEC, handler: handler, payload: payload, red: -1, seq: seq, ssrc: ssrc, mask: RtpFecMaskInterleaved}
+}
+
+// SetProtection set FEC packets per frame(or per RtpFecMaxMediaPackets packets), take effect from the next frame
+// @param[in] fec FEC packets per group, 0-disable
+// @param[in] mask protection masks, nil-RtpFecMaskInterleaved
+func (e *RtpFecEncoder) SetProtection(fec int, mask RtpFecMaskFunc) {
+	if mask == nil {
+		mask = RtpFecMaskInterleaved
+	}
+	e.fec, e.mask = fec, mask
+}
+
+// SetRepairWindow set FlexFEC repair-window parameter, time span of protected packets(us)
+func (e *RtpFecEncoder) SetRepairWindow(us int) {
+	e.window = us
+}
+
+// GetInfo return next sequence number and last timestamp,
+// ULPFEC: renumbered media stream, FlexFEC: FEC stream
+func (e *RtpFecEncoder) GetInfo() (seq uint16, timestamp uint32) {
+	return e.seq, e.timestamp
+}
+
+// Sdp add FEC(and RED) format and rtpmap to the media description, FlexFEC
+// also need a=ssrc-group:FEC-FR <media ssrc> <fec ssrc>
+/*
+   m=video 30000 RTP/AVP 96 116 117
+   a=rtpmap:116 red/90000
+   a=rtpmap:117 ulpfec/90000
+
+   m=video 30000 RTP/AVP 96 118
+   a=rtpmap:118 flexfec/90000
+   a=fmtp:118 repair-window=200000
+*/
+func (e *RtpFecEncoder) Sdp(media *sdp.SdpMedia) error {
+	if len(media.Formats) == 0 || media.Rtpmap(media.Formats[0]) == nil {
+		return errors.New("fec media payload not in sdp media.")
+	}
+	frequency := media.Rtpmap(media.Formats[0]).Frequency
+	add := func(payload int, encoding string) {
+		media.Formats = append(media.Formats, payload)
+		media.Rtpmaps = append(media.Rtpmaps, sdp.SdpRtpmap{Payload: payload, Encoding: encoding, Frequency: frequency})
+	}
+
+	if e.scheme == RTP_FEC_FLEXFEC {
+		add(int(e.payload), RtpFlexfecEncoding)
+		if e.window > 0 {
+			fmtp := sdp.SdpFmtp{Payload: int(e.payload)}
+			fmtp.Params = append(fmtp.Params, sdp.SdpParam{Name: "repair-window", Value: strconv.Itoa(e.window)})
+			media.Fmtps = append(media.Fmtps, fmtp)
+		}
+		return nil
+	}
+	if e.red >= 0 {
+		add(e.red, RtpRedEncoding)
+	}
+	add(int(e.payload), RtpUlpfecEncoding)
+	return nil
+}
+
+func (e *RtpFecEncoder) Alloc(param interface{}, bytes int) []byte {
+	return e.handler.Alloc(param, bytes)
+}
+
+func (e *RtpFecEncoder) Free(param interface{}, packet []byte) {
+	e.handler.Free(param, packet)
+}
+
+// Handle forward media packet, generate FEC packets at the end of frame
+func (e *RtpFecEncoder) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
+	var v rtp.RtpPacketView
+	if rtp.RtpPacketViewParse(&v, packet, bytes) != nil {
+		e.handler.Handle(param, packet, bytes, timestamp, flags)
+		return
+	}
+
+	if e.frame && v.Timestamp() != e.timestamp {
+		e.flush() // previous frame without marker bit, e.g. audio
+	}
+	if !e.frame {
+		e.frame = true
+		e.frameFec = e.fec
+	}
+	e.param = param
+	e.timestamp = v.Timestamp()
+
+	if e.scheme == RTP_FEC_ULPFEC {
+		if !e.started {
+			e.started = true
+			e.seq = v.SequenceNumber()
+		}
+		rtp.RtpWriteUint16(packet[rtp.RtpHeader_SeqNumOffset:], e.seq)
+		e.seq++
+	}
+
+	if e.red >= 0 {
+		// RED primary block carry payload only, padding removed before protection
+		payload := v.Payload()
+		header := bytes - len(payload) - int(v.Padding())*int(packet[bytes-1])
+		e.buf = append(append(e.buf[:0], packet[:header]...), payload...)
+		e.buf[0] &^= 1 << rtp.RtpHeader_PaddingShift
+		packet, bytes = e.buf, len(e.buf)
+	}
+
+	if e.frameFec > 0 {
+		if e.n < len(e.media) {
+			e.media[e.n] = append(e.media[e.n][:0], packet[:bytes]...)
+		} else {
+			e.media = append(e.media, append([]byte(nil), packet[:bytes]...))
+		}
+		e.n++
+	}
+
+	e.send(packet, bytes, timestamp, flags)
+	if v.Marker() != 0 {
+		e.flush()
+	} else if e.n >= RtpFecMaxMediaPackets {
+		e.generate()
+	}
+}
+
+// Flush generate FEC packets for the current frame, e.g. last audio packet
+func (e *RtpFecEncoder) Flush() {
+	e.flush()
+}
+
+func (e *RtpFecEncoder) flush() {
+	e.generate()
+	e.frame = false
+}
+
+func (e *RtpFecEncoder) send(packet []byte, bytes int, timestamp uint32, flags int) {
+	if e.red < 0 {
+		e.handler.Handle(e.param, packet, bytes, timestamp, flags)
+		return
+	}
+
+	header := rtp.RtpFixedHeader + int(packet[0]&0x0F)*4
+	if packet[0]&(1<<rtp.RtpHeader_ExtensionShift) != 0 {
+		header += 4 + int(rtp.RtpReadUint16(packet[header+2:]))*4
+	}
+	blocks := []RtpRedBlock{{Payload: packet[1] & 0x7F, Data: packet[header:bytes]}}
+	n := header + RtpRedSize(blocks)
+	red := e.handler.Alloc(e.param, n)
+	if len(red) < n {
+		return
+	}
+	copy(red, packet[:header])
+	red[1] = red[1]&0x80 | uint8(e.red)
+	if _, err := RtpRedWrite(blocks, red[header:n]); err == nil {
+		e.handler.Handle(e.param, red, n, timestamp, flags)
+	}
+	e.handler.Free(e.param, red)
+}
+
+func (e *RtpFecEncoder) generate() {
+	n := e.n
+	e.n = 0
+	if n == 0 || e.frameFec <= 0 {
+		return
+	}
+
+	fec := e.frameFec
+	if fec > n {
+		fec = n
+	}
+	for _, mask := range e.mask(n, fec) {
+		mask &= 1<<uint(n) - 1
+		if mask == 0 {
+			continue
+		}
+
+		// SN base: the minimum sequence number of the protected packets
+		var base uint16
+		var bits uint64
+		first := true
+		e.xor.reset()
+		for i := 0; i < n; i++ {
+			if mask&(1<<uint(i)) == 0 {
+				continue
+			}
+			seq := rtp.RtpReadUint16(e.media[i][rtp.RtpHeader_SeqNumOffset:])
+			if first {
+				first, base = false, seq
+			}
+			if off := seq - base; off < RtpFecMaxMediaPackets {
+				bits |= 1 << uint(off)
+				e.xor.add(e.media[i])
+			}
+		}
+
+		ssrc := rtp.RtpReadUint32(e.media[0][rtp.RtpHeader_SsrcOffset:])
+		if e.scheme == RTP_FEC_FLEXFEC {
+			e.flexfec(ssrc, base, bits)
+		} else {
+			e.ulpfec(ssrc, base, bits)
+		}
+	}
+}
+
+// RFC5109 7.3. FEC Header for FEC Packets (p9) / 7.4. FEC Level Header for FEC Packets (p10)
+/*
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |E|L|P|X|  CC   |M| PT recovery |            SN base            |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                          TS recovery                          |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |        length recovery        |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |       Protection Length       |             mask              |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |              mask cont. (present only when L = 1)             |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+func (e *RtpFecEncoder) ulpfec(ssrc uint32, base uint16, bits uint64) {
+	level := RtpUlpfecLevelShortLength
+	if bits>>16 != 0 {
+		level = RtpUlpfecLevelLongLength
+	}
+	n := rtp.RtpFixedHeader + RtpUlpfecHeaderLength + level + len(e.xor.payload)
+	pkt := make([]byte, n)
+	e.header(pkt, ssrc)
+
+	ptr := pkt[rtp.RtpFixedHeader:]
+	ptr[0] = e.xor.head[0] & 0x3F
+	if level == RtpUlpfecLevelLongLength {
+		ptr[0] |= 0x40 // L
+	}
+	ptr[1] = e.xor.head[1]
+	rtp.RtpWriteUint16(ptr[2:], base)
+	copy(ptr[4:8], e.xor.head[4:8])
+	copy(ptr[8:10], e.xor.head[2:4])
+
+	ptr = ptr[RtpUlpfecHeaderLength:]
+	rtp.RtpWriteUint16(ptr, uint16(len(e.xor.payload)))
+	if level == RtpUlpfecLevelShortLength {
+		rtp.RtpWriteUint16(ptr[2:], uint16(rtpFecMaskPack(bits, 0, 16)))
+	} else {
+		mask := rtpFecMaskPack(bits, 0, 48)
+		rtp.RtpWriteUint16(ptr[2:], uint16(mask>>32))
+		rtp.RtpWriteUint32(ptr[4:], uint32(mask))
+	}
+	copy(ptr[level:], e.xor.payload)
+	e.output(pkt)
+}
+
+// RFC8627 4.2.2.1. FEC Header with Flexible Mask (p14)
+/*
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |0|0|P|X|  CC   |M| PT recovery |        length recovery        |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                          TS recovery                          |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |           SN base_i           |k|          Mask [0-14]        |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |k|                   Mask [15-45] (optional)                   |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                     Mask [46-109] (optional)                  |
+   |                                                               |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+   The protected SSRC is the CSRC of the FlexFEC packet RTP header.
+*/
+func (e *RtpFecEncoder) flexfec(ssrc uint32, base uint16, bits uint64) {
+	masks := 2 // k bit and mask bytes
+	if bits>>15 != 0 {
+		masks = 6
+	}
+	if bits>>46 != 0 {
+		masks = 14
+	}
+	header := rtp.RtpFixedHeader + 4 // one CSRC
+	n := header + RtpFlexfecHeaderLength + masks + len(e.xor.payload)
+	pkt := make([]byte, n)
+	e.header(pkt, e.ssrc)
+	pkt[0] |= 1 // CC
+	rtp.RtpWriteUint32(pkt[rtp.RtpHeader_CsrcOffset:], ssrc)
+
+	ptr := pkt[header:]
+	ptr[0] = e.xor.head[0] & 0x3F
+	copy(ptr[1:8], e.xor.head[1:8])
+	rtp.RtpWriteUint16(ptr[8:], base)
+
+	m := uint16(rtpFecMaskPack(bits, 0, 15))
+	if masks == 2 {
+		m |= 0x8000 // k
+	}
+	rtp.RtpWriteUint16(ptr[10:], m)
+	if masks > 2 {
+		v := uint32(rtpFecMaskPack(bits, 15, 31))
+		if masks == 6 {
+			v |= 0x80000000
+		}
+		rtp.RtpWriteUint32(ptr[12:], v)
+	}
+	if masks > 6 {
+		v := rtpFecMaskPack(bits, 46, 64)
+		rtp.RtpWriteUint32(ptr[16:], uint32(v>>32))
+		rtp.RtpWriteUint32(ptr[20:], uint32(v))
+	}
+	copy(ptr[RtpFlexfecHeaderLength+masks:], e.xor.payload)
+	e.output(pkt)
+}
+
+// rtpFecMaskPack mask bits [from, from+width) to width bits, MSB first
+func rtpFecMaskPack(bits uint64, from, width int) uint64 {
+	var v uint64
+	for i := 0; i < width && from+i < 64; i++ {
+		if bits&(1<<uint(from+i)) != 0 {
+			v |= 1 << uint(width-1-i)
+		}
+	}
+	return v
+}
+
+// rtpFecMaskUnpack width bits MSB first to mask bits [from, from+width)
+// @return false if a bit beyond RtpFecMaxMediaPackets set
+func rtpFecMaskUnpack(v uint64, from, width int) (uint64, bool) {
+	var bits uint64
+	for i := 0; i < width; i++ {
+		if v&(1<<uint(width-1-i)) == 0 {
+			continue
+		}
+		if from+i >= RtpFecMaxMediaPackets {
+			return 0, false
+		}
+		bits |= 1 << uint(from+i)
+	}
+	return bits, true
+}
+
+func (e *RtpFecEncoder) header(pkt []byte, ssrc uint32) {
+	pkt[0] = rtp.RtpVersion << 6
+	pkt[1] = e.payload
+	rtp.RtpWriteUint16(pkt[rtp.RtpHeader_SeqNumOffset:], e.seq)
+	rtp.RtpWriteUint32(pkt[rtp.RtpHeader_TimestampOffset:], e.timestamp)
+	rtp.RtpWriteUint32(pkt[rtp.RtpHeader_SsrcOffset:], ssrc)
+	e.seq++
+}
+
+func (e *RtpFecEncoder) output(pkt []byte) {
+	e.Packets++
+	if e.red < 0 {
+		out := e.handler.Alloc(e.param, len(pkt))
+		if len(out) < len(pkt) {
+			return
+		}
+		copy(out, pkt)
+		e.handler.Handle(e.param, out, len(pkt), e.timestamp, 0)
+		e.handler.Free(e.param, out)
+		return
+	}
+	e.send(pkt, len(pkt), e.timestamp, 0)
+}
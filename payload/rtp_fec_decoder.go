@@ -0,0 +1,319 @@
+package payload
+
+import (
+	"errors"
+	"github.com/services-go/librtp/rtp"
+	"github.com/services-go/librtp/sdp"
+	"strconv"
+	"strings"
+)
+
+const RtpFecWindow = 256 // media packets kept for recovery
+
+type rtpFecMedia struct {
+	valid bool
+	seq   uint16
+	data  []byte
+}
+
+type rtpFecPacket struct {
+	ssrc    uint32 // protected SSRC
+	base    uint16 // SN base
+	mask    uint64 // bit i: SN base + i
+	head    [8]byte
+	payload []byte
+}
+
+// RtpFecDecoder RtpPayloadUnpacker recover lost media packets from ULPFEC or
+// FlexFEC packets, received and recovered packets are fed to the inner
+// unpacker(e.g. jitter buffer adapter), recovered packets arrive out of order.
+// One media source per decoder.
+type RtpFecDecoder struct {
+	Recovered int // media packets restored
+	Discard   int // FEC packets malformed, unsupported or out of window
+
+	scheme   int
+	unpacker RtpPayloadUnpacker
+	payload  uint8         // FEC payload type
+	red      int           // RED payload type, -1 if not encapsulated
+	ssrc     uint32        // FlexFEC stream SSRC, 0-any
+	media    []rtpFecMedia // ring buffer, index seq % RtpFecWindow
+	fecs     []*rtpFecPacket
+	highest  uint16
+	started  bool
+	xor      rtpFecXor
+	buf      []byte
+}
+
+// NewRtpUlpfecDecoder create RFC5109 ULPFEC decoder
+// @param[in] payload ULPFEC payload type
+// @param[in] red RFC2198 RED payload type, -1 if not used
+func NewRtpUlpfecDecoder(unpacker RtpPayloadUnpacker, payload uint8, red int) *RtpFecDecoder {
+	return &RtpFecDecoder{scheme: RTP_FEC_ULPFEC, unpacker: unpacker, payload: payload, red: red, media: make([]rtpFecMedia, RtpFecWindow)}
+}
+
+// NewRtpFlexfecDecoder create RFC8627 FlexFEC decoder
+// @param[in] payload FlexFEC payload type
+// @param[in] ssrc FlexFEC stream SSRC, 0-any
+func NewRtpFlexfecDecoder(unpacker RtpPayloadUnpacker, payload uint8, ssrc uint32) *RtpFecDecoder {
+	return &RtpFecDecoder{scheme: RTP_FEC_FLEXFEC, unpacker: unpacker, payload: payload, red: -1, ssrc: ssrc, media: make([]rtpFecMedia, RtpFecWindow)}
+}
+
+// RtpFecDecoderSdp create FEC decoder from red/ulpfec/flexfec rtpmap and ssrc-group FEC-FR lines,
+// return nil if no FEC format in media
+// a=ssrc-group:FEC-FR <media ssrc> <fec ssrc>
+func RtpFecDecoderSdp(media *sdp.SdpMedia, unpacker RtpPayloadUnpacker) (*RtpFecDecoder, error) {
+	red, ulpfec, flexfec := -1, -1, -1
+	for _, r := range media.Rtpmaps {
+		switch {
+		case strings.EqualFold(r.Encoding, RtpRedEncoding):
+			red = r.Payload
+		case strings.EqualFold(r.Encoding, RtpUlpfecEncoding):
+			ulpfec = r.Payload
+		case strings.EqualFold(r.Encoding, RtpFlexfecEncoding):
+			flexfec = r.Payload
+		}
+	}
+
+	switch {
+	case ulpfec >= 0:
+		return NewRtpUlpfecDecoder(unpacker, uint8(ulpfec), red), nil
+	case flexfec >= 0:
+		var ssrc uint64
+		for _, a := range media.Attributes {
+			fields := strings.Fields(a.Value)
+			if a.Name != "ssrc-group" || len(fields) != 3 || fields[0] != "FEC-FR" {
+				continue
+			}
+			var err error
+			if ssrc, err = strconv.ParseUint(fields[2], 10, 32); err != nil {
+				return nil, errors.New("sdp ssrc-group error: " + a.Value)
+			}
+		}
+		return NewRtpFlexfecDecoder(unpacker, uint8(flexfec), uint32(ssrc)), nil
+	}
+	return nil, nil
+}
+
+func (d *RtpFecDecoder) Init(handler RtpPayload, param interface{}) {
+	d.unpacker.Init(handler, param)
+}
+
+func (d *RtpFecDecoder) Destroy() {
+	d.unpacker.Destroy()
+}
+
+// Input keep media packet and feed the inner unpacker, keep FEC packet,
+// then feed recovered packets to the inner unpacker
+// @return 1-packet handled, 0-packet discard, <0-failed
+func (d *RtpFecDecoder) Input(packet []byte, bytes int) (int, error) {
+	var v rtp.RtpPacketView
+	if err := rtp.RtpPacketViewParse(&v, packet, bytes); err != nil {
+		return 0, err
+	}
+
+	if d.red >= 0 && v.PayloadType() == uint8(d.red) {
+		blocks, err := RtpRedParse(v.Payload())
+		if err != nil {
+			return 0, err
+		}
+		// primary block only, redundant blocks ignored
+		primary := blocks[len(blocks)-1]
+		header := bytes - len(v.Payload()) - int(v.Padding())*int(packet[bytes-1])
+		d.buf = append(append(d.buf[:0], packet[:header]...), primary.Data...)
+		d.buf[0] &^= 1 << rtp.RtpHeader_PaddingShift
+		d.buf[1] = d.buf[1]&0x80 | primary.Payload
+		packet, bytes = d.buf, len(d.buf)
+		if err = rtp.RtpPacketViewParse(&v, packet, bytes); err != nil {
+			return 0, err
+		}
+	}
+
+	if v.PayloadType() == d.payload {
+		if d.scheme == RTP_FEC_FLEXFEC && d.ssrc != 0 && d.ssrc != v.SSRC() {
+			d.Discard++ // FlexFEC of another stream
+			return 0, nil
+		}
+		if f := d.parse(&v); f != nil {
+			d.fecs = append(d.fecs, f)
+		} else {
+			d.Discard++
+		}
+		return 1, d.recover()
+	}
+
+	d.store(packet[:bytes])
+	r, err := d.unpacker.Input(packet, bytes)
+	if e := d.recover(); e != nil && err == nil {
+		err = e
+	}
+	return r, err
+}
+
+func (d *RtpFecDecoder) store(packet []byte) {
+	seq := rtp.RtpReadUint16(packet[rtp.RtpHeader_SeqNumOffset:])
+	if !d.started || int16(seq-d.highest) > 0 {
+		d.started = true
+		d.highest = seq
+	}
+	m := &d.media[int(seq)%len(d.media)]
+	m.valid = true
+	m.seq = seq
+	m.data = append(m.data[:0], packet...)
+}
+
+func (d *RtpFecDecoder) get(seq uint16) []byte {
+	m := &d.media[int(seq)%len(d.media)]
+	if !m.valid || m.seq != seq || uint16(d.highest-seq) >= RtpFecWindow {
+		return nil
+	}
+	return m.data
+}
+
+// recover restore media packets protected by FEC packets with only one packet missing,
+// repeat until no more packet restored
+func (d *RtpFecDecoder) recover() error {
+	var err error
+	for progress := true; progress; {
+		progress = false
+		for i := 0; i < len(d.fecs); {
+			f := d.fecs[i]
+			missing, count := uint16(0), 0
+			for j := uint(0); j < RtpFecMaxMediaPackets; j++ {
+				if f.mask&(1<<j) != 0 && d.get(f.base+uint16(j)) == nil {
+					missing, count = f.base+uint16(j), count+1
+				}
+			}
+
+			old := d.started && int16(d.highest-f.base) >= RtpFecWindow-RtpFecMaxMediaPackets
+			if count > 1 && !old {
+				i++
+				continue
+			}
+			d.fecs = append(d.fecs[:i], d.fecs[i+1:]...)
+			if count != 1 {
+				continue
+			}
+
+			packet := d.restore(f, missing)
+			if packet == nil {
+				d.Discard++
+				continue
+			}
+			d.Recovered++
+			progress = true
+			d.store(packet)
+			if _, e := d.unpacker.Input(packet, len(packet)); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// RFC5109 10.4. Recovery of the RTP Header / RFC8627 6.3.2. Repair Packet Processing
+func (d *RtpFecDecoder) restore(f *rtpFecPacket, seq uint16) []byte {
+	d.xor.reset()
+	d.xor.head = f.head
+	d.xor.payload = append(d.xor.payload, f.payload...)
+	for j := uint(0); j < RtpFecMaxMediaPackets; j++ {
+		if s := f.base + uint16(j); f.mask&(1<<j) != 0 && s != seq {
+			d.xor.add(d.get(s))
+		}
+	}
+
+	n := int(rtp.RtpReadUint16(d.xor.head[2:]))
+	if n > len(f.payload) {
+		return nil // protection length less than packet
+	}
+	packet := make([]byte, rtp.RtpFixedHeader+n)
+	packet[0] = rtp.RtpVersion<<rtp.RtpHeader_VersionShift | d.xor.head[0]&0x3F
+	packet[1] = d.xor.head[1]
+	rtp.RtpWriteUint16(packet[rtp.RtpHeader_SeqNumOffset:], seq)
+	copy(packet[rtp.RtpHeader_TimestampOffset:], d.xor.head[4:8])
+	rtp.RtpWriteUint32(packet[rtp.RtpHeader_SsrcOffset:], f.ssrc)
+	copy(packet[rtp.RtpFixedHeader:], d.xor.payload[:n])
+
+	var v rtp.RtpPacketView
+	if rtp.RtpPacketViewParse(&v, packet, len(packet)) != nil {
+		return nil
+	}
+	return packet
+}
+
+func (d *RtpFecDecoder) parse(v *rtp.RtpPacketView) *rtpFecPacket {
+	ptr := v.Payload()
+	f := &rtpFecPacket{}
+	if d.scheme == RTP_FEC_FLEXFEC {
+		if v.CC() != 1 || len(ptr) < RtpFlexfecHeaderLength+2 || ptr[0]&0xC0 != 0 {
+			return nil // R/F bit: retransmission or fixed mask, not supported
+		}
+		f.ssrc = v.CSRC(0)
+		f.head[0] = ptr[0] & 0x3F
+		copy(f.head[1:8], ptr[1:8])
+		f.base = rtp.RtpReadUint16(ptr[8:])
+
+		m := rtp.RtpReadUint16(ptr[10:])
+		f.mask, _ = rtpFecMaskUnpack(uint64(m&0x7FFF), 0, 15)
+		ptr = ptr[RtpFlexfecHeaderLength+2:]
+		if m&0x8000 == 0 {
+			if len(ptr) < 4 {
+				return nil
+			}
+			v := rtp.RtpReadUint32(ptr)
+			bits, ok := rtpFecMaskUnpack(uint64(v&0x7FFFFFFF), 15, 31)
+			if !ok {
+				return nil
+			}
+			f.mask |= bits
+			ptr = ptr[4:]
+			if v&0x80000000 == 0 {
+				if len(ptr) < 8 {
+					return nil
+				}
+				if bits, ok = rtpFecMaskUnpack(uint64(rtp.RtpReadUint32(ptr))<<32|uint64(rtp.RtpReadUint32(ptr[4:])), 46, 64); !ok {
+					return nil
+				}
+				f.mask |= bits
+				ptr = ptr[8:]
+			}
+		}
+	} else {
+		if len(ptr) < RtpUlpfecHeaderLength+RtpUlpfecLevelShortLength || ptr[0]&0x80 != 0 {
+			return nil // E bit: header extension reserved
+		}
+		f.ssrc = v.SSRC()
+		f.head[0] = ptr[0] & 0x3F
+		f.head[1] = ptr[1]
+		f.base = rtp.RtpReadUint16(ptr[2:])
+		copy(f.head[4:8], ptr[4:8])
+		copy(f.head[2:4], ptr[8:10])
+
+		level := RtpUlpfecLevelShortLength
+		if ptr[0]&0x40 != 0 {
+			level = RtpUlpfecLevelLongLength
+		}
+		ptr = ptr[RtpUlpfecHeaderLength:]
+		if len(ptr) < level {
+			return nil
+		}
+		length := int(rtp.RtpReadUint16(ptr))
+		mask := uint64(rtp.RtpReadUint16(ptr[2:]))
+		width := 16
+		if level == RtpUlpfecLevelLongLength {
+			mask, width = mask<<32|uint64(rtp.RtpReadUint32(ptr[4:])), 48
+		}
+		f.mask, _ = rtpFecMaskUnpack(mask, 0, width)
+		ptr = ptr[level:]
+		if length > len(ptr) {
+			return nil
+		}
+		ptr = ptr[:length]
+	}
+
+	if f.mask == 0 {
+		return nil
+	}
+	f.payload = append([]byte(nil), ptr...)
+	return f
+}
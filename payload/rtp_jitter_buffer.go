@@ -0,0 +1,200 @@
+package payload
+
+import (
+	"errors"
+	"github.com/services-go/librtp/rtp"
+	"time"
+)
+
+// RtpPayloadSourcer is implemented by unpackers keep sequence number state,
+// the jitter buffer re-sync it when the source restarted
+type RtpPayloadSourcer interface {
+	Source() *rtp.RtpSource
+}
+
+type rtpJitterPacket struct {
+	seq     int64 // extended sequence number
+	arrival time.Time
+	data    []byte
+}
+
+// RtpJitterBuffer reorder RTP packets of one source before unpacking,
+// packets are released in sequence order, missing packets are skipped after
+// waiting latency or if more than depth packets are held, the unpacker
+// detect the sequence gap and set RTP_PAYLOAD_FLAG_PACKET_LOST. On source
+// restart, unpacker implements RtpPayloadSourcer is re-synced as well.
+// All times are supplied by caller so the buffer is deterministic.
+type RtpJitterBuffer struct {
+	Latency time.Duration // maximum time to wait a missing packet, 0-no timeout
+	Depth   int           // maximum packets held, 0-unlimited
+
+	Lost      int // packets skipped
+	Late      int // packets arrived after released or skipped
+	Duplicate int // packets already held
+	Bad       int // packets with sequence number out of window
+
+	unpacker RtpPayloadUnpacker
+	packets  []*rtpJitterPacket // sort by extended sequence number
+	free     []*rtpJitterPacket
+	started  bool
+	next     int64  // next extended sequence number to release
+	badSeq   uint16 // RFC3550 A.1 bad_seq, last 'bad' seq number + 1
+	bad      bool
+}
+
+// NewRtpJitterBuffer create jitter buffer in front of unpacker
+// @param[in] unpacker receive packets in sequence order
+// @param[in] latency maximum time to wait a missing packet, 0-no timeout
+// @param[in] depth maximum packets held, 0-unlimited
+func NewRtpJitterBuffer(unpacker RtpPayloadUnpacker, latency time.Duration, depth int) *RtpJitterBuffer {
+	return &RtpJitterBuffer{Latency: latency, Depth: depth, unpacker: unpacker}
+}
+
+// Input hold a RTP packet and release in-order packets to the unpacker
+// @param[in] packet RTP packet, copied
+// @param[in] bytes RTP packet length in bytes
+// @param[in] now packet arrival time
+// @return first unpacker error
+func (jb *RtpJitterBuffer) Input(packet []byte, bytes int, now time.Time) error {
+	if bytes < rtp.RtpFixedHeader || bytes > len(packet) {
+		return errors.New("rtp header need 12 bytes.")
+	}
+	seq := rtp.RtpReadUint16(packet[rtp.RtpHeader_SeqNumOffset:])
+
+	if !jb.started {
+		jb.started = true
+		jb.next = int64(seq)
+	}
+
+	var err error
+	ext := jb.next + int64(int16(seq-uint16(jb.next)))
+	if ext-jb.next > rtp.RtpMaxDropout || jb.next-ext > rtp.RtpMaxMisorder {
+		// RFC3550 A.1: two sequential packets -- assume that the other side
+		// restarted without telling us so just re-sync
+		if !jb.bad || seq != jb.badSeq {
+			jb.bad = true
+			jb.badSeq = seq + 1
+			jb.Bad++
+			return nil
+		}
+		err = jb.Flush()
+		jb.next = int64(seq)
+		ext = jb.next
+		if s, ok := jb.unpacker.(RtpPayloadSourcer); ok {
+			s.Source().Resync(seq) // don't discard the first packet as bad
+		}
+	}
+	jb.bad = false
+
+	if ext < jb.next {
+		jb.Late++
+		return err
+	}
+
+	i := len(jb.packets)
+	for i > 0 && jb.packets[i-1].seq >= ext {
+		i--
+	}
+	if i < len(jb.packets) && jb.packets[i].seq == ext {
+		jb.Duplicate++
+		return nil
+	}
+
+	pkt := jb.alloc()
+	pkt.seq = ext
+	pkt.arrival = now
+	pkt.data = append(pkt.data[:0], packet[:bytes]...)
+	jb.packets = append(jb.packets, nil)
+	copy(jb.packets[i+1:], jb.packets[i:])
+	jb.packets[i] = pkt
+	if e := jb.Poll(now); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
+
+// Poll release in-order packets, skip missing packets timeout or exceed depth
+// @param[in] now current time
+// @return first unpacker error
+func (jb *RtpJitterBuffer) Poll(now time.Time) error {
+	var err error
+	for len(jb.packets) > 0 {
+		pkt := jb.packets[0]
+		if pkt.seq != jb.next {
+			if !jb.expired(now) {
+				break
+			}
+			jb.Lost += int(pkt.seq - jb.next)
+			jb.next = pkt.seq
+		}
+
+		if e := jb.release(pkt); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Flush release all packets, missing packets are skipped
+// @return first unpacker error
+func (jb *RtpJitterBuffer) Flush() error {
+	var err error
+	for len(jb.packets) > 0 {
+		pkt := jb.packets[0]
+		jb.Lost += int(pkt.seq - jb.next)
+		jb.next = pkt.seq
+		if e := jb.release(pkt); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Next return time the oldest held packet timeout, zero if no packet held or no timeout,
+// call Poll at that time
+func (jb *RtpJitterBuffer) Next() time.Time {
+	if len(jb.packets) == 0 || jb.Latency <= 0 {
+		return time.Time{}
+	}
+	return jb.oldest().Add(jb.Latency)
+}
+
+// Len return packets held
+func (jb *RtpJitterBuffer) Len() int {
+	return len(jb.packets)
+}
+
+func (jb *RtpJitterBuffer) expired(now time.Time) bool {
+	if jb.Depth > 0 && len(jb.packets) > jb.Depth {
+		return true
+	}
+	return jb.Latency > 0 && !now.Before(jb.oldest().Add(jb.Latency))
+}
+
+// arrival time of the first packet waiting for the gap
+func (jb *RtpJitterBuffer) oldest() time.Time {
+	t := jb.packets[0].arrival
+	for _, pkt := range jb.packets[1:] {
+		if pkt.arrival.Before(t) {
+			t = pkt.arrival
+		}
+	}
+	return t
+}
+
+func (jb *RtpJitterBuffer) release(pkt *rtpJitterPacket) error {
+	jb.packets = jb.packets[1:]
+	jb.next = pkt.seq + 1
+	_, err := jb.unpacker.Input(pkt.data, len(pkt.data))
+	jb.free = append(jb.free, pkt)
+	return err
+}
+
+func (jb *RtpJitterBuffer) alloc() *rtpJitterPacket {
+	if n := len(jb.free); n > 0 {
+		pkt := jb.free[n-1]
+		jb.free = jb.free[:n-1]
+		return pkt
+	}
+	return &rtpJitterPacket{}
+}
@@ -0,0 +1,127 @@
+package payload
+
+import (
+	"github.com/services-go/librtp/rtp"
+	"time"
+)
+
+type rtpHistoryPacket struct {
+	valid     bool
+	seq       uint16
+	timestamp uint32
+	resent    time.Time // last retransmission time
+	data      []byte
+}
+
+// RtpPacketHistory RtpPayload handler keep a bounded history of recently sent
+// packets keyed by sequence number, forward packets to the inner handler and
+// answer RFC4585 Generic NACK by sending stored packets again.
+// The history must be the last handler before the network, after any handler
+// rewrite sequence numbers(e.g. ULPFEC RtpFecEncoder), NACKs carry wire sequence numbers.
+// e.g. history := NewRtpPacketHistory(transport, 512)
+// payload.RtpPayloadCreate(96, "H264", seq, ssrc, 1400, history, unpackhandler, nil)
+type RtpPacketHistory struct {
+	Retransmitted int // packets sent again
+	Missed        int // requested packets not in history
+	Throttled     int // requested again within one round-trip time
+
+	handler RtpPayload
+	param   interface{}
+	packets []rtpHistoryPacket // ring buffer, index seq % size
+}
+
+// NewRtpPacketHistory create history in front of packet handler
+// @param[in] handler inner packer handler(e.g. network sender)
+// @param[in] size packets kept, 1~65536
+func NewRtpPacketHistory(handler RtpPayload, size int) *RtpPacketHistory {
+	if size < 1 {
+		size = 1
+	} else if size > rtp.RtpSeqMod {
+		size = rtp.RtpSeqMod
+	}
+	return &RtpPacketHistory{handler: handler, packets: make([]rtpHistoryPacket, size)}
+}
+
+func (h *RtpPacketHistory) Alloc(param interface{}, bytes int) []byte {
+	return h.handler.Alloc(param, bytes)
+}
+
+func (h *RtpPacketHistory) Free(param interface{}, packet []byte) {
+	h.handler.Free(param, packet)
+}
+
+// Handle copy packet to history, then forward to inner handler
+func (h *RtpPacketHistory) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
+	if bytes >= rtp.RtpFixedHeader {
+		seq := rtp.RtpReadUint16(packet[rtp.RtpHeader_SeqNumOffset:])
+		p := &h.packets[int(seq)%len(h.packets)]
+		p.valid = true
+		p.seq = seq
+		p.timestamp = timestamp
+		p.resent = time.Time{}
+		p.data = append(p.data[:0], packet[:bytes]...)
+	}
+	h.param = param
+	h.handler.Handle(param, packet, bytes, timestamp, flags)
+}
+
+// Get return stored RTP packet, valid until overwritten by a later packet
+// @return packet, timestamp, false if not in history
+func (h *RtpPacketHistory) Get(seq uint16) ([]byte, uint32, bool) {
+	p := &h.packets[int(seq)%len(h.packets)]
+	if !p.valid || p.seq != seq {
+		return nil, 0, false
+	}
+	return p.data, p.timestamp, true
+}
+
+// OnNack send requested packets again through the inner handler
+// @param[in] nack Generic NACK from RtcpCompoundDeserialize
+// @param[in] now current time
+// @param[in] rtt round-trip time, a packet is sent at most once per rtt, 0-no limit
+// @return packets sent
+func (h *RtpPacketHistory) OnNack(nack *rtp.RtcpNack, now time.Time, rtt time.Duration) int {
+	return h.Retransmit(nack.Lost, now, rtt, h.handler)
+}
+
+// Retransmit send requested packets through handler, e.g. RTX wrapper
+// @param[in] lost requested sequence numbers
+// @param[in] handler receive stored packets, flags RTP_PAYLOAD_FLAG_RETRANSMISSION
+// @return packets sent
+func (h *RtpPacketHistory) Retransmit(lost []uint16, now time.Time, rtt time.Duration, handler RtpPayload) int {
+	if handler == nil {
+		return 0
+	}
+
+	n := 0
+	for _, seq := range lost {
+		p := &h.packets[int(seq)%len(h.packets)]
+		if !p.valid || p.seq != seq {
+			h.Missed++
+			continue
+		}
+		if !p.resent.IsZero() && now.Sub(p.resent) < rtt {
+			h.Throttled++
+			continue
+		}
+
+		p.resent = now
+		packet := handler.Alloc(h.param, len(p.data))
+		if len(packet) < len(p.data) {
+			break
+		}
+		copy(packet, p.data)
+		handler.Handle(h.param, packet, len(p.data), p.timestamp, RTP_PAYLOAD_FLAG_RETRANSMISSION)
+		handler.Free(h.param, packet)
+		n++
+	}
+	h.Retransmitted += n
+	return n
+}
+
+// Reset drop all stored packets, e.g. after SSRC change
+func (h *RtpPacketHistory) Reset() {
+	for i := range h.packets {
+		h.packets[i].valid = false
+	}
+}
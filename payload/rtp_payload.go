@@ -4,10 +4,14 @@ import (
 	"errors"
 	"github.com/services-go/librtp/rtp"
 	"strconv"
+	"strings"
+	"time"
 )
 
 const (
-	RTP_PAYLOAD_FLAG_PACKET_LOST = 1
+	RTP_PAYLOAD_FLAG_PACKET_LOST    = 1
+	RTP_PAYLOAD_FLAG_NOT_SYNCED     = 2 // presentation time not on the common timeline, see RtpLipSync
+	RTP_PAYLOAD_FLAG_RETRANSMISSION = 4 // packer handler: packet sent again by RtpPacketHistory/RtpRtxPacker
 )
 
 type RtpPayload interface {
@@ -58,6 +62,7 @@ type RtpPayloadUnpacker interface {
 type RtpPayloadDelegate struct {
 	Packer   RtpPayloadPacker
 	Unpacker RtpPayloadUnpacker
+	Profile  *rtp.RtpProfile // payload type, encoding name and clock rate
 	//RtpMaxPacketSize int // default 1434 from VLC
 }
 
@@ -68,12 +73,45 @@ func RtpPayloadCreate(payload int, name string, seq uint16, ssrc uint32, packsiz
 	if err != nil {
 		return nil, err
 	}
+
+	if payload >= 96 {
+		delegate.Profile = rtp.RtpProfileFindByName(name)
+	} else {
+		delegate.Profile = rtp.RtpProfileFind(payload)
+	}
+	if delegate.Profile == nil {
+		return nil, errors.New("rtp unknown clock rate: " + name)
+	}
+	delegate.Profile.Payload = payload
 	//size := delegate.RtpPacketGetSize()
 	delegate.Packer.Init(packsize, uint8(payload), seq, ssrc, packhandler, cbparam)
 	delegate.Unpacker.Init(unpackhandler, cbparam)
 	return delegate, nil
 }
 
+// RtpPayloadCreateProfile create payload delegate from session payload type mapping
+// @param[in] profiles static and registered dynamic payload types of the session
+// @param[in] payload RTP header PT field
+func RtpPayloadCreateProfile(profiles *rtp.RtpProfileTable, payload int, seq uint16, ssrc uint32, packsize int,
+	packhandler RtpPayload, unpackhandler RtpPayload, cbparam interface{}) (*RtpPayloadDelegate, error) {
+	profile := profiles.Find(payload)
+	if profile == nil {
+		return nil, errors.New("rtp payload not registered: " + strconv.Itoa(payload))
+	}
+
+	delegate, err := RtpPayloadCreate(payload, profile.EncodingName(), seq, ssrc, packsize, packhandler, unpackhandler, cbparam)
+	if err != nil {
+		return nil, err
+	}
+	delegate.Profile = profile // registered clock rate and channels
+	return delegate, nil
+}
+
+// RtpPayloadTimestamp convert duration to RTP timestamp with the payload clock rate
+func (de *RtpPayloadDelegate) RtpPayloadTimestamp(d time.Duration) uint32 {
+	return de.Profile.Timestamp(d)
+}
+
 func (de *RtpPayloadDelegate) RtpPayloadPackerDestroy() {
 	de.Packer.Destroy()
 }
@@ -86,6 +124,24 @@ func (de *RtpPayloadDelegate) RtpPayloadPackerInput(data []byte, bytes int, time
 	return de.Packer.Input(data, bytes, timestamp)
 }
 
+// RtpPayloadPackerSetPadding pad every packet to multiple of block bytes
+func (de *RtpPayloadDelegate) RtpPayloadPackerSetPadding(block int) error {
+	padder, ok := de.Packer.(RtpPayloadPadder)
+	if !ok {
+		return errors.New("rtp packer not support padding.")
+	}
+	return padder.SetPadding(block)
+}
+
+// RtpPayloadPackerProbe send padding-only probe packets
+func (de *RtpPayloadDelegate) RtpPayloadPackerProbe(bytes int) error {
+	padder, ok := de.Packer.(RtpPayloadPadder)
+	if !ok {
+		return errors.New("rtp packer not support padding.")
+	}
+	return padder.Probe(bytes)
+}
+
 func (de *RtpPayloadDelegate) RtpPayloadUnpackerDestroy() {
 	de.Unpacker.Destroy()
 }
@@ -107,27 +163,26 @@ func (de *RtpPayloadDelegate) rtpPayloadFind(payload int, encoding string) error
 		return errors.New("rtp error payload: " + strconv.Itoa(payload))
 	}
 	if payload >= 96 && len(encoding) > 0 {
-		switch encoding {
+		// encoding name is case insensitive
+		switch strings.ToUpper(encoding) {
 		case "H264":
 			// H.264 video (MPEG-4 Part 10) (RFC 6184)
 			de.Packer = &RtpPackH264{}
 			de.Unpacker = &RtpUnpackH264{}
-		case "H265":
-		case "HEVC":
+		case "H265", "HEVC":
 			// H.265 video (HEVC) (RFC 7798)
 			return errors.New("not support h265.")
-		case "mpeg4-generic":
-		case "AAC":
+		case "MPEG4-GENERIC", "AAC":
 			/// RFC3640 RTP Payload Format for Transport of MPEG-4 Elementary Streams
 			/// 4.1. MIME Type Registration (p27)
 			de.Packer = &RtpPackMpeg4Generic{}
 			de.Unpacker = &RtpUnpackMpeg4Generic{}
-		case "OPUS": // RFC7587 RTP Payload Format for the Opus Speech and Audio Codec
-		case "G726-16": // ITU-T G.726 audio 16 kbit/s (RFC 3551)
-		case "G726-24": // ITU-T G.726 audio 24 kbit/s (RFC 3551)
-		case "G726-32": // ITU-T G.726 audio 32 kbit/s (RFC 3551)
-		case "G726-40": // ITU-T G.726 audio 40 kbit/s (RFC 3551)
-		case "G7221": // RFC5577 RTP Payload Format for ITU-T Recommendation G.722.1
+		case "OPUS", // RFC7587 RTP Payload Format for the Opus Speech and Audio Codec
+			"G726-16", // ITU-T G.726 audio 16 kbit/s (RFC 3551)
+			"G726-24", // ITU-T G.726 audio 24 kbit/s (RFC 3551)
+			"G726-32", // ITU-T G.726 audio 32 kbit/s (RFC 3551)
+			"G726-40", // ITU-T G.726 audio 40 kbit/s (RFC 3551)
+			"G7221":   // RFC5577 RTP Payload Format for ITU-T Recommendation G.722.1
 			de.Packer = &RtpCommPack{}
 			de.Unpacker = &RtpCommUnpack{}
 		default:
@@ -135,10 +190,10 @@ func (de *RtpPayloadDelegate) rtpPayloadFind(payload int, encoding string) error
 		}
 	} else {
 		switch payload {
-		case rtp.RTP_PAYLOAD_PCMU: // ITU-T G.711 PCM u-Law audio 64 kbit/s (RFC 3551)
-		case rtp.RTP_PAYLOAD_PCMA: // ITU-T G.711 PCM A-Law audio 64 kbit/s (RFC 3551)
-		case rtp.RTP_PAYLOAD_G722: // ITU-T G.722 audio 64 kbit/s (RFC 3551)
-		case rtp.RTP_PAYLOAD_G729: // ITU-T G.729 and G.729a audio 8 kbit/s (RFC 3551)
+		case rtp.RTP_PAYLOAD_PCMU, // ITU-T G.711 PCM u-Law audio 64 kbit/s (RFC 3551)
+			rtp.RTP_PAYLOAD_PCMA, // ITU-T G.711 PCM A-Law audio 64 kbit/s (RFC 3551)
+			rtp.RTP_PAYLOAD_G722, // ITU-T G.722 audio 64 kbit/s (RFC 3551)
+			rtp.RTP_PAYLOAD_G729: // ITU-T G.729 and G.729a audio 8 kbit/s (RFC 3551)
 			de.Packer = &RtpCommPack{}
 			de.Unpacker = &RtpCommUnpack{}
 		default:
@@ -8,9 +8,9 @@ import (
 type RtpPayloadHelper struct {
 	handler   RtpPayload
 	cbparam   interface{}
-	lost      int    // wait for next frame
-	flags     int    // lost packet
-	seq       uint16 // rtp seq
+	lost      int           // wait for next frame
+	flags     int           // lost packet
+	source    rtp.RtpSource // rtp seq
 	timestamp uint32
 	ptr       []byte
 	size      int
@@ -23,33 +23,47 @@ func (h *RtpPayloadHelper) Destroy() {
 	}
 }
 
-func (h *RtpPayloadHelper) RtpPayloadCheck(pkt *rtp.RtpPacket) {
+// Source return sequence number state of the unpacked stream
+func (h *RtpPayloadHelper) Source() *rtp.RtpSource {
+	return &h.source
+}
+
+// RtpPayloadSequence check sequence number (RFC3550 A.1)
+// @return RTP_SEQ_XXX
+func (h *RtpPayloadHelper) RtpPayloadSequence(pkt *rtp.RtpPacketView) int {
 	// first packet only
 	if h.flags == -1 {
 		h.flags = 0
-		h.seq = pkt.Header.SequenceNumber - 1  // disable packet lost
-		h.timestamp = pkt.Header.Timestamp + 1 // flag for new frame
+		h.source.Start(pkt.SequenceNumber(), 0) // disable packet lost
+		h.timestamp = pkt.Timestamp() + 1       // flag for new frame
 	}
+	return h.source.Update(pkt.SequenceNumber())
+}
 
-	// check sequence number
-	if pkt.Header.SequenceNumber != h.seq+1 {
+// RtpPayloadCheck check sequence number and frame boundary
+// @return false-late/duplicate packet, should be discarded
+func (h *RtpPayloadHelper) RtpPayloadCheck(pkt *rtp.RtpPacketView) bool {
+	switch h.RtpPayloadSequence(pkt) {
+	case rtp.RTP_SEQ_PROBATION, rtp.RTP_SEQ_LATE, rtp.RTP_SEQ_BAD:
+		return false // don't break current frame
+	case rtp.RTP_SEQ_LOST, rtp.RTP_SEQ_RESTART:
 		h.size = 0
 		h.lost = 1
 		h.flags |= RTP_PAYLOAD_FLAG_PACKET_LOST
-		h.timestamp = pkt.Header.Timestamp
+		h.timestamp = pkt.Timestamp()
 	}
-	h.seq = pkt.Header.SequenceNumber
 
 	// check timestamp
-	if pkt.Header.Timestamp != h.timestamp {
+	if pkt.Timestamp() != h.timestamp {
 		h.RtpPayloadOnFrame()
 	}
-	h.timestamp = pkt.Header.Timestamp
+	h.timestamp = pkt.Timestamp()
+	return true
 }
 
-func (h *RtpPayloadHelper) RtpPayloadWrite(pkt *rtp.RtpPacket) error {
-	if h.size+pkt.PayloadLen > h.capacity {
-		size := h.size + pkt.PayloadLen + 8000
+func (h *RtpPayloadHelper) RtpPayloadWrite(payload []byte) error {
+	if h.size+len(payload) > h.capacity {
+		size := h.size + len(payload) + 8000
 		ptr := make([]byte, size)
 		if h.ptr != nil {
 			copy(ptr, h.ptr)
@@ -58,11 +72,11 @@ func (h *RtpPayloadHelper) RtpPayloadWrite(pkt *rtp.RtpPacket) error {
 		h.capacity = size
 	}
 
-	if h.capacity < h.size+pkt.PayloadLen {
+	if h.capacity < h.size+len(payload) {
 		return errors.New("payload helper capacity error.")
 	}
-	copy(h.ptr[h.size:], pkt.Payload[:pkt.PayloadLen])
-	h.size += pkt.PayloadLen
+	copy(h.ptr[h.size:], payload)
+	h.size += len(payload)
 	return nil
 }
 
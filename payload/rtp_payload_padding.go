@@ -0,0 +1,86 @@
+package payload
+
+import (
+	"errors"
+	"github.com/services-go/librtp/rtp"
+)
+
+const (
+	RTP_PAYLOAD_PADDING_MAX = 255 // padding count is 8 bits
+)
+
+// RtpPayloadPadder is implemented by packers support RTP padding
+type RtpPayloadPadder interface {
+	// pad every RTP packet to multiple of block bytes(e.g. SRTP cipher block)
+	// @param[in] block alignment 2~255, 0-disable padding
+	SetPadding(block int) error
+
+	// send padding-only packets for bandwidth probing, consume sequence numbers
+	// of the stream and reuse the last timestamp, marker bit always 0,
+	// unpackers update sequence number state and drop them
+	// @param[in] bytes total padding bytes to send, split into packets of 255 bytes at most
+	Probe(bytes int) error
+}
+
+func rtpPayloadCheckPadding(block int) error {
+	if block < 0 || block > RTP_PAYLOAD_PADDING_MAX {
+		return errors.New("rtp padding block error.")
+	}
+	return nil
+}
+
+// rtpPayloadPaddingMax reserved bytes for padding in a packet of size limit
+func rtpPayloadPaddingMax(block int) int {
+	if block <= 1 {
+		return 0
+	}
+	return block - 1
+}
+
+// rtpPayloadProbe send padding-only packets
+// @param[in] size maximum RTP packet size
+func rtpPayloadProbe(pkt *rtp.RtpPacket, handler RtpPayload, cbparam interface{}, size int, bytes int) error {
+	limit := size - rtp.RtpFixedHeader
+	if limit > RTP_PAYLOAD_PADDING_MAX {
+		limit = RTP_PAYLOAD_PADDING_MAX
+	}
+	if limit < 1 {
+		return errors.New("rtp packet size too small.")
+	}
+
+	var err error
+	var n int
+	for marker := pkt.Header.Marker; bytes > 0; pkt.Header.SequenceNumber++ {
+		padlen := bytes
+		if padlen > limit {
+			padlen = limit
+		}
+
+		n = rtp.RtpFixedHeader + padlen
+		rtpb := handler.Alloc(cbparam, n)
+		if rtpb == nil {
+			return errors.New("alloc rtp buffer failed.")
+		}
+
+		pkt.Header.Marker = 0
+		pkt.PayloadLen = 0
+		n, err = rtp.RtpPacketSerializeHeader(pkt, rtpb, n)
+		pkt.Header.Marker = marker
+		if err != nil {
+			return err
+		}
+		if n != rtp.RtpFixedHeader {
+			return errors.New("rtp packet serialize header failed.")
+		}
+
+		n, err = rtp.RtpPacketPadding(rtpb, n, padlen)
+		if err != nil {
+			return err
+		}
+
+		handler.Handle(cbparam, rtpb, n, pkt.Header.Timestamp, 0)
+		handler.Free(cbparam, rtpb)
+		bytes -= padlen
+	}
+	return nil
+}
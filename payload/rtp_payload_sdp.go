@@ -0,0 +1,137 @@
+package payload
+
+import (
+	"errors"
+	"github.com/services-go/librtp/rtp"
+	"github.com/services-go/librtp/sdp"
+	"strconv"
+)
+
+// RtpPayloadFmtper is implemented by packers can describe themselves as SDP fmtp
+type RtpPayloadFmtper interface {
+	// Fmtp return format specific parameters matching the packed stream
+	Fmtp() *sdp.SdpFmtp
+}
+
+// RtpPayloadRtpmapper is implemented by packers know the clock rate from the stream,
+// e.g. AAC sampling rate from AudioSpecificConfig
+type RtpPayloadRtpmapper interface {
+	// Rtpmap return clock rate and channels of the packed stream, 0 if unknown
+	Rtpmap() (frequency, channels int)
+}
+
+// RtpPayloadCreateSdp create payload delegate from SDP media description,
+// clock rate and channels from rtpmap, packer/unpacker configured by fmtp
+// @param[in] media SDP media description
+// @param[in] payload RTP header PT field, one of the media formats
+func RtpPayloadCreateSdp(media *sdp.SdpMedia, payload int, seq uint16, ssrc uint32, packsize int,
+	packhandler RtpPayload, unpackhandler RtpPayload, cbparam interface{}) (*RtpPayloadDelegate, error) {
+	found := false
+	for _, pt := range media.Formats {
+		found = found || pt == payload
+	}
+	if !found {
+		return nil, errors.New("rtp payload not in sdp media: " + strconv.Itoa(payload))
+	}
+
+	profiles, err := media.Profiles()
+	if err != nil {
+		return nil, err
+	}
+	delegate, err := RtpPayloadCreateProfile(profiles, payload, seq, ssrc, packsize, packhandler, unpackhandler, cbparam)
+	if err != nil {
+		return nil, err
+	}
+
+	if fmtp := media.Fmtp(payload); fmtp != nil {
+		if err = delegate.rtpPayloadConfigure(fmtp); err != nil {
+			return nil, err
+		}
+	}
+	return delegate, nil
+}
+
+func (de *RtpPayloadDelegate) rtpPayloadConfigure(fmtp *sdp.SdpFmtp) error {
+	if p, ok := de.Packer.(*RtpPackH264); ok {
+		// RFC6184 8.1. Media Type Registration (p74)
+		mode, ok, err := fmtp.Int("packetization-mode")
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err = p.SetPacketizationMode(mode); err != nil {
+				return err
+			}
+		}
+	}
+
+	if up, ok := de.Unpacker.(*RtpUnpackMpeg4Generic); ok {
+		// RFC3640 4.1. Media Type Registration (p27)
+		// indexLength/indexDeltaLength default 0 if sizeLength present
+		sizeLength, ok, err := fmtp.Int("sizeLength")
+		if err != nil {
+			return err
+		}
+		if ok {
+			indexLength, _, err := fmtp.Int("indexLength")
+			if err != nil {
+				return err
+			}
+			indexDeltaLength, _, err := fmtp.Int("indexDeltaLength")
+			if err != nil {
+				return err
+			}
+			if err = up.SetAUHeader(sizeLength, indexLength, indexDeltaLength); err != nil {
+				return err
+			}
+			if err = de.Packer.(*RtpPackMpeg4Generic).SetAUHeader(sizeLength, indexLength); err != nil {
+				return err
+			}
+		}
+
+		config, ok, err := fmtp.Hex("config")
+		if err != nil {
+			return err
+		}
+		if ok {
+			up.SetConfig(config)
+		}
+	}
+	return nil
+}
+
+// RtpPayloadPackerSdp describe the packer as SDP media description,
+// rtpmap and fmtp may change after input, e.g. H.264 SPS/PPS, AAC ADTS sampling rate
+// @param[in] port RTP port of m= line
+// @return error if clock rate unknown, e.g. AAC before SetConfig or the first ADTS frame
+func (de *RtpPayloadDelegate) RtpPayloadPackerSdp(port int) (*sdp.SdpMedia, error) {
+	media := &sdp.SdpMedia{Media: "application", Port: port, Proto: "RTP/AVP"}
+	switch de.Profile.Avtype {
+	case rtp.RTP_AVTYPE_AUDIO:
+		media.Media = "audio"
+	case rtp.RTP_AVTYPE_VIDEO:
+		media.Media = "video"
+	}
+
+	frequency, channels := de.Profile.Frequency, de.Profile.Channels
+	if r, ok := de.Packer.(RtpPayloadRtpmapper); ok {
+		if f, c := r.Rtpmap(); f > 0 {
+			frequency, channels = f, c
+		}
+	}
+	if frequency <= 0 {
+		return nil, errors.New("rtp payload clock rate unknown.")
+	}
+	rtpmap := sdp.SdpRtpmap{Payload: de.Profile.Payload, Encoding: de.Profile.EncodingName(), Frequency: frequency}
+	if de.Profile.Avtype == rtp.RTP_AVTYPE_AUDIO && channels > 1 {
+		rtpmap.Channels = channels
+	}
+	media.Formats = []int{de.Profile.Payload}
+	media.Rtpmaps = []sdp.SdpRtpmap{rtpmap}
+	if f, ok := de.Packer.(RtpPayloadFmtper); ok {
+		if fmtp := f.Fmtp(); fmtp != nil {
+			media.Fmtps = []sdp.SdpFmtp{*fmtp}
+		}
+	}
+	return media, nil
+}
@@ -0,0 +1,165 @@
+package payload
+
+import (
+	"errors"
+	"github.com/services-go/librtp/rtp"
+	"time"
+)
+
+// RFC3550 6.4.1 SR: Sender Report RTCP Packet (p37)
+// The NTP timestamp of the sender reports of streams from the same
+// CNAME share a common wall-clock, the corresponding RTP timestamp
+// map media clock of each stream to the wall-clock for synchronization.
+
+// RtpSyncHandler receive unpacked frames on the common presentation timeline
+type RtpSyncHandler interface {
+	// @param[in] ssrc stream of the frame
+	// @param[in] pts presentation time relative to the timeline origin
+	// @param[in] timestamp RTP timestamp of the frame
+	// @param[in] flags RTP_PAYLOAD_FLAG_XXX, RTP_PAYLOAD_FLAG_NOT_SYNCED if pts is stream local
+	Handle(param interface{}, ssrc uint32, frame []byte, bytes int, pts time.Duration, timestamp uint32, flags int)
+}
+
+type rtpSyncStream struct {
+	ssrc      uint32
+	cname     string
+	clock     *rtp.RtpClockRecovery
+	first     int64  // extended RTP timestamp of the first frame
+	timestamp uint32 // RTP timestamp of the last frame
+	frames    int
+}
+
+// RtpLipSync synchronize streams of the same CNAME (e.g. audio and video of a camera)
+// by sender reports, and place unpacked frames on a common presentation timeline
+type RtpLipSync struct {
+	streams map[uint32]*rtpSyncStream
+	origin  time.Time // wall-clock time of presentation time 0
+}
+
+func NewRtpLipSync() *RtpLipSync {
+	return &RtpLipSync{streams: make(map[uint32]*rtpSyncStream)}
+}
+
+// AddStream add stream to synchronize
+// @param[in] ssrc stream SSRC
+// @param[in] frequency payload clock rate, e.g. RtpPayloadDelegate.Profile.Frequency
+func (s *RtpLipSync) AddStream(ssrc uint32, frequency int) {
+	if _, ok := s.streams[ssrc]; !ok {
+		s.streams[ssrc] = &rtpSyncStream{ssrc: ssrc, clock: rtp.NewRtpClockRecovery(frequency)}
+	}
+}
+
+// RemoveStream forget stream, e.g. BYE
+func (s *RtpLipSync) RemoveStream(ssrc uint32) {
+	delete(s.streams, ssrc)
+}
+
+// OnRtcp record sender reports and CNAME of the streams from a RTCP compound packet
+func (s *RtpLipSync) OnRtcp(pkts []rtp.RtcpPacket) {
+	for _, pkt := range pkts {
+		switch v := pkt.(type) {
+		case *rtp.RtcpSR:
+			if stream, ok := s.streams[v.SSRC]; ok {
+				stream.clock.OnSR(v)
+			}
+		case *rtp.RtcpSdes:
+			for _, stream := range s.streams {
+				if cname := v.CNAME(stream.ssrc); cname != nil {
+					stream.cname = string(cname)
+				}
+			}
+		}
+	}
+}
+
+// Synced return true if stream wall-clock is known
+func (s *RtpLipSync) Synced(ssrc uint32) bool {
+	stream, ok := s.streams[ssrc]
+	return ok && stream.clock.Synced()
+}
+
+// Offset return playout offset between the last frames of two streams,
+// positive if stream b is ahead of stream a,
+// e.g. delay audio playout by the offset to align with video
+// @param[in] a, b SSRC of streams with the same CNAME
+func (s *RtpLipSync) Offset(a, b uint32) (time.Duration, error) {
+	sa, sb := s.streams[a], s.streams[b]
+	if sa == nil || sb == nil {
+		return 0, errors.New("rtp sync stream not found.")
+	}
+	if len(sa.cname) == 0 || len(sb.cname) == 0 {
+		return 0, errors.New("rtp sync stream no cname.") // wait for RTCP SDES
+	}
+	if sa.cname != sb.cname {
+		return 0, errors.New("rtp sync streams cname mismatch.")
+	}
+	if sa.frames == 0 || sb.frames == 0 {
+		return 0, errors.New("rtp sync stream no frame.")
+	}
+
+	ta, ok := sa.clock.Time(sa.timestamp)
+	if !ok {
+		return 0, errors.New("rtp sync stream no sender report.")
+	}
+	tb, ok := sb.clock.Time(sb.timestamp)
+	if !ok {
+		return 0, errors.New("rtp sync stream no sender report.")
+	}
+	return tb.Sub(ta), nil
+}
+
+// Presentation return presentation time of a frame of stream,
+// the wall-clock time of the first synced frame is the timeline origin
+// @param[in] ssrc stream SSRC
+// @param[in] timestamp RTP timestamp of the frame
+// @return presentation time, false if stream not synced and the time is relative to the first frame of the stream
+func (s *RtpLipSync) Presentation(ssrc uint32, timestamp uint32) (time.Duration, bool) {
+	stream, ok := s.streams[ssrc]
+	if !ok {
+		return 0, false
+	}
+
+	ext := stream.clock.Extend(timestamp)
+	if stream.frames == 0 {
+		stream.first = ext
+	}
+	stream.frames++
+	stream.timestamp = timestamp
+
+	capture, ok := stream.clock.Time(timestamp)
+	if !ok {
+		return rtp.RtpTimestampToDuration(ext-stream.first, stream.clock.Frequency), false
+	}
+	if s.origin.IsZero() {
+		s.origin = capture
+	}
+	return capture.Sub(s.origin), true
+}
+
+// Handler return unpacker handler deliver frames of stream to h with presentation time
+// @param[in] ssrc stream SSRC, added with AddStream
+// @param[in] h frame handler
+func (s *RtpLipSync) Handler(ssrc uint32, h RtpSyncHandler) RtpPayload {
+	return &rtpSyncPayload{sync: s, ssrc: ssrc, handler: h}
+}
+
+type rtpSyncPayload struct {
+	sync    *RtpLipSync
+	ssrc    uint32
+	handler RtpSyncHandler
+}
+
+func (p *rtpSyncPayload) Alloc(param interface{}, bytes int) []byte {
+	return make([]byte, bytes)
+}
+
+func (p *rtpSyncPayload) Free(param interface{}, packet []byte) {
+}
+
+func (p *rtpSyncPayload) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
+	pts, synced := p.sync.Presentation(p.ssrc, timestamp)
+	if !synced {
+		flags |= RTP_PAYLOAD_FLAG_NOT_SYNCED
+	}
+	p.handler.Handle(param, p.ssrc, packet, bytes, pts, timestamp, flags)
+}
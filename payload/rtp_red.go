@@ -0,0 +1,346 @@
+package payload
+
+import (
+	"errors"
+	"github.com/services-go/librtp/rtp"
+	"github.com/services-go/librtp/sdp"
+	"strconv"
+	"strings"
+)
+
+// RFC2198 RTP Payload for Redundant Audio Data
+// 3. RTP Payload Format for Redundant Data (p3)
+/*
+    0                   1                    2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |F|   block PT  |  timestamp offset         |   block length    |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+   The final header:
+    0 1 2 3 4 5 6 7
+   +-+-+-+-+-+-+-+-+
+   |0|   Block PT  |
+   +-+-+-+-+-+-+-+-+
+*/
+const (
+	RtpRedHeaderLength       = 4
+	RtpRedFinalHeaderLength  = 1
+	RtpRedMaxTimestampOffset = 0x3FFF // 14 bits
+	RtpRedMaxBlockLength     = 0x3FF  // 10 bits
+	RtpRedEncoding           = "red"
+)
+
+// RtpRedBlock one block of RED payload, the last block is the primary encoding
+type RtpRedBlock struct {
+	Payload         uint8  // block PT
+	TimestampOffset uint16 // primary timestamp - block timestamp, 0 for primary
+	Data            []byte
+}
+
+// RtpRedParse parse RED payload(after RTP header), blocks reference payload
+// @return blocks, redundant blocks first, primary block last
+func RtpRedParse(payload []byte) ([]RtpRedBlock, error) {
+	var blocks []RtpRedBlock
+	ptr := payload
+	for {
+		if len(ptr) < 1 {
+			return nil, errors.New("rtp red header error.")
+		}
+		if ptr[0]&0x80 == 0 {
+			blocks = append(blocks, RtpRedBlock{Payload: ptr[0] & 0x7F})
+			ptr = ptr[RtpRedFinalHeaderLength:]
+			break
+		}
+		if len(ptr) < RtpRedHeaderLength {
+			return nil, errors.New("rtp red header error.")
+		}
+		v := uint32(ptr[1])<<16 | uint32(ptr[2])<<8 | uint32(ptr[3])
+		blocks = append(blocks, RtpRedBlock{
+			Payload:         ptr[0] & 0x7F,
+			TimestampOffset: uint16(v >> 10),
+			Data:            make([]byte, v&RtpRedMaxBlockLength), // length only, data filled below
+		})
+		ptr = ptr[RtpRedHeaderLength:]
+	}
+
+	for i := range blocks[:len(blocks)-1] {
+		n := len(blocks[i].Data)
+		if n > len(ptr) {
+			return nil, errors.New("rtp red block length error.")
+		}
+		blocks[i].Data = ptr[:n]
+		ptr = ptr[n:]
+	}
+	blocks[len(blocks)-1].Data = ptr
+	return blocks, nil
+}
+
+// RtpRedSize return RED payload size of blocks
+func RtpRedSize(blocks []RtpRedBlock) int {
+	n := RtpRedFinalHeaderLength
+	for i := range blocks {
+		n += len(blocks[i].Data)
+	}
+	return n + RtpRedHeaderLength*(len(blocks)-1)
+}
+
+// RtpRedWrite write RED payload(headers then block data)
+// @param[in] blocks redundant blocks first, primary block last
+// @return bytes written
+func RtpRedWrite(blocks []RtpRedBlock, data []byte) (int, error) {
+	if len(blocks) == 0 {
+		return 0, errors.New("rtp red without block.")
+	}
+	size := RtpRedSize(blocks)
+	if len(data) < size {
+		return 0, errors.New("rtp red buffer too small.")
+	}
+
+	ptr := data
+	for i := range blocks[:len(blocks)-1] {
+		b := &blocks[i]
+		if b.TimestampOffset > RtpRedMaxTimestampOffset || len(b.Data) > RtpRedMaxBlockLength {
+			return 0, errors.New("rtp red block too large.")
+		}
+		v := uint32(b.TimestampOffset)<<10 | uint32(len(b.Data))
+		ptr[0] = 0x80 | b.Payload
+		ptr[1] = byte(v >> 16)
+		ptr[2] = byte(v >> 8)
+		ptr[3] = byte(v)
+		ptr = ptr[RtpRedHeaderLength:]
+	}
+	ptr[0] = blocks[len(blocks)-1].Payload & 0x7F
+	ptr = ptr[RtpRedFinalHeaderLength:]
+	for i := range blocks {
+		ptr = ptr[copy(ptr, blocks[i].Data):]
+	}
+	return size, nil
+}
+
+type rtpRedFrame struct {
+	payload   uint8
+	timestamp uint32
+	data      []byte
+}
+
+// RtpRedPacker RtpPayload handler wrap each audio packet into RED with up to
+// distance previous frames as redundant blocks, one frame per packet(e.g. RtpCommPack)
+// e.g. red := NewRtpRedPacker(transport, 121, 1)
+// payload.RtpPayloadCreate(111, "opus", seq, ssrc, 1200, red, nil, nil)
+type RtpRedPacker struct {
+	handler  RtpPayload
+	payload  uint8          // RED payload type
+	distance int            // redundant frames per packet
+	frames   []*rtpRedFrame // previous frames, oldest first
+	blocks   []RtpRedBlock
+}
+
+// NewRtpRedPacker create RED packer
+// @param[in] handler inner packer handler(e.g. network sender)
+// @param[in] payload RED payload type
+// @param[in] distance previous frames carried as redundant blocks, 0-primary only
+func NewRtpRedPacker(handler RtpPayload, payload uint8, distance int) *RtpRedPacker {
+	if distance < 0 {
+		distance = 0
+	}
+	return &RtpRedPacker{handler: handler, payload: payload, distance: distance}
+}
+
+// RFC2198 5. Usage with SDP (p7)
+/*
+   m=audio 12345 RTP/AVP 121 0 5
+   a=rtpmap:121 red/8000/1
+   a=fmtp:121 0/5
+*/
+func (p *RtpRedPacker) Fmtp(primary int) *sdp.SdpFmtp {
+	formats := make([]string, p.distance+1)
+	for i := range formats {
+		formats[i] = strconv.Itoa(primary)
+	}
+	return &sdp.SdpFmtp{Payload: int(p.payload), Raw: strings.Join(formats, "/")}
+}
+
+// Sdp add RED format(preferred), rtpmap and fmtp to the primary media description
+// @param[in] media from RtpPayloadPackerSdp
+func (p *RtpRedPacker) Sdp(media *sdp.SdpMedia) error {
+	if len(media.Formats) == 0 || media.Rtpmap(media.Formats[0]) == nil {
+		return errors.New("red primary payload not in sdp media.")
+	}
+	r := media.Rtpmap(media.Formats[0])
+	fmtp := p.Fmtp(r.Payload)
+	media.Formats = append([]int{int(p.payload)}, media.Formats...)
+	media.Rtpmaps = append(media.Rtpmaps, sdp.SdpRtpmap{Payload: int(p.payload), Encoding: RtpRedEncoding, Frequency: r.Frequency, Channels: r.Channels})
+	media.Fmtps = append(media.Fmtps, *fmtp)
+	return nil
+}
+
+func (p *RtpRedPacker) Alloc(param interface{}, bytes int) []byte {
+	return p.handler.Alloc(param, bytes)
+}
+
+func (p *RtpRedPacker) Free(param interface{}, packet []byte) {
+	p.handler.Free(param, packet)
+}
+
+// Handle wrap packet payload as primary block after previous frames,
+// redundant blocks stop at the first frame with timestamp offset or length
+// out of range, so the n-th block before primary is always the n-th previous frame
+func (p *RtpRedPacker) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
+	var v rtp.RtpPacketView
+	if rtp.RtpPacketViewParse(&v, packet, bytes) != nil || len(v.Payload()) == 0 {
+		p.handler.Handle(param, packet, bytes, timestamp, flags) // e.g. padding-only probe
+		return
+	}
+
+	// newest previous frame first, then reverse
+	p.blocks = p.blocks[:0]
+	for i := len(p.frames) - 1; i >= 0; i-- {
+		f := p.frames[i]
+		offset := v.Timestamp() - f.timestamp
+		if offset == 0 || offset > RtpRedMaxTimestampOffset || len(f.data) > RtpRedMaxBlockLength {
+			break
+		}
+		p.blocks = append(p.blocks, RtpRedBlock{Payload: f.payload, TimestampOffset: uint16(offset), Data: f.data})
+	}
+	for i, j := 0, len(p.blocks)-1; i < j; i, j = i+1, j-1 {
+		p.blocks[i], p.blocks[j] = p.blocks[j], p.blocks[i]
+	}
+	p.blocks = append(p.blocks, RtpRedBlock{Payload: v.PayloadType(), Data: v.Payload()})
+
+	payload := v.Payload()
+	header := bytes - len(payload) - int(v.Padding())*int(packet[bytes-1])
+	n := header + RtpRedSize(p.blocks)
+	red := p.handler.Alloc(param, n)
+	if len(red) < n {
+		return
+	}
+	copy(red, packet[:header])
+	red[0] &^= 1 << rtp.RtpHeader_PaddingShift
+	red[1] = red[1]&0x80 | p.payload
+	if _, err := RtpRedWrite(p.blocks, red[header:n]); err == nil {
+		p.handler.Handle(param, red, n, timestamp, flags)
+	}
+	p.handler.Free(param, red)
+
+	if p.distance > 0 {
+		var f *rtpRedFrame
+		if len(p.frames) < p.distance {
+			f = &rtpRedFrame{}
+		} else {
+			f = p.frames[0]
+			p.frames = p.frames[1:]
+		}
+		f.payload = v.PayloadType()
+		f.timestamp = v.Timestamp()
+		f.data = append(f.data[:0], payload...)
+		p.frames = append(p.frames, f)
+	}
+}
+
+// RtpRedUnpacker RtpPayloadUnpacker unwrap RED packets, primary frames lost
+// before a RED packet are restored from its redundant blocks(one frame per
+// packet, the n-th block before primary is sequence number - n) and fed to
+// the inner unpacker in sequence order ahead of the primary frame, blocks
+// with timestamp not after the last frame delivered are skipped.
+// Other packets pass through.
+type RtpRedUnpacker struct {
+	Recovered int // lost frames restored from redundant blocks
+
+	unpacker  RtpPayloadUnpacker
+	payload   uint8 // RED payload type
+	started   bool
+	seq       uint16 // highest sequence number
+	timestamp uint32 // timestamp of the last frame delivered
+	buf       []byte
+}
+
+func NewRtpRedUnpacker(unpacker RtpPayloadUnpacker, payload uint8) *RtpRedUnpacker {
+	return &RtpRedUnpacker{unpacker: unpacker, payload: payload}
+}
+
+// RtpRedUnpackerSdp create RED unpacker from red rtpmap
+func RtpRedUnpackerSdp(media *sdp.SdpMedia, unpacker RtpPayloadUnpacker) (*RtpRedUnpacker, error) {
+	for _, r := range media.Rtpmaps {
+		if strings.EqualFold(r.Encoding, RtpRedEncoding) {
+			return NewRtpRedUnpacker(unpacker, uint8(r.Payload)), nil
+		}
+	}
+	return nil, errors.New("red not in sdp media.")
+}
+
+func (u *RtpRedUnpacker) Init(handler RtpPayload, param interface{}) {
+	u.unpacker.Init(handler, param)
+}
+
+func (u *RtpRedUnpacker) Destroy() {
+	u.unpacker.Destroy()
+}
+
+// Input restore lost frames from redundant blocks, then feed primary frame
+// @return 1-packet handled, 0-packet discard, <0-failed
+func (u *RtpRedUnpacker) Input(packet []byte, bytes int) (int, error) {
+	var v rtp.RtpPacketView
+	if err := rtp.RtpPacketViewParse(&v, packet, bytes); err != nil {
+		return 0, err
+	}
+
+	// lost packets between the highest and this one
+	seq := v.SequenceNumber()
+	lost, late := 0, false
+	if !u.started {
+		u.started = true
+		u.seq = seq
+		u.timestamp = v.Timestamp() - 1
+	} else if d := int16(seq - u.seq); d > 0 {
+		lost = int(d) - 1
+		u.seq = seq
+	} else {
+		late = true
+	}
+
+	if v.PayloadType() != u.payload {
+		if !late {
+			u.timestamp = v.Timestamp()
+		}
+		return u.unpacker.Input(packet, bytes)
+	}
+	blocks, err := RtpRedParse(v.Payload())
+	if err != nil {
+		return 0, err
+	}
+
+	header := bytes - len(v.Payload()) - int(v.Padding())*int(packet[bytes-1])
+	for i, b := range blocks[:len(blocks)-1] {
+		// frame identified by timestamp, skip frames delivered already
+		distance := len(blocks) - 1 - i
+		timestamp := v.Timestamp() - uint32(b.TimestampOffset)
+		if distance > lost || int32(timestamp-u.timestamp) <= 0 {
+			continue
+		}
+		pkt := u.frame(packet[:header], &b, seq-uint16(distance), timestamp)
+		u.timestamp = timestamp
+		if r, err := u.unpacker.Input(pkt, len(pkt)); err != nil {
+			return r, err
+		}
+		u.Recovered++
+	}
+
+	if !late {
+		u.timestamp = v.Timestamp()
+	}
+	primary := &blocks[len(blocks)-1]
+	pkt := u.frame(packet[:header], primary, seq, v.Timestamp())
+	pkt[1] |= packet[1] & 0x80 // marker
+	return u.unpacker.Input(pkt, len(pkt))
+}
+
+// frame build RTP packet of a block with RED packet header(CSRC, extension), marker bit cleared
+func (u *RtpRedUnpacker) frame(header []byte, b *RtpRedBlock, seq uint16, timestamp uint32) []byte {
+	u.buf = append(append(u.buf[:0], header...), b.Data...)
+	u.buf[0] &^= 1 << rtp.RtpHeader_PaddingShift
+	u.buf[1] = b.Payload
+	rtp.RtpWriteUint16(u.buf[rtp.RtpHeader_SeqNumOffset:], seq)
+	rtp.RtpWriteUint32(u.buf[rtp.RtpHeader_TimestampOffset:], timestamp)
+	return u.buf
+}
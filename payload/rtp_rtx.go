@@ -0,0 +1,253 @@
+package payload
+
+import (
+	"errors"
+	"github.com/services-go/librtp/rtp"
+	"github.com/services-go/librtp/sdp"
+	"strconv"
+	"strings"
+)
+
+// RFC4588 RTP Retransmission Payload Format
+// 4. RTP Payload Format (p8)
+/*
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                         RTP Header                            |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |            OSN                |                               |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+                               |
+   |                  Original RTP Packet Payload                  |
+   |                                                               |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+   The RTP header usage is as follows:
+   o In the case of session-multiplexing, same SSRC value MUST be used for
+     the original stream and the retransmission stream.
+   o The sequence number has the standard definition. It MUST be one
+     higher than the sequence number of the preceding packet sent in the
+     retransmission stream.
+   o The timestamp MUST be set to the same value as the timestamp of the
+     original packet.
+   o The marker bit (M), the CSRC count (CC) and the CSRC list MUST be the
+     same as the original packet.
+*/
+const (
+	RtpRtxOsnLength = 2 // original sequence number
+	RtpRtxEncoding  = "rtx"
+)
+
+// RtpRtxPacker RtpPayload handler wrap original packets into the RTX stream,
+// e.g. history.Retransmit(nack.Lost, now, rtt, rtxPacker)
+type RtpRtxPacker struct {
+	handler   RtpPayload
+	payload   uint8 // RTX payload type
+	apt       uint8 // associated original payload type
+	ssrc      uint32
+	seq       uint16
+	timestamp uint32
+	rtxTime   int // ms, 0 if not present
+}
+
+// NewRtpRtxPacker create RTX stream packer
+// @param[in] payload RTX payload type, dynamic 96~127
+// @param[in] apt associated original payload type
+// @param[in] seq first RTX sequence number, random
+// @param[in] ssrc RTX stream SSRC(session-multiplexing)
+// @param[in] handler receive RTX packets
+func NewRtpRtxPacker(payload, apt uint8, seq uint16, ssrc uint32, handler RtpPayload) *RtpRtxPacker {
+	return &RtpRtxPacker{handler: handler, payload: payload, apt: apt, ssrc: ssrc, seq: seq}
+}
+
+// RtpPayloadRtxPacker create RTX packer associated with the delegate payload type
+func (de *RtpPayloadDelegate) RtpPayloadRtxPacker(payload uint8, seq uint16, ssrc uint32, handler RtpPayload) *RtpRtxPacker {
+	return NewRtpRtxPacker(payload, uint8(de.Profile.Payload), seq, ssrc, handler)
+}
+
+// GetInfo return next RTX sequence number and timestamp of the last RTX packet, e.g. RTSP RTP-Info
+func (p *RtpRtxPacker) GetInfo() (seq uint16, timestamp uint32) {
+	return p.seq, p.timestamp
+}
+
+// SetRtxTime set rtx-time parameter, how long the sender keep packets(ms)
+func (p *RtpRtxPacker) SetRtxTime(ms int) {
+	p.rtxTime = ms
+}
+
+// RFC4588 8.6. Media Type Registration (p23)
+/*
+   a=rtpmap:97 rtx/90000
+   a=fmtp:97 apt=96;rtx-time=3000
+*/
+func (p *RtpRtxPacker) Fmtp() *sdp.SdpFmtp {
+	fmtp := &sdp.SdpFmtp{Payload: int(p.payload)}
+	fmtp.Params = append(fmtp.Params, sdp.SdpParam{Name: "apt", Value: strconv.Itoa(int(p.apt))})
+	if p.rtxTime > 0 {
+		fmtp.Params = append(fmtp.Params, sdp.SdpParam{Name: "rtx-time", Value: strconv.Itoa(p.rtxTime)})
+	}
+	return fmtp
+}
+
+// Sdp add RTX format, rtpmap and fmtp to the original media description
+// @param[in] media from RtpPayloadPackerSdp
+func (p *RtpRtxPacker) Sdp(media *sdp.SdpMedia) error {
+	r := media.Rtpmap(int(p.apt))
+	if r == nil {
+		return errors.New("rtx associated payload not in sdp media: " + strconv.Itoa(int(p.apt)))
+	}
+	media.Formats = append(media.Formats, int(p.payload))
+	media.Rtpmaps = append(media.Rtpmaps, sdp.SdpRtpmap{Payload: int(p.payload), Encoding: RtpRtxEncoding, Frequency: r.Frequency})
+	media.Fmtps = append(media.Fmtps, *p.Fmtp())
+	return nil
+}
+
+func (p *RtpRtxPacker) Alloc(param interface{}, bytes int) []byte {
+	return p.handler.Alloc(param, bytes)
+}
+
+func (p *RtpRtxPacker) Free(param interface{}, packet []byte) {
+	p.handler.Free(param, packet)
+}
+
+// Handle wrap original packet: PT/SSRC/sequence number of RTX stream, OSN
+// before payload, original padding removed
+func (p *RtpRtxPacker) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
+	var v rtp.RtpPacketView
+	if rtp.RtpPacketViewParse(&v, packet, bytes) != nil || v.PayloadType() != p.apt {
+		return
+	}
+
+	payload := v.Payload()
+	header := bytes - len(payload) - int(v.Padding())*int(packet[bytes-1]) // header, CSRC and extension
+	n := header + RtpRtxOsnLength + len(payload)
+	rtx := p.handler.Alloc(param, n)
+	if len(rtx) < n {
+		return
+	}
+
+	copy(rtx, packet[:header])
+	rtx[0] &^= 1 << rtp.RtpHeader_PaddingShift
+	rtx[1] = rtx[1]&0x80 | p.payload
+	rtp.RtpWriteUint16(rtx[rtp.RtpHeader_SeqNumOffset:], p.seq)
+	rtp.RtpWriteUint32(rtx[rtp.RtpHeader_SsrcOffset:], p.ssrc)
+	rtp.RtpWriteUint16(rtx[header:], v.SequenceNumber())
+	copy(rtx[header+RtpRtxOsnLength:], payload)
+
+	p.seq++
+	p.timestamp = v.Timestamp()
+	p.handler.Handle(param, rtx, n, p.timestamp, flags|RTP_PAYLOAD_FLAG_RETRANSMISSION)
+	p.handler.Free(param, rtx)
+}
+
+// RtpRtxUnpacker RtpPayloadUnpacker unwrap RTX packets back into original
+// packets, other packets pass through to the inner unpacker
+type RtpRtxUnpacker struct {
+	Unwrapped int // RTX packets restored
+	Discard   int // RTX packets without OSN(padding-only probe) or unknown SSRC
+
+	unpacker RtpPayloadUnpacker
+	apt      map[uint8]uint8   // RTX payload type => original payload type
+	ssrc     map[uint32]uint32 // RTX SSRC => original SSRC
+	buf      []byte
+}
+
+func NewRtpRtxUnpacker(unpacker RtpPayloadUnpacker) *RtpRtxUnpacker {
+	return &RtpRtxUnpacker{unpacker: unpacker, apt: make(map[uint8]uint8), ssrc: make(map[uint32]uint32)}
+}
+
+// RtpRtxUnpackerSdp create RTX unpacker from rtx rtpmap/fmtp apt and ssrc-group FID lines
+// a=ssrc-group:FID <original ssrc> <rtx ssrc>
+func RtpRtxUnpackerSdp(media *sdp.SdpMedia, unpacker RtpPayloadUnpacker) (*RtpRtxUnpacker, error) {
+	u := NewRtpRtxUnpacker(unpacker)
+	for _, r := range media.Rtpmaps {
+		if !strings.EqualFold(r.Encoding, RtpRtxEncoding) {
+			continue
+		}
+		fmtp := media.Fmtp(r.Payload)
+		if fmtp == nil {
+			return nil, errors.New("rtx apt not present: " + strconv.Itoa(r.Payload))
+		}
+		apt, ok, err := fmtp.Int("apt")
+		if err != nil || !ok || apt < 0 || apt > 127 {
+			return nil, errors.New("rtx apt error: " + strconv.Itoa(r.Payload))
+		}
+		u.SetPayload(uint8(r.Payload), uint8(apt))
+	}
+
+	for _, a := range media.Attributes {
+		fields := strings.Fields(a.Value)
+		if a.Name != "ssrc-group" || len(fields) != 3 || fields[0] != "FID" {
+			continue
+		}
+		ssrc, err1 := strconv.ParseUint(fields[1], 10, 32)
+		rtx, err2 := strconv.ParseUint(fields[2], 10, 32)
+		if err1 != nil || err2 != nil {
+			return nil, errors.New("sdp ssrc-group error: " + a.Value)
+		}
+		u.Associate(uint32(rtx), uint32(ssrc))
+	}
+	return u, nil
+}
+
+// SetPayload associate RTX payload type with original payload type(apt)
+func (u *RtpRtxUnpacker) SetPayload(rtx, apt uint8) {
+	u.apt[rtx] = apt
+}
+
+// Associate RTX SSRC with original SSRC
+func (u *RtpRtxUnpacker) Associate(rtx, ssrc uint32) {
+	u.ssrc[rtx] = ssrc
+}
+
+func (u *RtpRtxUnpacker) Init(handler RtpPayload, param interface{}) {
+	u.unpacker.Init(handler, param)
+}
+
+func (u *RtpRtxUnpacker) Destroy() {
+	u.unpacker.Destroy()
+}
+
+// Input unwrap RTX packet and feed the inner unpacker
+// @return 1-packet handled, 0-packet discard, <0-failed
+func (u *RtpRtxUnpacker) Input(packet []byte, bytes int) (int, error) {
+	data, n, err := u.Unwrap(packet, bytes)
+	if err != nil || n == 0 {
+		return 0, err
+	}
+	return u.unpacker.Input(data, n)
+}
+
+// Unwrap restore original packet from RTX packet, e.g. before jitter buffer or NACK generator
+// @return original packet(valid until next Unwrap) or packet itself if not RTX, 0 bytes-discard
+func (u *RtpRtxUnpacker) Unwrap(packet []byte, bytes int) ([]byte, int, error) {
+	var v rtp.RtpPacketView
+	if err := rtp.RtpPacketViewParse(&v, packet, bytes); err != nil {
+		return nil, 0, err
+	}
+	apt, ok := u.apt[v.PayloadType()]
+	if !ok {
+		return packet, bytes, nil
+	}
+
+	ssrc, ok := u.ssrc[v.SSRC()]
+	payload := v.Payload()
+	if !ok || len(payload) < RtpRtxOsnLength {
+		u.Discard++
+		return nil, 0, nil
+	}
+
+	header := bytes - len(payload) - int(v.Padding())*int(packet[bytes-1])
+	n := bytes - RtpRtxOsnLength
+	if cap(u.buf) < n {
+		u.buf = make([]byte, n)
+	}
+	u.buf = u.buf[:n]
+	copy(u.buf, packet[:header])
+	copy(u.buf[header:], packet[header+RtpRtxOsnLength:bytes])
+	u.buf[1] = u.buf[1]&0x80 | apt
+	rtp.RtpWriteUint16(u.buf[rtp.RtpHeader_SeqNumOffset:], rtp.RtpReadUint16(payload))
+	rtp.RtpWriteUint32(u.buf[rtp.RtpHeader_SsrcOffset:], ssrc)
+	u.Unwrapped++
+	return u.buf, n, nil
+}
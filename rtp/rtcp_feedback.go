@@ -0,0 +1,109 @@
+package rtp
+
+import (
+	"errors"
+)
+
+// RFC4585 6.1 Common Packet Format for Feedback Messages (p31)
+/*
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |V=2|P|   FMT   |       PT      |          length               |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                  SSRC of packet sender                        |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                  SSRC of media source                         |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   :            Feedback Control Information (FCI)                 :
+   :                                                               :
+*/
+const (
+	RtcpFeedbackHeader = 8 // SSRC of packet sender + SSRC of media source
+
+	// RFC4585 6.2 Transport Layer Feedback Messages (p33)
+	RTCP_RTPFB_NACK = 1 // Generic NACK
+)
+
+// RFC4585 6.2.1 Generic NACK (p34)
+/*
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |            PID                |             BLP               |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+
+   Packet ID (PID): 16 bits. The PID field is used to specify a lost packet.
+   bitmask of following lost packets (BLP): 16 bits. The BLP allows for
+   reporting losses of any of the 16 RTP packets immediately following
+   the RTP packet indicated by the PID.
+*/
+type RtcpNack struct {
+	SenderSSRC uint32   // SSRC of packet sender
+	MediaSSRC  uint32   // SSRC of media source
+	Lost       []uint16 // lost sequence numbers, ascending order packs best
+}
+
+func (nack *RtcpNack) PacketType() byte {
+	return RTCP_RTPFB
+}
+
+func (nack *RtcpNack) PacketSize() int {
+	return RtcpHeaderLength + RtcpFeedbackHeader + 4*len(nack.fci())
+}
+
+func (nack *RtcpNack) Serialize(data []byte, bytes int) (int, error) {
+	if len(nack.Lost) == 0 {
+		return 0, errors.New("rtcp nack without lost packet.")
+	}
+
+	fci := nack.fci()
+	size := RtcpHeaderLength + RtcpFeedbackHeader + 4*len(fci)
+	if err := rtcpSerializeHeader(data, bytes, RTCP_RTPFB, RTCP_RTPFB_NACK, size); err != nil {
+		return 0, err
+	}
+
+	ptr := data[RtcpHeaderLength:]
+	RtpWriteUint32(ptr, nack.SenderSSRC)
+	RtpWriteUint32(ptr[4:], nack.MediaSSRC)
+	ptr = ptr[RtcpFeedbackHeader:]
+	for _, v := range fci {
+		RtpWriteUint32(ptr, v)
+		ptr = ptr[4:]
+	}
+	return size, nil
+}
+
+// fci pack lost sequence numbers to PID/BLP pairs
+func (nack *RtcpNack) fci() []uint32 {
+	var fci []uint32
+	var pid uint16
+	for i, seq := range nack.Lost {
+		if d := seq - pid; i > 0 && d >= 1 && d <= 16 {
+			fci[len(fci)-1] |= 1 << (d - 1)
+			continue
+		}
+		pid = seq
+		fci = append(fci, uint32(seq)<<16)
+	}
+	return fci
+}
+
+func rtcpNackDeserialize(ptr []byte) (*RtcpNack, error) {
+	if len(ptr) < RtcpFeedbackHeader || len(ptr)%4 != 0 {
+		return nil, errors.New("rtcp nack length error.")
+	}
+
+	nack := &RtcpNack{SenderSSRC: RtpReadUint32(ptr), MediaSSRC: RtpReadUint32(ptr[4:])}
+	for ptr = ptr[RtcpFeedbackHeader:]; len(ptr) >= 4; ptr = ptr[4:] {
+		pid := RtpReadUint16(ptr)
+		blp := RtpReadUint16(ptr[2:])
+		nack.Lost = append(nack.Lost, pid)
+		for i := uint16(0); i < 16; i++ {
+			if blp&(1<<i) != 0 {
+				nack.Lost = append(nack.Lost, pid+i+1)
+			}
+		}
+	}
+	return nack, nil
+}
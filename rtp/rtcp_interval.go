@@ -0,0 +1,205 @@
+package rtp
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RFC3550 6.2 RTCP Transmission Interval (p24)
+// RFC3550 A.7 Computing the RTCP Transmission Interval (p91)
+const (
+	RtcpBandwidthFraction = 0.05            // RTCP bandwidth is 5% of session bandwidth
+	RtcpSenderBwFraction  = 0.25            // fraction of RTCP bandwidth for senders
+	RtcpRcvrBwFraction    = 1 - 0.25        // fraction of RTCP bandwidth for receivers
+	RtcpCompensation      = 2.71828 - 1.5   // e-3/2, compensate timer reconsideration
+	RtcpByeMembers        = 50              // BYE backoff if members more than 50 (6.3.7)
+	RtcpInitialSize       = 28 + 8 + 8 + 24 // UDP/IP + RR + SDES(short CNAME), probable first packet size
+)
+
+// RtcpScheduler decide when to send RTCP packets (RFC3550 6.3)
+// all times are supplied by caller so the schedule is deterministic
+type RtcpScheduler struct {
+	Bandwidth float64 // session bandwidth in octets per second
+
+	table   *RtpMemberTable
+	random  func() float64 // [0, 1)
+	tp      time.Time      // last time an RTCP packet was transmitted
+	tn      time.Time      // next scheduled transmission time
+	avgSize float64        // average compound RTCP packet size, include UDP/IP
+	initial bool           // no RTCP packet transmitted yet
+
+	pmembers int  // estimated number of members at the time tn was last recomputed
+	leaving  bool // BYE backoff (6.3.7)
+	byes     int  // BYE packets received during backoff
+}
+
+// NewRtcpScheduler create RTCP scheduler
+// @param[in] table session members, provide members/senders/we_sent
+// @param[in] bandwidth session bandwidth in octets per second
+// @param[in] random [0, 1) random number generator, nil-math/rand
+// @param[in] now session join time
+func NewRtcpScheduler(table *RtpMemberTable, bandwidth float64, random func() float64, now time.Time) *RtcpScheduler {
+	if random == nil {
+		random = rand.Float64
+	}
+
+	s := &RtcpScheduler{Bandwidth: bandwidth, table: table, random: random}
+	s.tp = now
+	s.avgSize = RtcpInitialSize
+	s.initial = true
+	s.pmembers = 1
+	s.tn = now.Add(s.interval(s.table.Members(), s.table.Senders(), s.table.Self().Sender, true))
+	return s
+}
+
+// RtcpInterval compute the RTCP transmission interval (RFC3550 A.7)
+// @param[in] members estimated number of session members, include self
+// @param[in] senders number of active senders, include self
+// @param[in] rtcpBw target RTCP bandwidth in octets per second
+// @param[in] weSent true if sent data since the second previous RTCP report
+// @param[in] avgSize average compound RTCP packet size in octets, include UDP/IP
+// @param[in] initial true if not yet sent an RTCP packet
+// @return deterministic interval(before randomization)
+func RtcpInterval(members, senders int, rtcpBw float64, weSent bool, avgSize float64, initial bool) time.Duration {
+	// Minimum average time between RTCP packets from this site (in seconds).
+	// This time prevents the reports from `clumping' when sessions are small
+	// and the law of large numbers isn't helping to smooth out the traffic.
+	// It also keeps the report interval from becoming ridiculously small
+	// during transient outages like a network partition.
+	minTime := RtcpMinInterval.Seconds()
+
+	// Very first call at application start-up uses half the min
+	// delay for quicker notification while still allowing some time
+	// before reporting for randomization and to learn about other
+	// sources so the report interval will converge to the correct
+	// interval more quickly.
+	if initial {
+		minTime /= 2
+	}
+
+	// Dedicate a fraction of the RTCP bandwidth to senders unless
+	// the number of senders is large enough that their share is
+	// more than that fraction.
+	n := members
+	if float64(senders) <= float64(members)*RtcpSenderBwFraction {
+		if weSent {
+			rtcpBw *= RtcpSenderBwFraction
+			n = senders
+		} else {
+			rtcpBw *= RtcpRcvrBwFraction
+			n -= senders
+		}
+	}
+
+	// The effective number of sites times the average packet size is
+	// the total number of octets sent when each site sends a report.
+	// Dividing this by the effective bandwidth gives the time
+	// interval over which those packets must be sent in order to
+	// meet the bandwidth target, with a minimum enforced.
+	t := minTime
+	if rtcpBw > 0 {
+		t = avgSize * float64(n) / rtcpBw
+		if t < minTime {
+			t = minTime
+		}
+	}
+	return time.Duration(t * float64(time.Second))
+}
+
+// randomize interval to [0.5, 1.5] times then divided by e-3/2
+func (s *RtcpScheduler) randomize(t time.Duration) time.Duration {
+	return time.Duration(float64(t) * (s.random() + 0.5) / RtcpCompensation)
+}
+
+func (s *RtcpScheduler) interval(members, senders int, weSent, initial bool) time.Duration {
+	return s.randomize(RtcpInterval(members, senders, s.Bandwidth*RtcpBandwidthFraction, weSent, s.avgSize, initial))
+}
+
+// Next return next scheduled transmission time
+func (s *RtcpScheduler) Next() time.Time {
+	return s.tn
+}
+
+// Deterministic return the deterministic interval Td for member timeout (6.3.5)
+func (s *RtcpScheduler) Deterministic() time.Duration {
+	return RtcpInterval(s.table.Members(), s.table.Senders(), s.Bandwidth*RtcpBandwidthFraction, false, s.avgSize, false)
+}
+
+// Interval return current randomized transmission interval T for sender timeout
+func (s *RtcpScheduler) Interval() time.Duration {
+	return s.interval(s.table.Members(), s.table.Senders(), s.table.Self().Sender, s.initial)
+}
+
+// OnExpire timer reconsideration when the transmission timer expires (A.7 OnExpire)
+// @param[in] now current time
+// @return true-send RTCP report(or BYE if Leave called) now, then call OnSent
+func (s *RtcpScheduler) OnExpire(now time.Time) bool {
+	members, senders, weSent := s.table.Members(), s.table.Senders(), s.table.Self().Sender
+	if s.leaving {
+		// RFC3550 6.3.7: members is the number of BYE received, sender is 0
+		members, senders, weSent = s.byes, 0, false
+	}
+
+	t := s.interval(members, senders, weSent, s.initial)
+	tn := s.tp.Add(t)
+	s.pmembers = members
+	if !tn.After(now) {
+		return true
+	}
+	s.tn = tn
+	return false
+}
+
+// OnSent update state after a compound packet sent
+// @param[in] now send time
+// @param[in] size compound packet size in octets, include UDP/IP
+func (s *RtcpScheduler) OnSent(now time.Time, size int) {
+	s.avgSize = float64(size)/16 + s.avgSize*15/16
+	s.tp = now
+	s.initial = false
+	s.tn = now.Add(s.Interval())
+}
+
+// OnReceive update average RTCP packet size (A.7 OnReceive)
+// @param[in] size compound packet size in octets, include UDP/IP
+func (s *RtcpScheduler) OnReceive(size int) {
+	s.avgSize = float64(size)/16 + s.avgSize*15/16
+}
+
+// OnBye reverse reconsideration after a BYE received or members timeout (6.3.4)
+// call after RtpMemberTable.OnRtcp/Timeout removed members
+// @param[in] now current time
+// @param[in] byes BYE packets in the compound packet
+func (s *RtcpScheduler) OnBye(now time.Time, byes int) {
+	if s.leaving {
+		s.byes += byes
+		return
+	}
+
+	members := s.table.Members()
+	if members < s.pmembers && s.pmembers > 0 {
+		ratio := float64(members) / float64(s.pmembers)
+		s.tn = now.Add(time.Duration(float64(s.tn.Sub(now)) * ratio))
+		s.tp = now.Add(-time.Duration(float64(now.Sub(s.tp)) * ratio))
+		s.pmembers = members
+	}
+}
+
+// Leave start BYE transmission (6.3.7)
+// @param[in] now current time
+// @return true-send BYE immediately, false-wait OnExpire return true
+func (s *RtcpScheduler) Leave(now time.Time) bool {
+	if s.table.Members() <= RtcpByeMembers {
+		return true
+	}
+
+	// BYE reconsideration: pretend we are joining a new session
+	s.leaving = true
+	s.byes = 1
+	s.pmembers = 1
+	s.initial = true
+	s.tp = now
+	s.avgSize = RtcpInitialSize
+	s.tn = now.Add(s.interval(1, 0, false, true))
+	return false
+}
@@ -0,0 +1,522 @@
+package rtp
+
+import (
+	"errors"
+)
+
+// RFC3550 6.1 RTCP Packet Format (p21)
+// Multiple RTCP packets can be concatenated without any intervening
+// separators to form a compound RTCP packet that is sent in a single
+// packet of the lower layer protocol.
+/*
+if encrypted: random 32-bit integer
+|
+|[--------- packet --------][---------- packet ----------][-packet-]
+|
+|                receiver            chunk        chunk
+V                reports           item  item   item  item
+--------------------------------------------------------------------
+R[SR #sendinfo #site1#site2][SDES #CNAME PHONE #CNAME LOC][BYE##why]
+--------------------------------------------------------------------
+|                                                                  |
+|<-----------------------  compound packet ----------------------->|
+|<--------------------------  UDP packet ------------------------->|
+*/
+func RtcpCompoundDeserialize(data []byte, bytes int) ([]RtcpPacket, error) {
+	if bytes < RtcpHeaderLength {
+		return nil, errors.New("rtcp header need 4 bytes.")
+	}
+
+	var pkts []RtcpPacket
+	for ptr := data[:bytes]; len(ptr) > 0; {
+		pkt, n, err := RtcpPacketDeserialize(ptr, len(ptr))
+		if err != nil {
+			return nil, err
+		}
+
+		// RFC3550 6.4.1: the padding bit may only be set in the last packet
+		if RTCP_P(RtpReadUint32(ptr)) > 0 && n != len(ptr) {
+			return nil, errors.New("rtcp padding in middle packet.")
+		}
+		pkts = append(pkts, pkt)
+		ptr = ptr[n:]
+	}
+	return pkts, nil
+}
+
+func RtcpCompoundSerialize(pkts []RtcpPacket, data []byte, bytes int) (int, error) {
+	total := 0
+	for _, pkt := range pkts {
+		n, err := pkt.Serialize(data[total:], bytes-total)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// parse one RTCP packet
+// @return packet, bytes consumed(header+body+padding), error
+func RtcpPacketDeserialize(data []byte, bytes int) (RtcpPacket, int, error) {
+	if bytes < RtcpHeaderLength {
+		return nil, 0, errors.New("rtcp header need 4 bytes.")
+	}
+
+	var h RtcpHeader
+	ReadRtcpHeader(data, &h)
+	if h.Version != RtpVersion {
+		return nil, 0, errors.New("rtcp version error.")
+	}
+
+	size := (int(h.Length) + 1) * 4
+	if size > bytes {
+		return nil, 0, errors.New("rtcp length error.")
+	}
+
+	body := data[RtcpHeaderLength:size]
+	if h.Padding > 0 {
+		padding := int(data[size-1])
+		if padding == 0 || padding > len(body) {
+			return nil, 0, errors.New("rtcp padding error.")
+		}
+		body = body[:len(body)-padding]
+	}
+
+	var pkt RtcpPacket
+	var err error
+	switch h.PT {
+	case RTCP_SR:
+		pkt, err = rtcpSRDeserialize(&h, body)
+	case RTCP_RR:
+		pkt, err = rtcpRRDeserialize(&h, body)
+	case RTCP_SDES:
+		pkt, err = rtcpSdesDeserialize(&h, body)
+	case RTCP_BYE:
+		pkt, err = rtcpByeDeserialize(&h, body)
+	case RTCP_APP:
+		pkt, err = rtcpAppDeserialize(&h, body)
+	case RTCP_RTPFB:
+		if h.RC == RTCP_RTPFB_NACK {
+			pkt, err = rtcpNackDeserialize(body)
+		} else {
+			pkt = &RtcpRaw{Header: h, Payload: body}
+		}
+	default:
+		pkt = &RtcpRaw{Header: h, Payload: body}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return pkt, size, nil
+}
+
+func rtcpSerializeHeader(data []byte, bytes int, pt byte, rc int, size int) error {
+	if size > bytes || len(data) < size {
+		return errors.New("rtcp buffer too small.")
+	}
+	if rc > RtcpMaxReportSize {
+		return errors.New("rtcp report count error.")
+	}
+	if size%4 != 0 || size/4-1 > 0xFFFF {
+		return errors.New("rtcp length error.")
+	}
+
+	h := RtcpHeader{Version: RtpVersion, RC: byte(rc), PT: pt, Length: uint16(size/4 - 1)}
+	WriteRtcpHeader(data, &h)
+	return nil
+}
+
+func rtcpReportDeserialize(ptr []byte, report *RtcpReport) {
+	report.SSRC = RtpReadUint32(ptr)
+	report.Fraction = ptr[4]
+	lost := uint32(ptr[5])<<16 | uint32(ptr[6])<<8 | uint32(ptr[7])
+	if lost&0x800000 != 0 {
+		lost |= 0xFF000000 // sign extension
+	}
+	report.Lost = int32(lost)
+	report.ExtSeq = RtpReadUint32(ptr[8:])
+	report.Jitter = RtpReadUint32(ptr[12:])
+	report.LSR = RtpReadUint32(ptr[16:])
+	report.DLSR = RtpReadUint32(ptr[20:])
+}
+
+func rtcpReportSerialize(ptr []byte, report *RtcpReport) {
+	lost := report.Lost
+	if lost > 0x7FFFFF {
+		lost = 0x7FFFFF // clamp to 24 bits
+	} else if lost < -0x800000 {
+		lost = -0x800000
+	}
+
+	RtpWriteUint32(ptr, report.SSRC)
+	RtpWriteUint32(ptr[4:], uint32(lost)&0xFFFFFF)
+	ptr[4] = report.Fraction
+	RtpWriteUint32(ptr[8:], report.ExtSeq)
+	RtpWriteUint32(ptr[12:], report.Jitter)
+	RtpWriteUint32(ptr[16:], report.LSR)
+	RtpWriteUint32(ptr[20:], report.DLSR)
+}
+
+func rtcpReportsDeserialize(h *RtcpHeader, ptr []byte) ([]RtcpReport, []byte, error) {
+	if len(ptr) < int(h.RC)*RtcpReportBlock {
+		return nil, nil, errors.New("rtcp report block length error.")
+	}
+
+	reports := make([]RtcpReport, h.RC)
+	for i := range reports {
+		rtcpReportDeserialize(ptr, &reports[i])
+		ptr = ptr[RtcpReportBlock:]
+	}
+
+	var ext []byte
+	if len(ptr) > 0 {
+		ext = ptr
+	}
+	return reports, ext, nil
+}
+
+func (sr *RtcpSR) PacketType() byte {
+	return RTCP_SR
+}
+
+func (sr *RtcpSR) PacketSize() int {
+	return RtcpHeaderLength + 4 + RtcpSenderInfo + len(sr.Reports)*RtcpReportBlock + len(sr.Extension)
+}
+
+func (sr *RtcpSR) Serialize(data []byte, bytes int) (int, error) {
+	size := sr.PacketSize()
+	if err := rtcpSerializeHeader(data, bytes, RTCP_SR, len(sr.Reports), size); err != nil {
+		return 0, err
+	}
+
+	ptr := data[RtcpHeaderLength:]
+	RtpWriteUint32(ptr, sr.SSRC)
+	RtpWriteUint32(ptr[4:], sr.NTPMSW)
+	RtpWriteUint32(ptr[8:], sr.NTPLSW)
+	RtpWriteUint32(ptr[12:], sr.RTPTime)
+	RtpWriteUint32(ptr[16:], sr.Packets)
+	RtpWriteUint32(ptr[20:], sr.Octets)
+	ptr = ptr[4+RtcpSenderInfo:]
+	for i := range sr.Reports {
+		rtcpReportSerialize(ptr, &sr.Reports[i])
+		ptr = ptr[RtcpReportBlock:]
+	}
+	copy(ptr, sr.Extension)
+	return size, nil
+}
+
+func rtcpSRDeserialize(h *RtcpHeader, ptr []byte) (*RtcpSR, error) {
+	if len(ptr) < 4+RtcpSenderInfo {
+		return nil, errors.New("rtcp sr length error.")
+	}
+
+	sr := &RtcpSR{}
+	sr.SSRC = RtpReadUint32(ptr)
+	sr.NTPMSW = RtpReadUint32(ptr[4:])
+	sr.NTPLSW = RtpReadUint32(ptr[8:])
+	sr.RTPTime = RtpReadUint32(ptr[12:])
+	sr.Packets = RtpReadUint32(ptr[16:])
+	sr.Octets = RtpReadUint32(ptr[20:])
+
+	var err error
+	sr.Reports, sr.Extension, err = rtcpReportsDeserialize(h, ptr[4+RtcpSenderInfo:])
+	if err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
+
+func (rr *RtcpRR) PacketType() byte {
+	return RTCP_RR
+}
+
+func (rr *RtcpRR) PacketSize() int {
+	return RtcpHeaderLength + 4 + len(rr.Reports)*RtcpReportBlock + len(rr.Extension)
+}
+
+func (rr *RtcpRR) Serialize(data []byte, bytes int) (int, error) {
+	size := rr.PacketSize()
+	if err := rtcpSerializeHeader(data, bytes, RTCP_RR, len(rr.Reports), size); err != nil {
+		return 0, err
+	}
+
+	ptr := data[RtcpHeaderLength:]
+	RtpWriteUint32(ptr, rr.SSRC)
+	ptr = ptr[4:]
+	for i := range rr.Reports {
+		rtcpReportSerialize(ptr, &rr.Reports[i])
+		ptr = ptr[RtcpReportBlock:]
+	}
+	copy(ptr, rr.Extension)
+	return size, nil
+}
+
+func rtcpRRDeserialize(h *RtcpHeader, ptr []byte) (*RtcpRR, error) {
+	if len(ptr) < 4 {
+		return nil, errors.New("rtcp rr length error.")
+	}
+
+	rr := &RtcpRR{}
+	rr.SSRC = RtpReadUint32(ptr)
+
+	var err error
+	rr.Reports, rr.Extension, err = rtcpReportsDeserialize(h, ptr[4:])
+	if err != nil {
+		return nil, err
+	}
+	return rr, nil
+}
+
+// RFC3550 6.5 SDES: Source Description RTCP Packet (p45)
+/*
+        0                   1                   2                   3
+        0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+       +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+header |V=2|P|    SC   |  PT=SDES=202  |             length            |
+       +=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+
+chunk  |                          SSRC/CSRC_1                          |
+  1    +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+       |                           SDES items                          |
+       |                              ...                              |
+       +=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+
+*/
+func rtcpSdesChunkSize(chunk *RtcpSdesChunk) int {
+	n := 1 // null item terminate list
+	for _, item := range chunk.Items {
+		n += 2 + len(item.Text)
+	}
+	return 4 + (n+3)/4*4
+}
+
+func (sdes *RtcpSdes) PacketType() byte {
+	return RTCP_SDES
+}
+
+func (sdes *RtcpSdes) PacketSize() int {
+	size := RtcpHeaderLength
+	for i := range sdes.Chunks {
+		size += rtcpSdesChunkSize(&sdes.Chunks[i])
+	}
+	return size
+}
+
+func (sdes *RtcpSdes) Serialize(data []byte, bytes int) (int, error) {
+	size := sdes.PacketSize()
+	if err := rtcpSerializeHeader(data, bytes, RTCP_SDES, len(sdes.Chunks), size); err != nil {
+		return 0, err
+	}
+
+	ptr := data[RtcpHeaderLength:size]
+	for i := range sdes.Chunks {
+		chunk := &sdes.Chunks[i]
+		n := rtcpSdesChunkSize(chunk)
+		RtpWriteUint32(ptr, chunk.SSRC)
+		item := ptr[4:n]
+		for _, it := range chunk.Items {
+			if it.Type == RTCP_SDES_END || len(it.Text) > 255 {
+				return 0, errors.New("rtcp sdes item error.")
+			}
+			item[0] = it.Type
+			item[1] = byte(len(it.Text))
+			copy(item[2:], it.Text)
+			item = item[2+len(it.Text):]
+		}
+		for j := range item {
+			item[j] = 0 // end of list and padding
+		}
+		ptr = ptr[n:]
+	}
+	return size, nil
+}
+
+func rtcpSdesDeserialize(h *RtcpHeader, ptr []byte) (*RtcpSdes, error) {
+	sdes := &RtcpSdes{Chunks: make([]RtcpSdesChunk, h.RC)}
+	for i := range sdes.Chunks {
+		if len(ptr) < 4 {
+			return nil, errors.New("rtcp sdes chunk length error.")
+		}
+
+		chunk := &sdes.Chunks[i]
+		chunk.SSRC = RtpReadUint32(ptr)
+		n := 4
+		for {
+			if n >= len(ptr) {
+				return nil, errors.New("rtcp sdes item length error.")
+			}
+			if ptr[n] == RTCP_SDES_END {
+				break
+			}
+			if n+2 > len(ptr) || n+2+int(ptr[n+1]) > len(ptr) {
+				return nil, errors.New("rtcp sdes item length error.")
+			}
+			chunk.Items = append(chunk.Items, RtcpSdesItem{Type: ptr[n], Text: ptr[n+2 : n+2+int(ptr[n+1])]})
+			n += 2 + int(ptr[n+1])
+		}
+
+		// skip null octets to next 32-bit boundary
+		n = (n + 4) / 4 * 4
+		if n > len(ptr) {
+			n = len(ptr)
+		}
+		ptr = ptr[n:]
+	}
+	return sdes, nil
+}
+
+// CNAME return the CNAME of first chunk match ssrc
+func (sdes *RtcpSdes) CNAME(ssrc uint32) []byte {
+	for _, chunk := range sdes.Chunks {
+		if chunk.SSRC != ssrc {
+			continue
+		}
+		for _, item := range chunk.Items {
+			if item.Type == RTCP_SDES_CNAME {
+				return item.Text
+			}
+		}
+	}
+	return nil
+}
+
+// RFC3550 6.6 BYE: Goodbye RTCP Packet (p49)
+/*
+       0                   1                   2                   3
+       0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+      |V=2|P|    SC   |   PT=BYE=203  |             length            |
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+      |                           SSRC/CSRC                           |
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+      :                              ...                              :
+      +=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+
+(opt) |     length    |               reason for leaving            ...
+      +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+func (bye *RtcpBye) PacketType() byte {
+	return RTCP_BYE
+}
+
+func (bye *RtcpBye) PacketSize() int {
+	size := RtcpHeaderLength + 4*len(bye.SSRC)
+	if len(bye.Reason) > 0 {
+		size += (1 + len(bye.Reason) + 3) / 4 * 4
+	}
+	return size
+}
+
+func (bye *RtcpBye) Serialize(data []byte, bytes int) (int, error) {
+	if len(bye.Reason) > 255 {
+		return 0, errors.New("rtcp bye reason too long.")
+	}
+
+	size := bye.PacketSize()
+	if err := rtcpSerializeHeader(data, bytes, RTCP_BYE, len(bye.SSRC), size); err != nil {
+		return 0, err
+	}
+
+	ptr := data[RtcpHeaderLength:size]
+	for _, ssrc := range bye.SSRC {
+		RtpWriteUint32(ptr, ssrc)
+		ptr = ptr[4:]
+	}
+	if len(bye.Reason) > 0 {
+		ptr[0] = byte(len(bye.Reason))
+		n := copy(ptr[1:], bye.Reason)
+		for i := 1 + n; i < len(ptr); i++ {
+			ptr[i] = 0
+		}
+	}
+	return size, nil
+}
+
+func rtcpByeDeserialize(h *RtcpHeader, ptr []byte) (*RtcpBye, error) {
+	if len(ptr) < int(h.RC)*4 {
+		return nil, errors.New("rtcp bye length error.")
+	}
+
+	bye := &RtcpBye{SSRC: make([]uint32, h.RC)}
+	for i := range bye.SSRC {
+		bye.SSRC[i] = RtpReadUint32(ptr)
+		ptr = ptr[4:]
+	}
+	if len(ptr) > 0 {
+		if 1+int(ptr[0]) > len(ptr) {
+			return nil, errors.New("rtcp bye reason length error.")
+		}
+		bye.Reason = ptr[1 : 1+int(ptr[0])]
+	}
+	return bye, nil
+}
+
+// RFC3550 6.7 APP: Application-Defined RTCP Packet (p50)
+/*
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |V=2|P| subtype |   PT=APP=204  |             length            |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                           SSRC/CSRC                           |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                          name (ASCII)                         |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                   application-dependent data                ...
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+func (app *RtcpApp) PacketType() byte {
+	return RTCP_APP
+}
+
+func (app *RtcpApp) PacketSize() int {
+	return RtcpHeaderLength + 8 + len(app.Data)
+}
+
+func (app *RtcpApp) Serialize(data []byte, bytes int) (int, error) {
+	if len(app.Data)%4 != 0 {
+		return 0, errors.New("rtcp app data must be multiple of 32 bits.")
+	}
+
+	size := app.PacketSize()
+	if err := rtcpSerializeHeader(data, bytes, RTCP_APP, int(app.Subtype), size); err != nil {
+		return 0, err
+	}
+
+	ptr := data[RtcpHeaderLength:]
+	RtpWriteUint32(ptr, app.SSRC)
+	copy(ptr[4:8], app.Name[:])
+	copy(ptr[8:], app.Data)
+	return size, nil
+}
+
+func rtcpAppDeserialize(h *RtcpHeader, ptr []byte) (*RtcpApp, error) {
+	if len(ptr) < 8 {
+		return nil, errors.New("rtcp app length error.")
+	}
+
+	app := &RtcpApp{Subtype: h.RC}
+	app.SSRC = RtpReadUint32(ptr)
+	copy(app.Name[:], ptr[4:8])
+	app.Data = ptr[8:]
+	return app, nil
+}
+
+func (raw *RtcpRaw) PacketType() byte {
+	return raw.Header.PT
+}
+
+func (raw *RtcpRaw) PacketSize() int {
+	return RtcpHeaderLength + (len(raw.Payload)+3)/4*4
+}
+
+func (raw *RtcpRaw) Serialize(data []byte, bytes int) (int, error) {
+	size := raw.PacketSize()
+	if err := rtcpSerializeHeader(data, bytes, raw.Header.PT, int(raw.Header.RC), size); err != nil {
+		return 0, err
+	}
+
+	n := copy(data[RtcpHeaderLength:size], raw.Payload)
+	for i := RtcpHeaderLength + n; i < size; i++ {
+		data[i] = 0
+	}
+	return size, nil
+}
@@ -0,0 +1,169 @@
+package rtp
+
+// RFC3550 6. RTP Control Protocol -- RTCP (p19)
+const (
+	RtcpHeaderLength  = 4  // common header 4 bytes
+	RtcpReportBlock   = 24 // reception report block 24 bytes
+	RtcpSenderInfo    = 20 // sender info 20 bytes
+	RtcpMaxReportSize = 31 // RC field is 5 bits
+)
+
+// RFC3550 12.1 RTCP Packet Types (p88)
+// RFC4585 6.1 Common Packet Format for Feedback Messages (p31)
+// RFC3611 2. Applicability (XR)
+const (
+	RTCP_FIR   = 192 // RFC2032 full intra-frame request
+	RTCP_NACK  = 193 // RFC2032 negative acknowledgement
+	RTCP_SMPTE = 194 // RFC5484 SMPTE time-code mapping
+	RTCP_IJ    = 195 // RFC5450 extended inter-arrival jitter report
+	RTCP_SR    = 200 // sender report
+	RTCP_RR    = 201 // receiver report
+	RTCP_SDES  = 202 // source description
+	RTCP_BYE   = 203 // goodbye
+	RTCP_APP   = 204 // application-defined
+	RTCP_RTPFB = 205 // RFC4585 transport layer feedback
+	RTCP_PSFB  = 206 // RFC4585 payload-specific feedback
+	RTCP_XR    = 207 // RFC3611 extended report
+)
+
+// RFC3550 12.2 SDES Types (p88)
+const (
+	RTCP_SDES_END   = 0 // end of SDES list
+	RTCP_SDES_CNAME = 1 // canonical name
+	RTCP_SDES_NAME  = 2 // user name
+	RTCP_SDES_EMAIL = 3 // user's electronic mail address
+	RTCP_SDES_PHONE = 4 // user's phone number
+	RTCP_SDES_LOC   = 5 // geographic user location
+	RTCP_SDES_TOOL  = 6 // name of application or tool
+	RTCP_SDES_NOTE  = 7 // notice about the source
+	RTCP_SDES_PRIV  = 8 // private extensions
+)
+
+// RFC3550 6.4.1 SR: Sender Report RTCP Packet (p36)
+/*
+ 0               1               2               3
+ 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|V=2|P|    RC   |   PT=SR=200   |             length            |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+type RtcpHeader struct {
+	Version byte   // protocol version
+	Padding byte   // padding flag
+	RC      byte   // reception report count / source count / subtype
+	PT      byte   // packet type
+	Length  uint16 // length in 32-bit words minus one, including header and padding
+}
+
+// RFC3550 6.4.1 report block (p38)
+/*
++=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+
+|                 SSRC_1 (SSRC of first source)                 |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+| fraction lost |       cumulative number of packets lost       |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|           extended highest sequence number received           |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                      interarrival jitter                      |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                         last SR (LSR)                         |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                   delay since last SR (DLSR)                  |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+type RtcpReport struct {
+	SSRC     uint32 // source this report is about
+	Fraction byte   // fraction lost since previous SR/RR
+	Lost     int32  // cumulative number of packets lost (signed 24 bits)
+	ExtSeq   uint32 // extended highest sequence number received
+	Jitter   uint32 // interarrival jitter
+	LSR      uint32 // last SR timestamp (middle 32 bits of NTP)
+	DLSR     uint32 // delay since last SR, 1/65536 seconds
+}
+
+// RtcpPacket is a single packet inside a RTCP compound packet
+type RtcpPacket interface {
+	// RTCP header PT field
+	PacketType() byte
+
+	// packet length in bytes, including the 4-byte common header
+	PacketSize() int
+
+	// write packet(header included) to data
+	// @return bytes written
+	Serialize(data []byte, bytes int) (int, error)
+}
+
+// RFC3550 6.4.1 SR: Sender Report RTCP Packet (p36)
+type RtcpSR struct {
+	SSRC      uint32 // SSRC of sender
+	NTPMSW    uint32 // NTP timestamp, most significant word
+	NTPLSW    uint32 // NTP timestamp, least significant word
+	RTPTime   uint32 // RTP timestamp
+	Packets   uint32 // sender's packet count
+	Octets    uint32 // sender's octet count
+	Reports   []RtcpReport
+	Extension []byte // profile-specific extensions
+}
+
+// RFC3550 6.4.2 RR: Receiver Report RTCP Packet (p42)
+type RtcpRR struct {
+	SSRC      uint32 // SSRC of packet sender
+	Reports   []RtcpReport
+	Extension []byte // profile-specific extensions
+}
+
+// RFC3550 6.5 SDES: Source Description RTCP Packet (p45)
+type RtcpSdesItem struct {
+	Type byte
+	Text []byte // 0~255 bytes
+}
+
+type RtcpSdesChunk struct {
+	SSRC  uint32
+	Items []RtcpSdesItem
+}
+
+type RtcpSdes struct {
+	Chunks []RtcpSdesChunk
+}
+
+// RFC3550 6.6 BYE: Goodbye RTCP Packet (p49)
+type RtcpBye struct {
+	SSRC   []uint32
+	Reason []byte // optional, 0~255 bytes
+}
+
+// RFC3550 6.7 APP: Application-Defined RTCP Packet (p50)
+type RtcpApp struct {
+	Subtype byte // 5 bits
+	SSRC    uint32
+	Name    [4]byte
+	Data    []byte // multiple of 32 bits
+}
+
+// RtcpRaw keep packet types this library don't parse(PSFB/XR/RTPFB other than NACK...)
+type RtcpRaw struct {
+	Header  RtcpHeader
+	Payload []byte // packet body after common header, padding removed
+}
+
+func RTCP_V(v uint32) byte {
+	return byte((v >> 30) & 0x03)
+}
+
+func RTCP_P(v uint32) byte {
+	return byte((v >> 29) & 0x01)
+}
+
+func RTCP_RC(v uint32) byte {
+	return byte((v >> 24) & 0x1F)
+}
+
+func RTCP_PT(v uint32) byte {
+	return byte((v >> 16) & 0xFF)
+}
+
+func RTCP_LEN(v uint32) uint16 {
+	return uint16((v >> 00) & 0xFFFF)
+}
@@ -0,0 +1,133 @@
+package rtp
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RFC3550 4. Byte Order, Alignment, and Time Format (p12)
+// Wallclock time (absolute date and time) is represented using the
+// timestamp format of the Network Time Protocol (NTP), which is in
+// seconds relative to 0h UTC on 1 January 1900. The full resolution
+// NTP timestamp is a 64-bit unsigned fixed-point number with the
+// integer part in the first 32 bits and the fractional part in the
+// last 32 bits.
+const (
+	RtpNtpOffset = 2208988800 // seconds from 1900-01-01 to 1970-01-01
+)
+
+// RtpTimeToNtp convert wall-clock time to 64-bit NTP timestamp
+func RtpTimeToNtp(t time.Time) uint64 {
+	sec := uint64(t.Unix() + RtpNtpOffset)
+	frac := (uint64(t.Nanosecond()) << 32) / uint64(time.Second)
+	return sec<<32 | frac
+}
+
+// RtpNtpToTime convert 64-bit NTP timestamp to wall-clock time
+func RtpNtpToTime(ntp uint64) time.Time {
+	sec := int64(ntp>>32) - RtpNtpOffset
+	nsec := int64(((ntp & 0xFFFFFFFF) * uint64(time.Second)) >> 32)
+	return time.Unix(sec, nsec)
+}
+
+// RtpClock sender side media clock, map wall-clock time to RTP timestamp
+// RFC3550 5.1 RTP Fixed Header Fields (p14)
+// The initial value of the timestamp SHOULD be random
+type RtpClock struct {
+	Frequency int    // payload clock rate
+	Offset    uint32 // RTP timestamp of base time
+	base      time.Time
+}
+
+// NewRtpClock create media clock
+// @param[in] frequency payload clock rate
+// @param[in] base wall-clock time of RTP timestamp Offset, e.g. stream start time
+// @param[in] random initial RTP timestamp generator, nil-math/rand
+func NewRtpClock(frequency int, base time.Time, random func() uint32) *RtpClock {
+	if random == nil {
+		random = rand.Uint32
+	}
+	return &RtpClock{Frequency: frequency, Offset: random(), base: base}
+}
+
+// Timestamp return RTP timestamp of the wall-clock time, e.g. capture time
+func (c *RtpClock) Timestamp(t time.Time) uint32 {
+	d := t.Sub(c.base)
+	if d < 0 {
+		return c.Offset - RtpDurationToTimestamp(-d, c.Frequency)
+	}
+	return c.Offset + RtpDurationToTimestamp(d, c.Frequency)
+}
+
+// SenderInfo fill NTP/RTP timestamp of sender report
+// @param[in] now report time
+func (c *RtpClock) SenderInfo(sr *RtcpSR, now time.Time) {
+	ntp := RtpTimeToNtp(now)
+	sr.NTPMSW = uint32(ntp >> 32)
+	sr.NTPLSW = uint32(ntp)
+	sr.RTPTime = c.Timestamp(now)
+}
+
+// RtpClockRecovery receiver side, reconstruct wall-clock capture time of
+// RTP timestamp from Sender Report NTP/RTP timestamp pair
+type RtpClockRecovery struct {
+	Frequency int // payload clock rate
+
+	started bool
+	highest int64 // highest extended timestamp
+
+	synced bool
+	srTime time.Time // wall-clock time of the last SR
+	srRtp  int64     // extended RTP timestamp of the last SR
+}
+
+func NewRtpClockRecovery(frequency int) *RtpClockRecovery {
+	return &RtpClockRecovery{Frequency: frequency}
+}
+
+// Extend extend 32-bit RTP timestamp to 64 bits, counting wraparound,
+// the nearest value to the highest timestamp seen is chosen,
+// so reordered timestamps within 2^31 ticks extend correctly
+func (r *RtpClockRecovery) Extend(timestamp uint32) int64 {
+	if !r.started {
+		r.started = true
+		r.highest = int64(timestamp)
+		return r.highest
+	}
+
+	ext := r.highest + int64(int32(timestamp-uint32(r.highest)))
+	if ext > r.highest {
+		r.highest = ext
+	}
+	return ext
+}
+
+// OnSR record NTP/RTP timestamp pair of the sender report
+func (r *RtpClockRecovery) OnSR(sr *RtcpSR) {
+	r.synced = true
+	r.srTime = RtpNtpToTime(uint64(sr.NTPMSW)<<32 | uint64(sr.NTPLSW))
+	r.srRtp = r.Extend(sr.RTPTime)
+}
+
+// Synced return true if a sender report received
+func (r *RtpClockRecovery) Synced() bool {
+	return r.synced
+}
+
+// Time return wall-clock capture time of RTP timestamp
+// @return false if no sender report received
+func (r *RtpClockRecovery) Time(timestamp uint32) (time.Time, bool) {
+	ext := r.Extend(timestamp)
+	if !r.synced || r.Frequency <= 0 {
+		return time.Time{}, false
+	}
+	return r.srTime.Add(RtpTimestampToDuration(ext-r.srRtp, r.Frequency)), true
+}
+
+// RtpTimestampToDuration convert RTP timestamp difference to duration, 0 if clock rate unknown
+func RtpTimestampToDuration(ticks int64, frequency int) time.Duration {
+	if frequency <= 0 {
+		return 0
+	}
+	return time.Duration(ticks/int64(frequency))*time.Second + time.Duration(ticks%int64(frequency))*time.Second/time.Duration(frequency)
+}
@@ -0,0 +1,226 @@
+package rtp
+
+import (
+	"errors"
+)
+
+// RFC8285 A General Mechanism for RTP Header Extensions
+// 4.2. One-Byte Header (p8)
+/*
+ 0                   1                   2                   3
+ 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|       0xBE    |    0xDE       |           length=3            |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|  ID   | L=0   |     data      |  ID   |  L=1  |   data...
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+      ...data   |    0 (pad)    |    0 (pad)    |  ID   | L=3   |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                          data                                 |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+// 4.3. Two-Byte Header (p10)
+/*
+ 0                   1                   2                   3
+ 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|       0x10    |    0x00       |           length=3            |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|      ID       |     L=0       |     ID        |     L=1       |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|       data    |    0 (pad)    |       ID      |      L=4      |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+|                          data                                 |
++-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+const (
+	RtpExtensionOneByte     = 0xBEDE // one-byte header profile
+	RtpExtensionTwoByte     = 0x1000 // two-byte header profile 0x100X, X is appbits
+	RtpExtensionTwoByteMask = 0xFFF0
+
+	RtpExtensionOneByteMaxID  = 14 // ID 15 reserved, stop parsing
+	RtpExtensionOneByteMaxLen = 16
+	RtpExtensionTwoByteMaxLen = 255
+)
+
+// RtpExtension is a RFC8285 header extension element
+type RtpExtension struct {
+	ID   byte
+	Data []byte
+}
+
+// RtpExtensionDeserialize parse RFC8285 header extension elements
+// @param[in] profile "defined by profile" field, 0xBEDE or 0x100X
+// @param[in] data extension data, without the 4-byte extension header
+// @return elements, data point to the packet buffer
+func RtpExtensionDeserialize(profile uint16, data []byte) ([]RtpExtension, error) {
+	var exts []RtpExtension
+	if profile == RtpExtensionOneByte {
+		for i := 0; i < len(data); {
+			if data[i]>>4 == 0 {
+				i++ // padding
+				continue
+			}
+
+			id := data[i] >> 4
+			n := int(data[i]&0x0F) + 1
+			if id == 15 {
+				break // reserved for future extension, stop processing
+			}
+			if i+1+n > len(data) {
+				return nil, errors.New("rtp extension length error.")
+			}
+			exts = append(exts, RtpExtension{ID: id, Data: data[i+1 : i+1+n]})
+			i += 1 + n
+		}
+	} else if profile&RtpExtensionTwoByteMask == RtpExtensionTwoByte {
+		for i := 0; i < len(data); {
+			if data[i] == 0 {
+				i++ // padding
+				continue
+			}
+			if i+2 > len(data) {
+				return nil, errors.New("rtp extension length error.")
+			}
+
+			id := data[i]
+			n := int(data[i+1])
+			if i+2+n > len(data) {
+				return nil, errors.New("rtp extension length error.")
+			}
+			exts = append(exts, RtpExtension{ID: id, Data: data[i+2 : i+2+n]})
+			i += 2 + n
+		}
+	} else {
+		return nil, errors.New("rtp extension profile not RFC8285.")
+	}
+	return exts, nil
+}
+
+// RtpExtensionSerialize write header extension elements,
+// choose one-byte header if possible, otherwise two-byte header
+// @return profile, extension data padding to 32 bits
+func RtpExtensionSerialize(exts []RtpExtension) (uint16, []byte, error) {
+	var profile uint16 = RtpExtensionOneByte
+	size := 0
+	for _, ext := range exts {
+		if ext.ID == 0 {
+			return 0, nil, errors.New("rtp extension id 0 is reserved.")
+		}
+		if len(ext.Data) > RtpExtensionTwoByteMaxLen {
+			return 0, nil, errors.New("rtp extension data too long.")
+		}
+		if ext.ID > RtpExtensionOneByteMaxID || len(ext.Data) < 1 || len(ext.Data) > RtpExtensionOneByteMaxLen {
+			profile = RtpExtensionTwoByte
+		}
+		size += len(ext.Data)
+	}
+
+	if profile == RtpExtensionOneByte {
+		size += len(exts)
+	} else {
+		size += len(exts) * 2
+	}
+
+	data := make([]byte, (size+3)/4*4)
+	ptr := data
+	for _, ext := range exts {
+		if profile == RtpExtensionOneByte {
+			ptr[0] = (ext.ID << 4) | byte(len(ext.Data)-1)
+			ptr = ptr[1:]
+		} else {
+			ptr[0] = ext.ID
+			ptr[1] = byte(len(ext.Data))
+			ptr = ptr[2:]
+		}
+		ptr = ptr[copy(ptr, ext.Data):]
+	}
+	return profile, data, nil
+}
+
+// Extensions return all RFC8285 header extension elements
+func (pkt *RtpPacket) Extensions() ([]RtpExtension, error) {
+	if pkt.Header.Extension == 0 {
+		return nil, nil
+	}
+	if int(pkt.Extlen) > len(pkt.Extension) {
+		return nil, errors.New("rtp extension length error.")
+	}
+	return RtpExtensionDeserialize(pkt.Reserved, pkt.Extension[:pkt.Extlen])
+}
+
+// GetExtension return header extension element data by id, nil if not found
+func (pkt *RtpPacket) GetExtension(id byte) []byte {
+	exts, err := pkt.Extensions()
+	if err != nil {
+		return nil
+	}
+	for _, ext := range exts {
+		if ext.ID == id {
+			return ext.Data
+		}
+	}
+	return nil
+}
+
+// SetExtension add or replace header extension element
+func (pkt *RtpPacket) SetExtension(id byte, data []byte) error {
+	exts, err := pkt.Extensions()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i := range exts {
+		if exts[i].ID == id {
+			exts[i].Data = data
+			found = true
+		}
+	}
+	if !found {
+		exts = append(exts, RtpExtension{ID: id, Data: data})
+	}
+	return pkt.setExtensions(exts)
+}
+
+// RemoveExtension remove header extension element,
+// the extension bit is cleared if no element left
+func (pkt *RtpPacket) RemoveExtension(id byte) error {
+	exts, err := pkt.Extensions()
+	if err != nil {
+		return err
+	}
+
+	n := 0
+	for _, ext := range exts {
+		if ext.ID != id {
+			exts[n] = ext
+			n++
+		}
+	}
+	return pkt.setExtensions(exts[:n])
+}
+
+func (pkt *RtpPacket) setExtensions(exts []RtpExtension) error {
+	if len(exts) == 0 {
+		pkt.Header.Extension = 0
+		pkt.Extension = nil
+		pkt.Extlen = 0
+		pkt.Reserved = 0
+		return nil
+	}
+
+	profile, data, err := RtpExtensionSerialize(exts)
+	if err != nil {
+		return err
+	}
+	if len(data) > 0xFFFF {
+		return errors.New("rtp extension too long.")
+	}
+
+	pkt.Header.Extension = 1
+	pkt.Reserved = profile
+	pkt.Extension = data
+	pkt.Extlen = uint16(len(data))
+	return nil
+}
@@ -1 +1,320 @@
 package rtp
+
+import (
+	"errors"
+	"time"
+)
+
+// RFC3550 6.3 RTCP Packet Send and Receive Rules (p28)
+// RFC3550 8 SSRC Identifier Allocation and Use (p59)
+const (
+	RtpMinSequential   = 2 // packets required for a new source to become valid (A.1)
+	RtpSenderTimeout   = 2 // sender -> receiver after 2 * T without RTP (6.3.5)
+	RtpMemberTimeout   = 5 // member timeout after M * Td, M = 5 (6.3.5)
+	RtpConflictTimeout = 10 * RtcpMinInterval
+	RtcpMinInterval    = 5 * time.Second // RTCP minimum interval Tmin (6.2)
+)
+
+var (
+	ErrRtpSsrcCollision = errors.New("rtp ssrc collision.")
+	ErrRtpSsrcLoop      = errors.New("rtp ssrc loop.")
+)
+
+type RtpMember struct {
+	SSRC      uint32
+	SDES      [RTCP_SDES_PRIV + 1][]byte // SDES items, index by item type
+	Sender    bool                       // RTP sent within last 2 report intervals
+	Validated bool                       // passed probation or sent RTCP
+	Bye       bool                       // BYE received, member removed from table
+	Csrc      bool                       // learned from CSRC list only
+
+	FirstSeen   time.Time // first RTP/RTCP packet
+	LastSeen    time.Time // last RTP/RTCP packet
+	LastRtp     time.Time // last RTP packet
+	LastRtcp    time.Time // last RTCP packet
+	RtpAddress  string    // source transport address of RTP
+	RtcpAddress string    // source transport address of RTCP
+
+	Packets uint32 // RTP packets received
+	Octets  uint64 // RTP payload octets received
+
+	// last SR, for LSR/DLSR of the report block
+	SRNtp  uint64    // NTP timestamp of the last SR
+	SRRtp  uint32    // RTP timestamp of the last SR
+	SRTime time.Time // arrival time of the last SR
+
+	Source RtpSource // sequence number state
+}
+
+// CNAME return SDES CNAME item
+func (m *RtpMember) CNAME() []byte {
+	return m.SDES[RTCP_SDES_CNAME]
+}
+
+type rtpConflict struct {
+	address string
+	time    time.Time
+}
+
+// RtpMemberTable is the RFC3550 participant database of a session
+type RtpMemberTable struct {
+	self      *RtpMember
+	members   map[uint32]*RtpMember
+	conflicts []rtpConflict // RFC3550 8.2 conflicting source addresses of our own ssrc
+	nmembers  int           // validated members, include self
+	nsenders  int           // senders, include self
+}
+
+func NewRtpMemberTable(ssrc uint32, cname []byte, now time.Time) *RtpMemberTable {
+	t := &RtpMemberTable{members: make(map[uint32]*RtpMember)}
+	t.self = &RtpMember{SSRC: ssrc, Validated: true, FirstSeen: now, LastSeen: now}
+	t.self.SDES[RTCP_SDES_CNAME] = cname
+	t.members[ssrc] = t.self
+	t.nmembers = 1
+	return t
+}
+
+// Self return local participant
+func (t *RtpMemberTable) Self() *RtpMember {
+	return t.self
+}
+
+// Members return the number of validated members, include self
+func (t *RtpMemberTable) Members() int {
+	return t.nmembers
+}
+
+// Senders return the number of active senders, include self
+func (t *RtpMemberTable) Senders() int {
+	return t.nsenders
+}
+
+func (t *RtpMemberTable) Find(ssrc uint32) *RtpMember {
+	return t.members[ssrc]
+}
+
+// Range call f for every member(include self), stop if f return false
+func (t *RtpMemberTable) Range(f func(m *RtpMember) bool) {
+	for _, m := range t.members {
+		if !f(m) {
+			return
+		}
+	}
+}
+
+// SetSender mark local participant as sender(we_sent), call after send RTP packet
+func (t *RtpMemberTable) SetSender(now time.Time) {
+	t.self.LastRtp = now
+	t.self.LastSeen = now
+	t.setSender(t.self, true)
+}
+
+// ChangeSSRC choose a new local SSRC after collision (RFC3550 8.2)
+// the old identifier should be sent in a BYE by caller
+func (t *RtpMemberTable) ChangeSSRC(ssrc uint32) error {
+	if _, ok := t.members[ssrc]; ok {
+		return errors.New("rtp ssrc in use.")
+	}
+	delete(t.members, t.self.SSRC)
+	t.self.SSRC = ssrc
+	t.members[ssrc] = t.self
+	return nil
+}
+
+// OnRtp update member table with a received RTP packet
+// @param[in] pkt received RTP packet
+// @param[in] address source transport address
+// @param[in] now arrival time
+// @return member, ErrRtpSsrcCollision/ErrRtpSsrcLoop-packet should be discarded
+func (t *RtpMemberTable) OnRtp(pkt *RtpPacket, address string, now time.Time) (*RtpMember, error) {
+	m, err := t.fetch(pkt.Header.SSRC, address, false, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Packets == 0 {
+		probation := RtpMinSequential
+		if m.Validated {
+			probation = 0 // validated by RTCP
+		}
+		m.Source.Start(pkt.Header.SequenceNumber, probation)
+	}
+	if m.Source.Update(pkt.Header.SequenceNumber) != RTP_SEQ_PROBATION && !m.Validated {
+		t.validate(m)
+	}
+	m.Packets++
+	m.Octets += uint64(pkt.PayloadLen)
+	m.LastRtp = now
+	if m.Validated {
+		t.setSender(m, true)
+	}
+
+	// RFC3550 6.3.3: CSRC identifiers are also counted as members
+	for i := 0; i < int(pkt.Header.CSRC) && i < len(pkt.CSRC); i++ {
+		c, ok := t.members[pkt.CSRC[i]]
+		if !ok {
+			c = &RtpMember{SSRC: pkt.CSRC[i], Csrc: true, FirstSeen: now}
+			t.members[c.SSRC] = c
+			t.validate(c)
+		}
+		c.LastSeen = now
+	}
+	return m, nil
+}
+
+// OnRtcp update member table with a received RTCP compound packet
+// @param[in] pkts RTCP packets from RtcpCompoundDeserialize
+// @param[in] address source transport address
+// @param[in] now arrival time
+// @return ErrRtpSsrcCollision/ErrRtpSsrcLoop-packet should be discarded
+func (t *RtpMemberTable) OnRtcp(pkts []RtcpPacket, address string, now time.Time) error {
+	for _, pkt := range pkts {
+		switch v := pkt.(type) {
+		case *RtcpSR:
+			m, err := t.fetch(v.SSRC, address, true, now)
+			if err != nil {
+				return err
+			}
+			m.SRNtp = uint64(v.NTPMSW)<<32 | uint64(v.NTPLSW)
+			m.SRRtp = v.RTPTime
+			m.SRTime = now
+			t.validate(m)
+
+		case *RtcpRR:
+			m, err := t.fetch(v.SSRC, address, true, now)
+			if err != nil {
+				return err
+			}
+			t.validate(m)
+
+		case *RtcpSdes:
+			for _, chunk := range v.Chunks {
+				m, err := t.fetch(chunk.SSRC, address, true, now)
+				if err != nil {
+					return err
+				}
+				for _, item := range chunk.Items {
+					if int(item.Type) < len(m.SDES) {
+						m.SDES[item.Type] = append([]byte(nil), item.Text...)
+					}
+				}
+				t.validate(m)
+			}
+
+		case *RtcpBye:
+			for _, ssrc := range v.SSRC {
+				t.bye(ssrc)
+			}
+		}
+	}
+	return nil
+}
+
+// Timeout remove inactive members and clear sender flags (RFC3550 6.3.5)
+// @param[in] now current time
+// @param[in] interval current RTCP transmission interval T
+// @param[in] deterministic deterministic RTCP interval Td(without randomization)
+// @return removed SSRC list
+func (t *RtpMemberTable) Timeout(now time.Time, interval, deterministic time.Duration) []uint32 {
+	var removed []uint32
+	for ssrc, m := range t.members {
+		if m.Sender && now.Sub(m.LastRtp) > RtpSenderTimeout*interval {
+			t.setSender(m, false)
+		}
+		if m != t.self && now.Sub(m.LastSeen) > RtpMemberTimeout*deterministic {
+			t.remove(m)
+			removed = append(removed, ssrc)
+		}
+	}
+
+	// forget conflicting address, RFC3550 8.2: timed out after 10*RTCP interval
+	n := 0
+	for _, c := range t.conflicts {
+		if now.Sub(c.time) <= RtpConflictTimeout {
+			t.conflicts[n] = c
+			n++
+		}
+	}
+	t.conflicts = t.conflicts[:n]
+	return removed
+}
+
+// RFC3550 8.2 Collision Resolution and Loop Detection (p60)
+func (t *RtpMemberTable) fetch(ssrc uint32, address string, rtcp bool, now time.Time) (*RtpMember, error) {
+	m, ok := t.members[ssrc]
+	if !ok {
+		m = &RtpMember{SSRC: ssrc, FirstSeen: now}
+		if rtcp {
+			m.RtcpAddress = address
+		} else {
+			m.RtpAddress = address
+		}
+		t.members[ssrc] = m
+	} else if m == t.self {
+		// own ssrc received from network
+		for i := range t.conflicts {
+			if t.conflicts[i].address == address {
+				// our own packets have looped
+				t.conflicts[i].time = now
+				return nil, ErrRtpSsrcLoop
+			}
+		}
+		t.conflicts = append(t.conflicts, rtpConflict{address: address, time: now})
+		return nil, ErrRtpSsrcCollision
+	} else {
+		addr := &m.RtpAddress
+		if rtcp {
+			addr = &m.RtcpAddress
+		}
+		if *addr == "" || m.Csrc {
+			*addr = address
+			m.Csrc = false
+		} else if *addr != address {
+			// third-party collision or loop, discard and keep the original source
+			return nil, ErrRtpSsrcCollision
+		}
+	}
+
+	m.LastSeen = now
+	if rtcp {
+		m.LastRtcp = now
+	}
+	return m, nil
+}
+
+func (t *RtpMemberTable) validate(m *RtpMember) {
+	if !m.Validated {
+		m.Validated = true
+		t.nmembers++
+	}
+}
+
+func (t *RtpMemberTable) setSender(m *RtpMember, sender bool) {
+	if m.Sender != sender {
+		m.Sender = sender
+		if sender {
+			t.nsenders++
+		} else {
+			t.nsenders--
+		}
+	}
+}
+
+// RFC3550 6.3.7 Transmitting a BYE Packet / 6.2.1 Maintaining the Number of Session Members
+func (t *RtpMemberTable) bye(ssrc uint32) {
+	m, ok := t.members[ssrc]
+	if !ok || m == t.self {
+		return
+	}
+	m.Bye = true
+	t.remove(m)
+}
+
+func (t *RtpMemberTable) remove(m *RtpMember) {
+	t.setSender(m, false)
+	if m.Validated {
+		m.Validated = false
+		t.nmembers--
+	}
+	delete(t.members, m.SSRC)
+}
@@ -0,0 +1,155 @@
+package rtp
+
+import (
+	"time"
+)
+
+// RFC4585 3.5.1 Timing Rules / RFC4588 3. Retransmission Payload Format
+// Receiver request missing packets with Generic NACK, a packet is requested
+// again if not arrived one round-trip time after the previous request, and
+// given up after MaxRetries requests or MaxAge since detected(decoder moved on).
+const (
+	RtpNackMaxRetries  = 10
+	RtpNackMaxAge      = time.Second
+	RtpNackMaxSize     = 1000                   // missing packets tracked
+	RtpNackDefaultRtt  = 100 * time.Millisecond // before RTT known
+	RtpNackMinInterval = 5 * time.Millisecond   // lower bound of re-request interval
+)
+
+type rtpNackItem struct {
+	seq      int64 // extended sequence number
+	detected time.Time
+	sent     time.Time // last NACK time
+	retries  int
+}
+
+// RtpNackGenerator track missing sequence numbers of one source for Generic NACK,
+// feed every received packet sequence number(before jitter buffer/unpacker), then
+// call Nack periodically or after Input detected loss.
+// All times are supplied by caller so the generator is deterministic.
+type RtpNackGenerator struct {
+	MaxRetries int           // NACK requests per packet
+	MaxAge     time.Duration // stop requesting packet detected earlier than MaxAge
+	MaxSize    int           // maximum missing packets tracked, oldest dropped
+
+	Requested int // sequence numbers requested, retries included
+	Recovered int // missing packets arrived
+	Expired   int // missing packets given up
+
+	started bool
+	maxSeq  int64          // highest extended sequence number received
+	badSeq  uint32         // last out-of-window seq number + 1
+	missing []*rtpNackItem // sort by extended sequence number
+}
+
+func NewRtpNackGenerator() *RtpNackGenerator {
+	return &RtpNackGenerator{MaxRetries: RtpNackMaxRetries, MaxAge: RtpNackMaxAge, MaxSize: RtpNackMaxSize}
+}
+
+// Input update with a received sequence number
+// @param[in] seq RTP sequence number, retransmitted packets included(RTX OSN)
+// @param[in] now packet arrival time
+// @return true if the packet was missing(recovered)
+func (g *RtpNackGenerator) Input(seq uint16, now time.Time) bool {
+	if !g.started {
+		g.started = true
+		g.maxSeq = int64(seq)
+		g.badSeq = RtpSeqMod + 1 // so seq == badSeq is false
+		return false
+	}
+
+	ext := g.maxSeq + int64(int16(seq-uint16(g.maxSeq)))
+	if ext <= g.maxSeq {
+		// retransmission may arrive far behind the highest seq, e.g. mobile links
+		for i, item := range g.missing {
+			if item.seq == ext {
+				g.missing = append(g.missing[:i], g.missing[i+1:]...)
+				g.Recovered++
+				return true
+			}
+		}
+	}
+
+	if ext-g.maxSeq > RtpMaxDropout || g.maxSeq-ext > RtpMaxMisorder {
+		// RFC3550 A.1: resync only after two sequential out-of-window packets,
+		// a single one is a stray late/duplicate packet
+		if uint32(seq) != g.badSeq {
+			g.badSeq = (uint32(seq) + 1) & (RtpSeqMod - 1)
+			return false
+		}
+
+		// source restart, resync
+		g.Expired += len(g.missing)
+		g.missing = g.missing[:0]
+		g.maxSeq = int64(seq)
+		g.badSeq = RtpSeqMod + 1
+		return false
+	}
+
+	if ext <= g.maxSeq {
+		return false // duplicate or given up
+	}
+
+	for s := g.maxSeq + 1; s < ext; s++ {
+		g.missing = append(g.missing, &rtpNackItem{seq: s, detected: now})
+	}
+	g.maxSeq = ext
+	if g.MaxSize > 0 && len(g.missing) > g.MaxSize {
+		n := len(g.missing) - g.MaxSize
+		g.Expired += n
+		g.missing = append(g.missing[:0], g.missing[n:]...)
+	}
+	return false
+}
+
+// Nack return sequence numbers to request now
+// @param[in] now current time
+// @param[in] rtt round-trip time from RTCP report(LSR/DLSR), 0-unknown
+// @return lost sequence numbers in ascending order for RtcpNack.Lost, nil if nothing to request
+func (g *RtpNackGenerator) Nack(now time.Time, rtt time.Duration) []uint16 {
+	if rtt <= 0 {
+		rtt = RtpNackDefaultRtt
+	}
+	if rtt < RtpNackMinInterval {
+		rtt = RtpNackMinInterval
+	}
+
+	var lost []uint16
+	j := 0
+	for _, item := range g.missing {
+		due := item.retries == 0 || now.Sub(item.sent) >= rtt
+		if (due && g.MaxRetries > 0 && item.retries >= g.MaxRetries) || (g.MaxAge > 0 && now.Sub(item.detected) > g.MaxAge) {
+			g.Expired++
+			continue
+		}
+		g.missing[j] = item
+		j++
+
+		if due {
+			item.sent = now
+			item.retries++
+			lost = append(lost, uint16(item.seq))
+		}
+	}
+	for i := j; i < len(g.missing); i++ {
+		g.missing[i] = nil
+	}
+	g.missing = g.missing[:j]
+	g.Requested += len(lost)
+	return lost
+}
+
+// Feedback build Generic NACK packet
+// @return nil if nothing to request
+func (g *RtpNackGenerator) Feedback(sender, media uint32, now time.Time, rtt time.Duration) *RtcpNack {
+	lost := g.Nack(now, rtt)
+	if len(lost) == 0 {
+		return nil
+	}
+	return &RtcpNack{SenderSSRC: sender, MediaSSRC: media, Lost: lost}
+}
+
+// Missing return count of missing packets still requested
+func (g *RtpNackGenerator) Missing() int {
+	return len(g.missing)
+}
@@ -56,7 +56,11 @@ func RtpPacketDeserialize(pkt *RtpPacket, data []byte, bytes int) error {
 	}
 
 	// pkt contributing source
-	pkt.CSRC = make([]uint32, pkt.Header.CSRC)
+	if cap(pkt.CSRC) >= int(pkt.Header.CSRC) {
+		pkt.CSRC = pkt.CSRC[:pkt.Header.CSRC] // reuse
+	} else {
+		pkt.CSRC = make([]uint32, pkt.Header.CSRC)
+	}
 	for i := 0; i < int(pkt.Header.CSRC); i++ {
 		pkt.CSRC[i] = RtpReadUint32(ptr[12+i*4:])
 	}
@@ -87,6 +91,7 @@ func RtpPacketDeserialize(pkt *RtpPacket, data []byte, bytes int) error {
 			return errors.New("payload len error3.")
 		}
 		pkt.PayloadLen -= int(padding)
+		pkt.Padlen = int(padding)
 	}
 	return nil
 }
@@ -98,6 +103,9 @@ func RtpPacketSerializeHeader(pkt *RtpPacket, data []byte, bytes int) (int, erro
 	if (pkt.Extlen % 4) != 0 {
 		return 0, errors.New("rtp extendlen error.")
 	}
+	if pkt.Header.Extension > 0 && int(pkt.Extlen) > len(pkt.Extension) {
+		return 0, errors.New("rtp extension error.")
+	}
 
 	// RFC3550 5.1 RTP Fixed Header Fields(p12)
 	headlen := RtpFixedHeader + int(pkt.Header.CSRC*4)
@@ -129,6 +137,9 @@ func RtpPacketSerializeHeader(pkt *RtpPacket, data []byte, bytes int) (int, erro
 }
 
 func RtpPacketSerialize(pkt *RtpPacket, data []byte, bytes int) (int, error) {
+	if pkt.Header.Padding > 0 && (pkt.Padlen < 1 || pkt.Padlen > 255) {
+		return 0, errors.New("rtp padding flag without padding length(1~255).")
+	}
 	headlen, err := RtpPacketSerializeHeader(pkt, data, bytes)
 	if err != nil {
 		return 0, err
@@ -136,10 +147,50 @@ func RtpPacketSerialize(pkt *RtpPacket, data []byte, bytes int) (int, error) {
 	if headlen < RtpFixedHeader {
 		return 0, errors.New("Rtp fixed header error.")
 	}
-	if headlen+pkt.PayloadLen > bytes {
+	padlen := 0
+	if pkt.Header.Padding > 0 {
+		padlen = pkt.Padlen
+	}
+	if headlen+pkt.PayloadLen+padlen > bytes {
 		return 0, errors.New("bytes too small.")
 	}
 
 	copy(data[headlen:], pkt.Payload[:pkt.PayloadLen])
+	if pkt.Header.Padding > 0 {
+		return RtpPacketPadding(data, headlen+pkt.PayloadLen, padlen)
+	}
 	return headlen + pkt.PayloadLen, nil
 }
+
+// RtpPaddingSize return padding bytes to align packet size to multiple of block
+// @param[in] bytes packet size without padding
+// @param[in] block alignment, 0/1-no padding, 255 at most
+func RtpPaddingSize(bytes int, block int) int {
+	if block <= 1 {
+		return 0
+	}
+	return (block - bytes%block) % block
+}
+
+// RtpPacketPadding append padding to a serialized packet and set the P bit
+// RFC3550 5.1: The last octet of the padding contains a count of how many padding
+// octets should be ignored, including itself.
+// @param[in] data serialized packet, len(data) >= bytes + padlen
+// @param[in] bytes packet size without padding
+// @param[in] padlen padding bytes 1~255
+// @return packet size with padding
+func RtpPacketPadding(data []byte, bytes int, padlen int) (int, error) {
+	if padlen < 1 || padlen > 255 {
+		return 0, errors.New("rtp padding length error.")
+	}
+	if bytes < RtpFixedHeader || len(data) < bytes+padlen {
+		return 0, errors.New("bytes too small.")
+	}
+
+	data[0] |= RtpHeader_PaddingMask << RtpHeader_PaddingShift
+	for i := bytes; i < bytes+padlen-1; i++ {
+		data[i] = 0
+	}
+	data[bytes+padlen-1] = byte(padlen)
+	return bytes + padlen, nil
+}
@@ -1,5 +1,11 @@
 package rtp
 
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
 // https://en.wikipedia.org/wiki/RTP_audio_video_profile
 // RFC3551 6. Payload Type Definitions (p28)
 type RtpProfile struct {
@@ -15,65 +21,89 @@ type RtpProfile struct {
 	Name [32]byte // 32 byte
 }
 
-/***
-{
+const (
+	RTP_AVTYPE_UNKNOWN = 0
+	RTP_AVTYPE_AUDIO   = 1
+	RTP_AVTYPE_VIDEO   = 2
+	RTP_AVTYPE_SYSTEM  = 3 // audio/video
+)
+
+// RFC3551 6. Payload Type Definitions, Table 4/5 (p28)
+var rtpStaticProfiles = [...]struct {
+	payload   int
+	avtype    int
+	name      string
+	frequency int
+	channels  int
+}{
 	// audio
-	{ 0, "PCMU",	8000,	1 }, // G711 mu-law
-	{ 1, "",		0,		0 }, // reserved
-	{ 2, "",		0,		0 }, // reserved
-	{ 3, "GSM",		8000,	1 },
-	{ 4, "G723",	8000,	1 },
-	{ 5, "DVI4",	8000,	1 },
-	{ 6, "DVI4",	16000,	1 },
-	{ 7, "LPC",		8000,	1 },
-	{ 8, "PCMA",	8000,	1 }, // G711 A-law
-	{ 9, "G722",	8000,	1 },
-	{10, "L16",		44100,	2 },
-	{11, "L16",		44100,	1 },
-	{12, "QCELP",	8000,	1 },
-	{13, "CN",		8000,	1 },
-	{14, "MPA",		90000,	0 }, // MPEG-1/MPEG-2 audio
-	{15, "G728",	8000,	1 },
-	{16, "DVI4",	11025,	1 },
-	{17, "DVI4",	22050,	1 },
-	{18, "G729",	8000,	1 },
-	{19, "",		0,		0 }, // reserved
-	{20, "",		0,		0 }, // unassigned
-	{21, "",		0,		0 }, // unassigned
-	{22, "",		0,		0 }, // unassigned
-	{23, "",		0,		0 }, // unassigned
-	//{ 0, "G726-40", 8000,	1 },
-	//{ 0, "G726-32", 8000,	1 },
-	//{ 0, "G726-24", 8000,	1 },
-	//{ 0, "G726-16", 8000,	1 },
-	//{ 0, "G729-D",  8000,	1 },
-	//{ 0, "G729-E",  8000,	1 },
-	//{ 0, "GSM-EFR", 8000,	1 },
-	//{ 0, "L8",      var,	1 },
+	{0, RTP_AVTYPE_AUDIO, "PCMU", 8000, 1}, // G711 mu-law
+	{1, RTP_AVTYPE_UNKNOWN, "", 0, 0},      // reserved
+	{2, RTP_AVTYPE_UNKNOWN, "", 0, 0},      // reserved
+	{3, RTP_AVTYPE_AUDIO, "GSM", 8000, 1},
+	{4, RTP_AVTYPE_AUDIO, "G723", 8000, 1},
+	{5, RTP_AVTYPE_AUDIO, "DVI4", 8000, 1},
+	{6, RTP_AVTYPE_AUDIO, "DVI4", 16000, 1},
+	{7, RTP_AVTYPE_AUDIO, "LPC", 8000, 1},
+	{8, RTP_AVTYPE_AUDIO, "PCMA", 8000, 1}, // G711 A-law
+	{9, RTP_AVTYPE_AUDIO, "G722", 8000, 1},
+	{10, RTP_AVTYPE_AUDIO, "L16", 44100, 2},
+	{11, RTP_AVTYPE_AUDIO, "L16", 44100, 1},
+	{12, RTP_AVTYPE_AUDIO, "QCELP", 8000, 1},
+	{13, RTP_AVTYPE_AUDIO, "CN", 8000, 1},
+	{14, RTP_AVTYPE_AUDIO, "MPA", 90000, 0}, // MPEG-1/MPEG-2 audio
+	{15, RTP_AVTYPE_AUDIO, "G728", 8000, 1},
+	{16, RTP_AVTYPE_AUDIO, "DVI4", 11025, 1},
+	{17, RTP_AVTYPE_AUDIO, "DVI4", 22050, 1},
+	{18, RTP_AVTYPE_AUDIO, "G729", 8000, 1},
+	{19, RTP_AVTYPE_UNKNOWN, "", 0, 0}, // reserved
+	{20, RTP_AVTYPE_UNKNOWN, "", 0, 0}, // unassigned
+	{21, RTP_AVTYPE_UNKNOWN, "", 0, 0}, // unassigned
+	{22, RTP_AVTYPE_UNKNOWN, "", 0, 0}, // unassigned
+	{23, RTP_AVTYPE_UNKNOWN, "", 0, 0}, // unassigned
 
 	// video
-	{24, "",		0,		0 }, // unassigned
-	{25, "CelB",	90000,	0 }, // SUN CELL-B
-	{26, "JPEG",	90000,	0 },
-	{27, "",		0,		0 }, // unassigned
-	{28, "nv",		90000,	0 },
-	{29, "",		0,		0 }, // unassigned
-	{30, "",		0,		0 }, // unassigned
-	{31, "H261",	90000,	0 },
-	{32, "MPV",		90000,	0 }, // MPEG-1/MPEG-2 video
-	{33, "MP2T",	90000,	0 }, // MPEG-2 TS
-	{34, "H263",	90000,	0 },
-	//{ 0, "H263-1998",90000,	0 },
-
+	{24, RTP_AVTYPE_UNKNOWN, "", 0, 0},       // unassigned
+	{25, RTP_AVTYPE_VIDEO, "CelB", 90000, 0}, // SUN CELL-B
+	{26, RTP_AVTYPE_VIDEO, "JPEG", 90000, 0},
+	{27, RTP_AVTYPE_UNKNOWN, "", 0, 0}, // unassigned
+	{28, RTP_AVTYPE_VIDEO, "nv", 90000, 0},
+	{29, RTP_AVTYPE_UNKNOWN, "", 0, 0}, // unassigned
+	{30, RTP_AVTYPE_UNKNOWN, "", 0, 0}, // unassigned
+	{31, RTP_AVTYPE_VIDEO, "H261", 90000, 0},
+	{32, RTP_AVTYPE_VIDEO, "MPV", 90000, 0},   // MPEG-1/MPEG-2 video
+	{33, RTP_AVTYPE_SYSTEM, "MP2T", 90000, 0}, // MPEG-2 TS
+	{34, RTP_AVTYPE_VIDEO, "H263", 90000, 0},
 	// 35-71 unassigned
 	// 72-76 reserved
 	// 77-95 unassigned
 	// 96-127 dynamic
-	{96, "MPG4",	90000,  0 }, // RFC3640 RTP Payload Format for Transport of MPEG-4 Elementary Streams
-	{97, "MP2P",	90000,  0 }, // RFC3555 4.2.11 Registration of MIME media type video/MP2P
-	{98, "H264",	90000,	0 }, // RFC6184 RTP Payload Format for H.264 Video
-};
-***/
+}
+
+// well-known encodings use dynamic payload type, with the default payload type of this library,
+// frequency 0 if the clock rate is given by signaling(e.g. AAC sampling rate)
+var rtpDynamicProfiles = [...]struct {
+	payload   int
+	avtype    int
+	name      string
+	frequency int
+	channels  int
+}{
+	{RTP_PAYLOAD_MP4V, RTP_AVTYPE_VIDEO, "MP4V-ES", 90000, 0},    // RFC6416
+	{RTP_PAYLOAD_H264, RTP_AVTYPE_VIDEO, "H264", 90000, 0},       // RFC6184
+	{RTP_PAYLOAD_H265, RTP_AVTYPE_VIDEO, "H265", 90000, 0},       // RFC7798
+	{RTP_PAYLOAD_MP2P, RTP_AVTYPE_SYSTEM, "MP2P", 90000, 0},      // RFC3555
+	{RTP_PAYLOAD_MP4A, RTP_AVTYPE_AUDIO, "MP4A-LATM", 0, 0},      // RFC6416, clock rate from rtpmap/StreamMuxConfig
+	{RTP_PAYLOAD_OPUS, RTP_AVTYPE_AUDIO, "opus", 48000, 2},       // RFC7587
+	{RTP_PAYLOAD_MP4ES, RTP_AVTYPE_AUDIO, "mpeg4-generic", 0, 0}, // RFC3640, clock rate from rtpmap/AudioSpecificConfig
+	{-1, RTP_AVTYPE_VIDEO, "HEVC", 90000, 0},
+	{-1, RTP_AVTYPE_AUDIO, "AAC", 0, 0}, // clock rate from rtpmap/AudioSpecificConfig
+	{-1, RTP_AVTYPE_AUDIO, "G726-16", 8000, 1},
+	{-1, RTP_AVTYPE_AUDIO, "G726-24", 8000, 1},
+	{-1, RTP_AVTYPE_AUDIO, "G726-32", 8000, 1},
+	{-1, RTP_AVTYPE_AUDIO, "G726-40", 8000, 1},
+	{-1, RTP_AVTYPE_AUDIO, "G7221", 16000, 1}, // RFC5577
+}
 
 const (
 	RTP_PAYLOAD_PCMU = 0  // ITU-T G.711 PCM µ-Law audio 64 kbit/s (rfc3551)
@@ -95,3 +125,127 @@ const (
 	RTP_PAYLOAD_OPUS  = 101 // RTP Payload Format for the Opus Speech and Audio Codec (rfc7587)
 	RTP_PAYLOAD_MP4ES = 102 // MPEG4-generic audio/video MPEG-4 Elementary Streams (rfc3640)
 )
+
+func newRtpProfile(payload, avtype int, name string, frequency, channels int) *RtpProfile {
+	p := &RtpProfile{Payload: payload, Avtype: avtype, Channels: channels, Frequency: frequency}
+	copy(p.Name[:len(p.Name)-1], name)
+	return p
+}
+
+// EncodingName return encoding name as string
+func (p *RtpProfile) EncodingName() string {
+	n := 0
+	for n < len(p.Name) && p.Name[n] != 0 {
+		n++
+	}
+	return string(p.Name[:n])
+}
+
+// Timestamp convert duration to RTP timestamp units of the clock rate
+func (p *RtpProfile) Timestamp(d time.Duration) uint32 {
+	return RtpDurationToTimestamp(d, p.Frequency)
+}
+
+// RtpDurationToTimestamp convert duration to RTP timestamp units, wrap around 32 bits
+func RtpDurationToTimestamp(d time.Duration, frequency int) uint32 {
+	return uint32(int64(d/time.Second)*int64(frequency) + int64(d%time.Second)*int64(frequency)/int64(time.Second))
+}
+
+// RtpProfileFind find static payload type (RFC3551), nil if unassigned or dynamic
+func RtpProfileFind(payload int) *RtpProfile {
+	if payload < 0 || payload >= len(rtpStaticProfiles) || rtpStaticProfiles[payload].frequency == 0 {
+		return nil
+	}
+	v := &rtpStaticProfiles[payload]
+	return newRtpProfile(v.payload, v.avtype, v.name, v.frequency, v.channels)
+}
+
+// RtpProfileFindByName find profile by encoding name (case insensitive),
+// static payload types first, then well-known dynamic encodings.
+// Payload is -1 if the encoding has no default payload type.
+func RtpProfileFindByName(name string) *RtpProfile {
+	for i := range rtpStaticProfiles {
+		v := &rtpStaticProfiles[i]
+		if v.frequency > 0 && strings.EqualFold(v.name, name) {
+			return newRtpProfile(v.payload, v.avtype, v.name, v.frequency, v.channels)
+		}
+	}
+	for i := range rtpDynamicProfiles {
+		v := &rtpDynamicProfiles[i]
+		if strings.EqualFold(v.name, name) {
+			return newRtpProfile(v.payload, v.avtype, v.name, v.frequency, v.channels)
+		}
+	}
+	return nil
+}
+
+// RtpProfileTable is the payload type mapping of a session:
+// static payload types plus dynamic 96~127 registered from signaling(e.g. SDP rtpmap)
+type RtpProfileTable struct {
+	dynamic map[int]*RtpProfile
+}
+
+func NewRtpProfileTable() *RtpProfileTable {
+	return &RtpProfileTable{dynamic: make(map[int]*RtpProfile)}
+}
+
+// Register map dynamic payload type to encoding
+// @param[in] payload 96~127
+// @param[in] name encoding name, e.g. H264
+// @param[in] frequency clock rate, 0-default clock rate of well-known encoding, required for AAC
+// @param[in] channels number of channels, 0-default
+func (t *RtpProfileTable) Register(payload int, name string, frequency, channels int) error {
+	if payload < 96 || payload > 127 {
+		return errors.New("rtp dynamic payload type must be 96~127.")
+	}
+	if len(name) == 0 || len(name) >= len(RtpProfile{}.Name) {
+		return errors.New("rtp encoding name error.")
+	}
+
+	avtype := RTP_AVTYPE_UNKNOWN
+	if known := RtpProfileFindByName(name); known != nil {
+		avtype = known.Avtype
+		if frequency == 0 {
+			frequency = known.Frequency
+		}
+		if channels == 0 {
+			channels = known.Channels
+		}
+	}
+	if frequency <= 0 {
+		return errors.New("rtp clock rate error.")
+	}
+
+	t.dynamic[payload] = newRtpProfile(payload, avtype, name, frequency, channels)
+	return nil
+}
+
+func (t *RtpProfileTable) Unregister(payload int) {
+	delete(t.dynamic, payload)
+}
+
+// Find find profile by payload type, nil if not found
+func (t *RtpProfileTable) Find(payload int) *RtpProfile {
+	if p, ok := t.dynamic[payload]; ok {
+		v := *p
+		return &v
+	}
+	return RtpProfileFind(payload)
+}
+
+// FindByName find payload type by encoding name (case insensitive),
+// registered dynamic payload types first
+func (t *RtpProfileTable) FindByName(name string) *RtpProfile {
+	for payload := 96; payload <= 127; payload++ {
+		if p, ok := t.dynamic[payload]; ok && strings.EqualFold(p.EncodingName(), name) {
+			v := *p
+			return &v
+		}
+	}
+
+	p := RtpProfileFindByName(name)
+	if p != nil && p.Payload >= 96 {
+		return nil // not registered in this session
+	}
+	return p
+}
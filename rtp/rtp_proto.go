@@ -1,5 +1,6 @@
 package rtp
 
+// Deprecated: video only, use RtpProfile.Frequency/RtpDurationToTimestamp
 const KHZ = 90 // 90000Hz
 
 const (
@@ -49,6 +50,7 @@ type RtpPacket struct {
 	Reserved   uint16 // extension reserved
 	Payload    []byte // payload
 	PayloadLen int    //payload length in bytes
+	Padlen     int    // padding length in bytes, include the last count byte(valid only if rtp.p = 1)
 }
 
 func RTP_V(v uint32) byte {
@@ -0,0 +1,151 @@
+package rtp
+
+// RFC3550 A.1 RTP Data Header Validity Checks (p78)
+const (
+	RtpSeqMod      = 1 << 16
+	RtpMaxDropout  = 3000 // the largest forward jump treat as loss
+	RtpMaxMisorder = 100  // the largest backward jump treat as late packet
+)
+
+// RtpSource.Update result
+const (
+	RTP_SEQ_PROBATION = 0 // source not valid yet, packet should be discarded
+	RTP_SEQ_OK        = 1 // in order
+	RTP_SEQ_LOST      = 2 // in order, but some packets before it are missing
+	RTP_SEQ_LATE      = 3 // duplicate or reordered packet
+	RTP_SEQ_BAD       = 4 // very large jump, packet should be discarded
+	RTP_SEQ_RESTART   = 5 // source restarted(two sequential bad packets), sequence resynced
+)
+
+// RtpSource keep per-source sequence number state
+type RtpSource struct {
+	maxSeq    uint16 // highest seq. number seen
+	cycles    uint32 // shifted count of seq. number cycles
+	baseSeq   uint32 // base seq number
+	badSeq    uint32 // last 'bad' seq number + 1
+	probation int    // sequ. packets till source is valid
+	received  uint32 // packets received
+	started   bool   // Start called, maxSeq is seq - 1 until the first in-order packet
+}
+
+func (s *RtpSource) initSeq(seq uint16) {
+	s.baseSeq = uint32(seq)
+	s.maxSeq = seq
+	s.badSeq = RtpSeqMod + 1 // so seq == bad_seq is false
+	s.cycles = 0
+	s.received = 0
+	s.started = false
+}
+
+// Start reset source with the first packet seq
+// @param[in] seq sequence number of the first packet, pass it to Update later
+// @param[in] probation sequential packets required before valid, 0 if source known valid
+func (s *RtpSource) Start(seq uint16, probation int) {
+	s.initSeq(seq)
+	s.maxSeq = seq - 1
+	s.probation = probation
+	s.started = true
+}
+
+// Resync accept seq as the restart of the source, e.g. re-synced by a jitter buffer,
+// the next Update(seq) after a very large jump return RTP_SEQ_RESTART instead of RTP_SEQ_BAD
+func (s *RtpSource) Resync(seq uint16) {
+	s.badSeq = uint32(seq)
+}
+
+// Update check a received sequence number
+// @return RTP_SEQ_XXX
+func (s *RtpSource) Update(seq uint16) int {
+	udelta := seq - s.maxSeq
+
+	// Source is not valid until MIN_SEQUENTIAL packets with
+	// sequential sequence numbers have been received.
+	if s.probation > 0 {
+		// packet is in sequence
+		if seq == s.maxSeq+1 {
+			s.probation--
+			s.maxSeq = seq
+			if s.probation == 0 {
+				s.initSeq(seq)
+				s.received++
+				return RTP_SEQ_OK
+			}
+		} else {
+			s.probation = RtpMinSequential - 1
+			s.maxSeq = seq
+		}
+		return RTP_SEQ_PROBATION
+	} else if udelta < RtpMaxDropout {
+		if udelta == 0 {
+			// duplicate
+			s.received++
+			return RTP_SEQ_LATE
+		}
+
+		// in order, with permissible gap
+		if seq < s.maxSeq && !s.started {
+			// Sequence number wrapped - count another 64K cycle.
+			// The first packet after Start(0) is not a wrap.
+			s.cycles += RtpSeqMod
+		}
+		s.started = false
+		s.maxSeq = seq
+		s.received++
+		if udelta > 1 {
+			return RTP_SEQ_LOST
+		}
+		return RTP_SEQ_OK
+	} else if udelta <= RtpSeqMod-RtpMaxMisorder {
+		// the sequence number made a very large jump
+		if uint32(seq) == s.badSeq {
+			// Two sequential packets -- assume that the other side
+			// restarted without telling us so just re-sync
+			// (i.e., pretend this was the first packet).
+			s.initSeq(seq)
+			s.received++
+			return RTP_SEQ_RESTART
+		}
+		s.badSeq = (uint32(seq) + 1) & (RtpSeqMod - 1)
+		return RTP_SEQ_BAD
+	}
+
+	// duplicate or reordered packet
+	s.received++
+	return RTP_SEQ_LATE
+}
+
+// Valid return true if source passed probation
+func (s *RtpSource) Valid() bool {
+	return s.probation == 0
+}
+
+// MaxSeq return the highest sequence number seen
+func (s *RtpSource) MaxSeq() uint16 {
+	return s.maxSeq
+}
+
+// ExtendedMax return extended highest sequence number received(cycles + max seq)
+func (s *RtpSource) ExtendedMax() uint32 {
+	return s.cycles + uint32(s.maxSeq)
+}
+
+// Expected return the number of packets expected (RFC3550 A.3)
+func (s *RtpSource) Expected() uint32 {
+	return s.ExtendedMax() - s.baseSeq + 1
+}
+
+// Received return the number of packets received, include duplicate
+func (s *RtpSource) Received() uint32 {
+	return s.received
+}
+
+// Lost return the cumulative number of packets lost, clamp to 24 bits signed (RFC3550 A.3)
+func (s *RtpSource) Lost() int32 {
+	lost := int64(s.Expected()) - int64(s.received)
+	if lost > 0x7FFFFF {
+		lost = 0x7FFFFF
+	} else if lost < -0x800000 {
+		lost = -0x800000
+	}
+	return int32(lost)
+}
@@ -0,0 +1,171 @@
+package rtp
+
+import (
+	"time"
+)
+
+// RtpStats keep receive statistics of one source (RFC3550 A.3 / A.8)
+type RtpStats struct {
+	SSRC      uint32
+	Frequency int       // payload clock rate
+	Source    RtpSource // sequence number state
+
+	jitter        uint32 // estimated jitter, scaled by 16
+	transit       uint32 // relative transit time for prev packet
+	started       bool   // first packet received
+	base          time.Time
+	expectedPrior uint32 // packet expected at last interval
+	receivedPrior uint32 // packet received at last interval
+	updated       bool   // packet received since last report
+
+	// last SR, for LSR/DLSR of the report block
+	srNtp  uint64
+	srTime time.Time
+}
+
+func NewRtpStats(ssrc uint32, frequency int) *RtpStats {
+	return &RtpStats{SSRC: ssrc, Frequency: frequency}
+}
+
+// Update account a received RTP packet
+// @param[in] pkt packet from RtpPacketDeserialize
+// @param[in] arrival packet arrival time
+// @return RTP_SEQ_XXX
+func (s *RtpStats) Update(pkt *RtpPacket, arrival time.Time) int {
+	if !s.started {
+		s.started = true
+		s.base = arrival
+		s.Source.Start(pkt.Header.SequenceNumber, RtpMinSequential)
+	}
+
+	r := s.Source.Update(pkt.Header.SequenceNumber)
+	if r == RTP_SEQ_PROBATION || r == RTP_SEQ_BAD {
+		return r
+	}
+	if r == RTP_SEQ_RESTART {
+		s.expectedPrior, s.receivedPrior = 0, 0
+	}
+	s.updated = true
+
+	// RFC3550 A.8 Estimating the Interarrival Jitter (p94)
+	// arrival time in timestamp units, only differences are used
+	ticks := RtpDurationToTimestamp(arrival.Sub(s.base), s.Frequency)
+	transit := ticks - pkt.Header.Timestamp
+	if s.Source.Received() > 1 && r != RTP_SEQ_RESTART {
+		d := int32(transit - s.transit)
+		if d < 0 {
+			d = -d
+		}
+		s.jitter += uint32(d) - ((s.jitter + 8) >> 4)
+	}
+	s.transit = transit
+	return r
+}
+
+// OnSR record sender report of this source
+func (s *RtpStats) OnSR(sr *RtcpSR, arrival time.Time) {
+	s.srNtp = uint64(sr.NTPMSW)<<32 | uint64(sr.NTPLSW)
+	s.srTime = arrival
+}
+
+// Jitter return interarrival jitter in timestamp units
+func (s *RtpStats) Jitter() uint32 {
+	return s.jitter >> 4
+}
+
+// Report make a report block and start a new report interval (RFC3550 A.3)
+func (s *RtpStats) Report(now time.Time) RtcpReport {
+	expected := s.Source.Expected()
+	received := s.Source.Received()
+	expectedInterval := expected - s.expectedPrior
+	receivedInterval := received - s.receivedPrior
+	s.expectedPrior = expected
+	s.receivedPrior = received
+	s.updated = false
+
+	var fraction byte
+	lostInterval := int64(expectedInterval) - int64(receivedInterval)
+	if expectedInterval != 0 && lostInterval > 0 {
+		fraction = byte((lostInterval << 8) / int64(expectedInterval))
+	}
+
+	report := RtcpReport{
+		SSRC:     s.SSRC,
+		Fraction: fraction,
+		Lost:     s.Source.Lost(),
+		ExtSeq:   s.Source.ExtendedMax(),
+		Jitter:   s.Jitter(),
+	}
+	if !s.srTime.IsZero() {
+		report.LSR = uint32(s.srNtp >> 16) // middle 32 bits out of 64 in the NTP timestamp
+		report.DLSR = uint32(now.Sub(s.srTime) * 65536 / time.Second)
+	}
+	return report
+}
+
+// RtpReceiver keep receive statistics of all sources of a session
+type RtpReceiver struct {
+	Frequency int // payload clock rate of new sources
+	sources   map[uint32]*RtpStats
+}
+
+func NewRtpReceiver(frequency int) *RtpReceiver {
+	return &RtpReceiver{Frequency: frequency, sources: make(map[uint32]*RtpStats)}
+}
+
+// Find return statistics of ssrc, nil if not found
+func (r *RtpReceiver) Find(ssrc uint32) *RtpStats {
+	return r.sources[ssrc]
+}
+
+// Remove forget source, e.g. BYE or timeout
+func (r *RtpReceiver) Remove(ssrc uint32) {
+	delete(r.sources, ssrc)
+}
+
+// Input account a received RTP packet
+// @return source statistics, RTP_SEQ_XXX
+func (r *RtpReceiver) Input(pkt *RtpPacket, arrival time.Time) (*RtpStats, int) {
+	s, ok := r.sources[pkt.Header.SSRC]
+	if !ok {
+		s = NewRtpStats(pkt.Header.SSRC, r.Frequency)
+		r.sources[pkt.Header.SSRC] = s
+	}
+	return s, s.Update(pkt, arrival)
+}
+
+// OnRtcp record sender reports from a RTCP compound packet
+func (r *RtpReceiver) OnRtcp(pkts []RtcpPacket, arrival time.Time) {
+	for _, pkt := range pkts {
+		switch v := pkt.(type) {
+		case *RtcpSR:
+			if s, ok := r.sources[v.SSRC]; ok {
+				s.OnSR(v, arrival)
+			}
+		case *RtcpBye:
+			for _, ssrc := range v.SSRC {
+				r.Remove(ssrc)
+			}
+		}
+	}
+}
+
+// Reports make report blocks for sources heard since last report
+// @param[in] now report time
+// @param[in] max maximum report blocks, RtcpMaxReportSize at most
+func (r *RtpReceiver) Reports(now time.Time, max int) []RtcpReport {
+	if max > RtcpMaxReportSize {
+		max = RtcpMaxReportSize
+	}
+
+	var reports []RtcpReport
+	for _, s := range r.sources {
+		if len(reports) >= max {
+			break
+		}
+		if s.updated && s.Source.Valid() {
+			reports = append(reports, s.Report(now))
+		}
+	}
+	return reports
+}
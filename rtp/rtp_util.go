@@ -30,16 +30,18 @@ func WriteRtpHeader(ptr []byte, h *RtpHeader) {
 	RtpWriteUint32(ptr[8:], h.SSRC)
 }
 
-//func WriteRtcpHeader(ptr []byte, h *RtcpHeader) {
-////
-////}
+func WriteRtcpHeader(ptr []byte, h *RtcpHeader) {
+	ptr[0] = (h.Version << 6) | (h.Padding << 5) | (h.RC & 0x1F)
+	ptr[1] = h.PT
+	ptr[2] = byte(h.Length >> 8)
+	ptr[3] = byte(h.Length & 0xFF)
+}
 
-/*
-static inline void nbo_write_rtcp_header(uint8_t *ptr, const rtcp_header_t *header)
-{
-	ptr[0] = (uint8_t)((header->v << 6) | (header->p << 5) | header->rc);
-	ptr[1] = (uint8_t)(header->pt);
-	ptr[2] = (uint8_t)(header->length >> 8);
-	ptr[3] = (uint8_t)(header->length & 0xFF);
+func ReadRtcpHeader(ptr []byte, h *RtcpHeader) {
+	v := RtpReadUint32(ptr)
+	h.Version = RTCP_V(v)
+	h.Padding = RTCP_P(v)
+	h.RC = RTCP_RC(v)
+	h.PT = RTCP_PT(v)
+	h.Length = RTCP_LEN(v)
 }
-*/
@@ -0,0 +1,153 @@
+package rtp
+
+import (
+	"errors"
+)
+
+// RtpPacketView is a read-only view over a RTP packet buffer,
+// parse header fields in place without copy or allocation.
+// The view is valid as long as the underlying buffer isn't modified.
+type RtpPacketView struct {
+	data    []byte // whole packet, padding included
+	ext     int    // extension data offset, 0 if no extension
+	extlen  int    // extension data length in bytes
+	payload int    // payload offset
+	end     int    // payload end, padding excluded
+}
+
+// RtpPacketViewParse check and parse packet, same rules as RtpPacketDeserialize
+func RtpPacketViewParse(v *RtpPacketView, data []byte, bytes int) error {
+	// RFC3550 5.1 RTP Fixed Header Fields(p12)
+	if bytes < RtpFixedHeader || bytes > len(data) {
+		return errors.New("rtp header need 12 bytes.")
+	}
+
+	ptr := data[:bytes]
+	h := RtpReadUint32(ptr)
+	if RTP_V(h) != RtpVersion {
+		return errors.New("rtp version error.")
+	}
+
+	headerlen := RtpFixedHeader + int(RTP_CC(h))*4
+	var ext int
+	if RTP_X(h) > 0 {
+		ext += 4
+	}
+	if RTP_P(h) > 0 {
+		ext += 1
+	}
+	if bytes < headerlen+ext {
+		return errors.New("no enough bytes.")
+	}
+
+	v.data = ptr
+	v.ext = 0
+	v.extlen = 0
+	v.payload = headerlen
+	v.end = bytes
+
+	// pkt header extension
+	if RTP_X(h) > 0 {
+		extlen := int(RtpReadUint16(ptr[headerlen+2:])) * 4
+		if headerlen+4+extlen > bytes {
+			return errors.New("playload len error2.")
+		}
+		v.ext = headerlen + 4
+		v.extlen = extlen
+		v.payload = v.ext + extlen
+	}
+
+	// padding
+	if RTP_P(h) > 0 {
+		padding := int(ptr[bytes-1])
+		if v.end-v.payload < padding {
+			return errors.New("payload len error3.")
+		}
+		v.end -= padding
+	}
+	return nil
+}
+
+func (v *RtpPacketView) Version() byte {
+	return v.data[0] >> RtpHeader_VersionShift
+}
+
+func (v *RtpPacketView) Padding() byte {
+	return (v.data[0] >> RtpHeader_PaddingShift) & RtpHeader_PaddingMask
+}
+
+func (v *RtpPacketView) Extension() byte {
+	return (v.data[0] >> RtpHeader_ExtensionShift) & RtpHeader_ExtensionMask
+}
+
+// CC return CSRC count
+func (v *RtpPacketView) CC() byte {
+	return v.data[0] & RtpHeader_CCMask
+}
+
+func (v *RtpPacketView) Marker() byte {
+	return v.data[1] >> RtpHeader_MarkerShift
+}
+
+func (v *RtpPacketView) PayloadType() byte {
+	return v.data[1] & RtpHeader_PtMask
+}
+
+func (v *RtpPacketView) SequenceNumber() uint16 {
+	return RtpReadUint16(v.data[RtpHeader_SeqNumOffset:])
+}
+
+func (v *RtpPacketView) Timestamp() uint32 {
+	return RtpReadUint32(v.data[RtpHeader_TimestampOffset:])
+}
+
+func (v *RtpPacketView) SSRC() uint32 {
+	return RtpReadUint32(v.data[RtpHeader_SsrcOffset:])
+}
+
+// CSRC return the i-th contributing source, 0 <= i < CC()
+func (v *RtpPacketView) CSRC(i int) uint32 {
+	return RtpReadUint32(v.data[RtpHeader_CsrcOffset+i*RtpHeader_CsrcLength:])
+}
+
+// Header copy header fields
+func (v *RtpPacketView) Header() RtpHeader {
+	h := RtpReadUint32(v.data)
+	return RtpHeader{
+		Version:        RTP_V(h),
+		Padding:        RTP_P(h),
+		Extension:      RTP_X(h),
+		CSRC:           RTP_CC(h),
+		Marker:         RTP_M(h),
+		PayloadType:    RTP_PT(h),
+		SequenceNumber: RTP_SEQ(h),
+		Timestamp:      v.Timestamp(),
+		SSRC:           v.SSRC(),
+	}
+}
+
+// ExtensionProfile return "defined by profile" field, 0 if no extension
+func (v *RtpPacketView) ExtensionProfile() uint16 {
+	if v.ext == 0 {
+		return 0
+	}
+	return RtpReadUint16(v.data[v.ext-4:])
+}
+
+// ExtensionData return header extension data without the 4-byte extension header
+func (v *RtpPacketView) ExtensionData() []byte {
+	if v.ext == 0 {
+		return nil
+	}
+	return v.data[v.ext : v.ext+v.extlen]
+}
+
+// Payload return payload, padding excluded
+func (v *RtpPacketView) Payload() []byte {
+	return v.data[v.payload:v.end]
+}
+
+// Bytes return the whole packet
+func (v *RtpPacketView) Bytes() []byte {
+	return v.data
+}
@@ -0,0 +1,457 @@
+// RFC4566 SDP: Session Description Protocol
+// 5.14. Media Descriptions ("m=") (p22)
+//   m=<media> <port>/<number of ports> <proto> <fmt> ...
+// 6. SDP Attributes (p25)
+//   a=rtpmap:<payload type> <encoding name>/<clock rate> [/<encoding parameters>]
+//   a=fmtp:<format> <format specific parameters>
+//   a=ptime:<packet time>
+//   a=maxptime:<maximum packet time>
+// RFC8285 5. SDP Signaling Design (p14)
+//   a=extmap:<value>["/"<direction>] <URI> <extensionattributes>
+// RFC5576 4.1. The "ssrc" Media Attribute (p5)
+//   a=ssrc:<ssrc-id> <attribute>
+//   a=ssrc:<ssrc-id> <attribute>:<value>
+
+package sdp
+
+import (
+	"encoding/hex"
+	"errors"
+	"github.com/services-go/librtp/rtp"
+	"strconv"
+	"strings"
+)
+
+type SdpRtpmap struct {
+	Payload   int
+	Encoding  string
+	Frequency int
+	Channels  int // encoding parameters, 0 if not present
+}
+
+type SdpParam struct {
+	Name  string
+	Value string
+}
+
+type SdpFmtp struct {
+	Payload int
+	Params  []SdpParam // in order of appearance
+	Raw     string     // format specific parameters as is
+}
+
+type SdpExtmap struct {
+	ID         int
+	Direction  string // sendonly/recvonly/sendrecv/inactive, empty if not present
+	URI        string
+	Attributes string
+}
+
+type SdpSsrc struct {
+	SSRC      uint32
+	Attribute string // e.g. cname, msid
+	Value     string
+}
+
+type SdpAttribute struct {
+	Name  string
+	Value string
+}
+
+type SdpMedia struct {
+	Media    string // audio/video/application
+	Port     int
+	NumPorts int // 0 if not present
+	Proto    string
+	Formats  []int
+
+	Rtpmaps  []SdpRtpmap
+	Fmtps    []SdpFmtp
+	Ptime    int // ms, 0 if not present
+	MaxPtime int // ms, 0 if not present
+	Extmaps  []SdpExtmap
+	Ssrcs    []SdpSsrc
+
+	Attributes []SdpAttribute // other attributes
+}
+
+// SdpParse parse media descriptions of a session description,
+// session level extmap apply to every media
+// @param[in] text session description, CRLF or LF line ending
+// @return media descriptions in order of m= lines
+func SdpParse(text string) ([]*SdpMedia, error) {
+	var medias []*SdpMedia
+	var extmaps []SdpExtmap // session level
+	var m *SdpMedia
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if len(line) == 0 {
+			continue
+		}
+		if len(line) < 2 || line[1] != '=' {
+			return nil, errors.New("sdp line error: " + line)
+		}
+
+		v := line[2:]
+		switch line[0] {
+		case 'm':
+			media, err := sdpParseMedia(v)
+			if err != nil {
+				return nil, err
+			}
+			media.Extmaps = append(media.Extmaps, extmaps...)
+			medias = append(medias, media)
+			m = media
+		case 'a':
+			if m == nil {
+				if strings.HasPrefix(v, "extmap:") {
+					extmap, err := sdpParseExtmap(v[len("extmap:"):])
+					if err != nil {
+						return nil, err
+					}
+					extmaps = append(extmaps, extmap)
+				}
+				continue // other session level attributes
+			}
+			if err := m.parseAttribute(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return medias, nil
+}
+
+// m=<media> <port>/<number of ports> <proto> <fmt> ...
+func sdpParseMedia(v string) (*SdpMedia, error) {
+	fields := strings.Fields(v)
+	if len(fields) < 3 {
+		return nil, errors.New("sdp media line error: " + v)
+	}
+
+	var err error
+	m := &SdpMedia{Media: fields[0], Proto: fields[2]}
+	port := strings.SplitN(fields[1], "/", 2)
+	if m.Port, err = strconv.Atoi(port[0]); err != nil {
+		return nil, errors.New("sdp media port error: " + v)
+	}
+	if len(port) > 1 {
+		if m.NumPorts, err = strconv.Atoi(port[1]); err != nil {
+			return nil, errors.New("sdp media port error: " + v)
+		}
+	}
+
+	if !strings.Contains(m.Proto, "RTP") {
+		return m, nil // format isn't payload type, e.g. application/sctp
+	}
+	for _, f := range fields[3:] {
+		pt, err := strconv.Atoi(f)
+		if err != nil || pt < 0 || pt > 127 {
+			return nil, errors.New("sdp media format error: " + v)
+		}
+		m.Formats = append(m.Formats, pt)
+	}
+	return m, nil
+}
+
+func (m *SdpMedia) parseAttribute(v string) error {
+	name, value := v, ""
+	if i := strings.IndexByte(v, ':'); i >= 0 {
+		name, value = v[:i], v[i+1:]
+	}
+
+	var err error
+	switch name {
+	case "rtpmap":
+		var rtpmap SdpRtpmap
+		if rtpmap, err = sdpParseRtpmap(value); err == nil {
+			m.Rtpmaps = append(m.Rtpmaps, rtpmap)
+		}
+	case "fmtp":
+		var fmtp SdpFmtp
+		if fmtp, err = sdpParseFmtp(value); err == nil {
+			m.Fmtps = append(m.Fmtps, fmtp)
+		}
+	case "ptime":
+		if m.Ptime, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+			err = errors.New("sdp ptime error: " + value)
+		}
+	case "maxptime":
+		if m.MaxPtime, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+			err = errors.New("sdp maxptime error: " + value)
+		}
+	case "extmap":
+		var extmap SdpExtmap
+		if extmap, err = sdpParseExtmap(value); err == nil {
+			m.Extmaps = append(m.Extmaps, extmap)
+		}
+	case "ssrc":
+		var ssrc SdpSsrc
+		if ssrc, err = sdpParseSsrc(value); err == nil {
+			m.Ssrcs = append(m.Ssrcs, ssrc)
+		}
+	default:
+		m.Attributes = append(m.Attributes, SdpAttribute{Name: name, Value: value})
+	}
+	return err
+}
+
+// <payload type> <encoding name>/<clock rate> [/<encoding parameters>]
+func sdpParseRtpmap(v string) (SdpRtpmap, error) {
+	var r SdpRtpmap
+	fields := strings.Fields(v)
+	if len(fields) != 2 {
+		return r, errors.New("sdp rtpmap error: " + v)
+	}
+
+	var err error
+	if r.Payload, err = strconv.Atoi(fields[0]); err != nil || r.Payload < 0 || r.Payload > 127 {
+		return r, errors.New("sdp rtpmap payload error: " + v)
+	}
+	encoding := strings.Split(fields[1], "/")
+	if len(encoding) < 2 || len(encoding) > 3 || len(encoding[0]) == 0 {
+		return r, errors.New("sdp rtpmap encoding error: " + v)
+	}
+	r.Encoding = encoding[0]
+	if r.Frequency, err = strconv.Atoi(encoding[1]); err != nil || r.Frequency <= 0 {
+		return r, errors.New("sdp rtpmap clock rate error: " + v)
+	}
+	if len(encoding) > 2 {
+		if r.Channels, err = strconv.Atoi(encoding[2]); err != nil {
+			return r, errors.New("sdp rtpmap encoding parameters error: " + v)
+		}
+	}
+	return r, nil
+}
+
+// <format> <format specific parameters>
+// parameters are "name=value" separated by ";"
+func sdpParseFmtp(v string) (SdpFmtp, error) {
+	var f SdpFmtp
+	v = strings.TrimSpace(v)
+	i := strings.IndexAny(v, " \t")
+	if i < 0 {
+		i = len(v)
+	}
+
+	var err error
+	if f.Payload, err = strconv.Atoi(v[:i]); err != nil || f.Payload < 0 || f.Payload > 127 {
+		return f, errors.New("sdp fmtp payload error: " + v)
+	}
+	f.Raw = strings.TrimSpace(v[i:])
+	for _, param := range strings.Split(f.Raw, ";") {
+		param = strings.TrimSpace(param)
+		if len(param) == 0 {
+			continue
+		}
+		name, value := param, ""
+		if j := strings.IndexByte(param, '='); j >= 0 {
+			name, value = strings.TrimSpace(param[:j]), strings.TrimSpace(param[j+1:])
+		}
+		f.Params = append(f.Params, SdpParam{Name: name, Value: value})
+	}
+	return f, nil
+}
+
+// <value>["/"<direction>] <URI> <extensionattributes>
+func sdpParseExtmap(v string) (SdpExtmap, error) {
+	var e SdpExtmap
+	fields := strings.Fields(v)
+	if len(fields) < 2 {
+		return e, errors.New("sdp extmap error: " + v)
+	}
+
+	var err error
+	id := strings.SplitN(fields[0], "/", 2)
+	if e.ID, err = strconv.Atoi(id[0]); err != nil || e.ID < 1 || e.ID > 255 {
+		return e, errors.New("sdp extmap id error: " + v)
+	}
+	if len(id) > 1 {
+		e.Direction = id[1]
+	}
+	e.URI = fields[1]
+	e.Attributes = strings.Join(fields[2:], " ")
+	return e, nil
+}
+
+// <ssrc-id> <attribute>[:<value>]
+func sdpParseSsrc(v string) (SdpSsrc, error) {
+	var s SdpSsrc
+	v = strings.TrimSpace(v)
+	i := strings.IndexByte(v, ' ')
+	if i < 0 {
+		i = len(v)
+	}
+
+	ssrc, err := strconv.ParseUint(v[:i], 10, 32)
+	if err != nil {
+		return s, errors.New("sdp ssrc error: " + v)
+	}
+	s.SSRC = uint32(ssrc)
+	s.Attribute = strings.TrimSpace(v[i:])
+	if j := strings.IndexByte(s.Attribute, ':'); j >= 0 {
+		s.Attribute, s.Value = s.Attribute[:j], s.Attribute[j+1:]
+	}
+	return s, nil
+}
+
+// Rtpmap return rtpmap of payload type, nil if not present
+func (m *SdpMedia) Rtpmap(payload int) *SdpRtpmap {
+	for i := range m.Rtpmaps {
+		if m.Rtpmaps[i].Payload == payload {
+			return &m.Rtpmaps[i]
+		}
+	}
+	return nil
+}
+
+// Fmtp return fmtp of payload type, nil if not present
+func (m *SdpMedia) Fmtp(payload int) *SdpFmtp {
+	for i := range m.Fmtps {
+		if m.Fmtps[i].Payload == payload {
+			return &m.Fmtps[i]
+		}
+	}
+	return nil
+}
+
+// Attribute return value of the first attribute with name
+func (m *SdpMedia) Attribute(name string) (string, bool) {
+	for _, a := range m.Attributes {
+		if a.Name == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// Profiles register dynamic payload types of the rtpmap lines,
+// static payload types use the RFC3551 table
+func (m *SdpMedia) Profiles() (*rtp.RtpProfileTable, error) {
+	profiles := rtp.NewRtpProfileTable()
+	for _, r := range m.Rtpmaps {
+		if r.Payload < 96 {
+			continue
+		}
+		if err := profiles.Register(r.Payload, r.Encoding, r.Frequency, r.Channels); err != nil {
+			return nil, err
+		}
+	}
+	return profiles, nil
+}
+
+// Get return parameter value, parameter name is case insensitive
+func (f *SdpFmtp) Get(name string) (string, bool) {
+	for _, p := range f.Params {
+		if strings.EqualFold(p.Name, name) {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// Int return decimal parameter value
+func (f *SdpFmtp) Int(name string) (int, bool, error) {
+	v, ok := f.Get(name)
+	if !ok {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, true, errors.New("sdp fmtp " + name + " error: " + v)
+	}
+	return n, true, nil
+}
+
+// Hex return hexadecimal octet string parameter value, e.g. mpeg4-generic config
+func (f *SdpFmtp) Hex(name string) ([]byte, bool, error) {
+	v, ok := f.Get(name)
+	if !ok {
+		return nil, false, nil
+	}
+	b, err := hex.DecodeString(v)
+	if err != nil {
+		return nil, true, errors.New("sdp fmtp " + name + " error: " + v)
+	}
+	return b, true, nil
+}
+
+// String format the media description, rtpmap/fmtp in order of formats
+func (m *SdpMedia) String() string {
+	var b strings.Builder
+	port := strconv.Itoa(m.Port)
+	if m.NumPorts > 0 {
+		port += "/" + strconv.Itoa(m.NumPorts)
+	}
+	b.WriteString("m=" + m.Media + " " + port + " " + m.Proto)
+	for _, pt := range m.Formats {
+		b.WriteString(" " + strconv.Itoa(pt))
+	}
+	b.WriteString("\r\n")
+
+	for _, pt := range m.Formats {
+		if r := m.Rtpmap(pt); r != nil {
+			b.WriteString("a=rtpmap:" + r.String() + "\r\n")
+		}
+		if f := m.Fmtp(pt); f != nil {
+			b.WriteString("a=fmtp:" + f.String() + "\r\n")
+		}
+	}
+	if m.Ptime > 0 {
+		b.WriteString("a=ptime:" + strconv.Itoa(m.Ptime) + "\r\n")
+	}
+	if m.MaxPtime > 0 {
+		b.WriteString("a=maxptime:" + strconv.Itoa(m.MaxPtime) + "\r\n")
+	}
+	for _, e := range m.Extmaps {
+		id := strconv.Itoa(e.ID)
+		if len(e.Direction) > 0 {
+			id += "/" + e.Direction
+		}
+		b.WriteString("a=extmap:" + id + " " + e.URI)
+		if len(e.Attributes) > 0 {
+			b.WriteString(" " + e.Attributes)
+		}
+		b.WriteString("\r\n")
+	}
+	for _, s := range m.Ssrcs {
+		b.WriteString("a=ssrc:" + strconv.FormatUint(uint64(s.SSRC), 10) + " " + s.Attribute)
+		if len(s.Value) > 0 {
+			b.WriteString(":" + s.Value)
+		}
+		b.WriteString("\r\n")
+	}
+	for _, a := range m.Attributes {
+		b.WriteString("a=" + a.Name)
+		if len(a.Value) > 0 {
+			b.WriteString(":" + a.Value)
+		}
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
+
+// String format rtpmap value, e.g. "97 opus/48000/2"
+func (r *SdpRtpmap) String() string {
+	s := strconv.Itoa(r.Payload) + " " + r.Encoding + "/" + strconv.Itoa(r.Frequency)
+	if r.Channels > 0 {
+		s += "/" + strconv.Itoa(r.Channels)
+	}
+	return s
+}
+
+// String format fmtp value, Raw is used if no parameters
+func (f *SdpFmtp) String() string {
+	if len(f.Params) == 0 {
+		return strconv.Itoa(f.Payload) + " " + f.Raw
+	}
+
+	params := make([]string, 0, len(f.Params))
+	for _, p := range f.Params {
+		if len(p.Value) > 0 {
+			params = append(params, p.Name+"="+p.Value)
+		} else {
+			params = append(params, p.Name)
+		}
+	}
+	return strconv.Itoa(f.Payload) + " " + strings.Join(params, ";")
+}
@@ -0,0 +1,123 @@
+package srtp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"errors"
+	"github.com/services-go/librtp/rtp"
+)
+
+// RFC3711 3.4. Secure RTCP (p15)
+/*
+     0                   1                   2                   3
+     0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+    +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+<+
+    |V=2|P|    RC   |   PT=SR or RR   |             length          | |
+    +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+    |                         SSRC of sender                        | |
+  +>+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+  | ~                          sender info                          ~ |
+  | +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+  | ~                         report block 1                        ~ |
+  | +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+  | ~                         report block 2                        ~ |
+  | +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+  | ~                              ...                              ~ |
+  | +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+  | |V=2|P|    SC   |  PT=SDES=202  |             length            | |
+  | +=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+ |
+  | |                          SSRC/CSRC_1                          | |
+  | +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+  | ~                           SDES items                          ~ |
+  | +=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+ |
+  | ~                              ...                              ~ |
+  +>+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+  | |E|                         SRTCP index                         | |
+  | +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+<+
+  | ~                     SRTCP MKI (OPTIONAL)                      ~ |
+  | +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+  | :                     authentication tag                        : |
+  | +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+  |                                                                   |
+  +-- Encrypted Portion                    Authenticated Portion -----+
+*/
+const (
+	SrtcpHeaderLength = 8          // RTCP header + SSRC of sender, never encrypted
+	SrtcpIndexLength  = 4          // E-flag + 31-bit SRTCP index
+	SrtcpEFlag        = 0x80000000 // E-flag, encrypted
+	SrtcpIndexMask    = 0x7FFFFFFF
+)
+
+// RtcpOverhead return bytes appended to RTCP compound packet by ProtectRtcp
+func (c *SrtpContext) RtcpOverhead() int {
+	return SrtcpIndexLength + c.profile.rtcpTagLength
+}
+
+// ProtectRtcp encrypt RTCP compound packet in place, append E-flag, SRTCP index and authentication tag
+// @param[in] data RTCP compound packet from RtcpCompoundSerialize, capacity bytes+RtcpOverhead() at least
+// @param[in] bytes RTCP compound packet length in bytes
+// @return SRTCP packet length in bytes
+func (c *SrtpContext) ProtectRtcp(data []byte, bytes int) (int, error) {
+	if bytes < SrtcpHeaderLength || bytes > len(data) {
+		return 0, errors.New("rtcp packet too short.")
+	}
+	if len(data) < bytes+c.RtcpOverhead() {
+		return 0, errors.New("srtp buffer too small.")
+	}
+
+	// The SRTCP index MUST be set to zero before the first SRTCP
+	// packet is sent, and MUST be incremented by one,
+	// modulo 2^31, after each SRTCP packet is sent.
+	ssrc := rtp.RtpReadUint32(data[4:])
+	s := c.stream(ssrc)
+	index := s.rtcpIndex
+	s.rtcpIndex = (s.rtcpIndex + 1) & SrtcpIndexMask
+
+	if c.rtcpGcm != nil {
+		return c.protectRtcpAead(data, bytes, ssrc, index), nil
+	}
+	if err := srtpAesCmXor(c.rtcpBlock, c.rtcpSalt, ssrc, uint64(index), data[SrtcpHeaderLength:bytes]); err != nil {
+		return 0, err
+	}
+	rtp.RtpWriteUint32(data[bytes:], SrtcpEFlag|index)
+	n := bytes + SrtcpIndexLength
+	srtpMac(c.rtcpMac, data[n:n+c.profile.rtcpTagLength], data[:n])
+	return n + c.profile.rtcpTagLength, nil
+}
+
+// UnprotectRtcp authenticate and decrypt SRTCP packet in place
+// @param[in] data SRTCP packet
+// @param[in] bytes SRTCP packet length in bytes
+// @return RTCP compound packet length in bytes for RtcpCompoundDeserialize, ErrSrtpAuth/ErrSrtpReplay-packet should be discarded
+func (c *SrtpContext) UnprotectRtcp(data []byte, bytes int) (int, error) {
+	if bytes < SrtcpHeaderLength+c.RtcpOverhead() || bytes > len(data) {
+		return 0, errors.New("srtcp packet too short.")
+	}
+	if c.rtcpGcm != nil {
+		return c.unprotectRtcpAead(data, bytes)
+	}
+
+	n := bytes - c.profile.rtcpTagLength // authenticated portion
+	var tag [sha1.Size]byte
+	srtpMac(c.rtcpMac, tag[:c.profile.rtcpTagLength], data[:n])
+	if !hmac.Equal(tag[:c.profile.rtcpTagLength], data[n:bytes]) {
+		return 0, ErrSrtpAuth
+	}
+
+	ssrc := rtp.RtpReadUint32(data[4:])
+	n -= SrtcpIndexLength
+	v := rtp.RtpReadUint32(data[n:])
+	index := v & SrtcpIndexMask
+	s := c.stream(ssrc)
+	if !s.rtcpReplay.check(uint64(index)) {
+		return 0, ErrSrtpReplay
+	}
+
+	if v&SrtcpEFlag != 0 {
+		if err := srtpAesCmXor(c.rtcpBlock, c.rtcpSalt, ssrc, uint64(index), data[SrtcpHeaderLength:n]); err != nil {
+			return 0, err
+		}
+	}
+	s.rtcpReplay.update(uint64(index))
+	return n, nil
+}
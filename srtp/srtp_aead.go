@@ -0,0 +1,158 @@
+package srtp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"github.com/services-go/librtp/rtp"
+)
+
+// RFC7714 AES-GCM Authenticated Encryption in the Secure Real-time Transport Protocol (SRTP)
+const (
+	SrtpAeadSaltLength = 12 // 96 bits session salt
+	SrtpAeadTagLength  = 16 // 128 bits authentication tag
+)
+
+// RFC7714 8.1. SRTP IV Formation for AES-GCM (p19)
+/*
+     0  0  0  0  0  0  0  0  0  0  1  1
+     0  1  2  3  4  5  6  7  8  9  0  1
+   +--+--+--+--+--+--+--+--+--+--+--+--+
+   |00|00|    SSRC   |     ROC   | SEQ |---+
+   +--+--+--+--+--+--+--+--+--+--+--+--+   |
+                                           |
+   +--+--+--+--+--+--+--+--+--+--+--+--+   |
+   |         Encryption Salt           |->(+)
+   +--+--+--+--+--+--+--+--+--+--+--+--+   |
+                                           |
+   +--+--+--+--+--+--+--+--+--+--+--+--+   |
+   |       Initialization Vector       |<--+
+   +--+--+--+--+--+--+--+--+--+--+--+--+
+*/
+
+// SrtpAeadIV form 12-byte SRTP IV from SSRC, ROC and SEQ
+func SrtpAeadIV(salt []byte, ssrc uint32, roc uint32, seq uint16) []byte {
+	iv := make([]byte, SrtpAeadSaltLength)
+	rtp.RtpWriteUint32(iv[2:], ssrc)
+	rtp.RtpWriteUint32(iv[6:], roc)
+	rtp.RtpWriteUint16(iv[10:], seq)
+	for i := range iv {
+		iv[i] ^= salt[i]
+	}
+	return iv
+}
+
+// RFC7714 9.1. SRTCP IV Formation for AES-GCM (p22)
+/*
+    0  1  2  3  4  5  6  7  8  9 10 11
+   +--+--+--+--+--+--+--+--+--+--+--+--+
+   |00|00|    SSRC   |00|00|0+SRTCP Idx|---+
+   +--+--+--+--+--+--+--+--+--+--+--+--+   |
+                                           |
+   +--+--+--+--+--+--+--+--+--+--+--+--+   |
+   |         Encryption Salt           |->(+)
+   +--+--+--+--+--+--+--+--+--+--+--+--+   |
+                                           |
+   +--+--+--+--+--+--+--+--+--+--+--+--+   |
+   |       Initialization Vector       |<--+
+   +--+--+--+--+--+--+--+--+--+--+--+--+
+*/
+
+// SrtcpAeadIV form 12-byte SRTCP IV from SSRC and 31-bit SRTCP index
+func SrtcpAeadIV(salt []byte, ssrc uint32, index uint32) []byte {
+	iv := make([]byte, SrtpAeadSaltLength)
+	rtp.RtpWriteUint32(iv[2:], ssrc)
+	rtp.RtpWriteUint32(iv[8:], index&SrtcpIndexMask)
+	for i := range iv {
+		iv[i] ^= salt[i]
+	}
+	return iv
+}
+
+// srtpAeadSessionKeys derive AES-GCM session key and 96-bit salt, no authentication key
+// RFC7714 11. Key Derivation Functions (p26)
+func srtpAeadSessionKeys(p *srtpProfile, masterKey, masterSalt []byte, label byte) (cipher.AEAD, []byte, error) {
+	key, err := SrtpKeyDerive(masterKey, masterSalt, label, p.keyLength)
+	if err != nil {
+		return nil, nil, err
+	}
+	salt, err := SrtpKeyDerive(masterKey, masterSalt, label+2, p.saltLength)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return gcm, salt, nil
+}
+
+// RFC7714 9.2. Data Types in Encrypted SRTCP Packets (p23)
+/*
+     0                   1                   2                   3
+     0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+  A +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+  A |V=2|P|    RC   |  Packet Type  |            length             |
+  A +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+  A |           synchronization source (SSRC) of sender             |
+  P +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+  P |                         sender info                           :
+  P +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+  P |                         report block 1                        :
+  P +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+  P |                           ...                                 :
+  Q +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+  Q :                     Cipher Tag (16 octets)                    :
+  Q +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+  A |E|                         SRTCP index                         |
+  A +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+     A = Associated Data, P = Plaintext, Q = Ciphertext
+*/
+func (c *SrtpContext) protectRtcpAead(data []byte, bytes int, ssrc uint32, index uint32) int {
+	var aad [SrtcpHeaderLength + SrtcpIndexLength]byte
+	copy(aad[:], data[:SrtcpHeaderLength])
+	rtp.RtpWriteUint32(aad[SrtcpHeaderLength:], SrtcpEFlag|index)
+
+	iv := SrtcpAeadIV(c.rtcpSalt, ssrc, index)
+	c.rtcpGcm.Seal(data[SrtcpHeaderLength:SrtcpHeaderLength], iv, data[SrtcpHeaderLength:bytes], aad[:])
+	n := bytes + c.profile.rtcpTagLength
+	copy(data[n:], aad[SrtcpHeaderLength:])
+	return n + SrtcpIndexLength
+}
+
+func (c *SrtpContext) unprotectRtcpAead(data []byte, bytes int) (int, error) {
+	n := bytes - SrtcpIndexLength
+	v := rtp.RtpReadUint32(data[n:])
+	index := v & SrtcpIndexMask
+	ssrc := rtp.RtpReadUint32(data[4:])
+	s, found := c.lookup(ssrc)
+	if !s.rtcpReplay.check(uint64(index)) {
+		return 0, ErrSrtpReplay
+	}
+
+	iv := SrtcpAeadIV(c.rtcpSalt, ssrc, index)
+	if v&SrtcpEFlag != 0 {
+		var aad [SrtcpHeaderLength + SrtcpIndexLength]byte
+		copy(aad[:], data[:SrtcpHeaderLength])
+		copy(aad[SrtcpHeaderLength:], data[n:bytes])
+		if _, err := c.rtcpGcm.Open(data[SrtcpHeaderLength:SrtcpHeaderLength], iv, data[SrtcpHeaderLength:n], aad[:]); err != nil {
+			return 0, ErrSrtpAuth
+		}
+	} else {
+		// RFC7714 10.2: unencrypted SRTCP, the whole RTCP packet is associated data
+		tag := n - c.profile.rtcpTagLength
+		aad := make([]byte, 0, tag+SrtcpIndexLength)
+		aad = append(append(aad, data[:tag]...), data[n:bytes]...)
+		if _, err := c.rtcpGcm.Open(nil, iv, data[tag:n], aad); err != nil {
+			return 0, ErrSrtpAuth
+		}
+	}
+	if !found {
+		c.streams[ssrc] = s
+	}
+	s.rtcpReplay.update(uint64(index))
+	return n - c.profile.rtcpTagLength, nil
+}
@@ -0,0 +1,105 @@
+package srtp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+)
+
+// RFC3711 4.1.1 AES in Counter Mode (p21)
+/*
+   Conceptually, counter mode [AES-CTR] consists of encrypting
+   successive integers.  The actual definition is somewhat more
+   complicated, in order to randomize the starting point of the integer
+   sequence.  Each packet is encrypted with a distinct keystream
+   segment, which SHALL be computed as follows.
+
+     IV = (k_s * 2^16) XOR (SSRC * 2^64) XOR (i * 2^16)
+
+   Each of the three terms in the XOR-sum above is padded with as many
+   leading zeros as needed to make the operation well-defined,
+   considered as a 128-bit value.
+*/
+
+// SrtpAesCmXor encrypt or decrypt data in place with AES-CM keystream
+// @param[in] key session encryption key, 16/24/32 bytes
+// @param[in] salt session salt, 14 bytes
+// @param[in] ssrc RTP SSRC, 0 for key derivation
+// @param[in] index packet index(ROC << 16 | SEQ) or SRTCP index
+func SrtpAesCmXor(key, salt []byte, ssrc uint32, index uint64, data []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	return srtpAesCmXor(block, salt, ssrc, index, data)
+}
+
+func srtpAesCmXor(block cipher.Block, salt []byte, ssrc uint32, index uint64, data []byte) error {
+	if len(salt) != SrtpAesCmSaltLength {
+		return errors.New("srtp salt length error.")
+	}
+
+	var iv [aes.BlockSize]byte
+	copy(iv[:], salt)
+	iv[4] ^= byte(ssrc >> 24)
+	iv[5] ^= byte(ssrc >> 16)
+	iv[6] ^= byte(ssrc >> 8)
+	iv[7] ^= byte(ssrc)
+	for i := 0; i < 6; i++ {
+		iv[8+i] ^= byte(index >> uint(40-8*i))
+	}
+
+	// the 16-bit block counter in the low bits never overflow, at most 2^16 blocks per packet
+	cipher.NewCTR(block, iv[:]).XORKeyStream(data, data)
+	return nil
+}
+
+// RFC3711 4.3.1 Key Derivation Algorithm (p26)
+/*
+   Let "a DIV t" denote integer division of a by t, rounded down, and
+   with the convention that "a DIV 0 = 0" for all a.  We also make the
+   convention of treating "a DIV t" as a bit string of the same length
+   as a, and thus "a DIV t" will in general have leading zeros.
+
+   Key derivation SHALL be defined as follows in terms of <label>, an
+   8-bit constant (see below), master_salt and key_derivation_rate, as
+   determined in the cryptographic context, and index, the packet index
+   (i.e., the 48-bit ROC || SEQ for SRTP):
+
+   *  Let r = index DIV key_derivation_rate (with DIV as defined above).
+
+   *  Let key_id = <label> || r.
+
+   *  Let x = key_id XOR master_salt, where key_id and master_salt are
+      aligned so that their least significant bits agree (right-
+      alignment).
+*/
+const (
+	SRTP_LABEL_RTP_ENCRYPTION  = 0x00
+	SRTP_LABEL_RTP_AUTH        = 0x01
+	SRTP_LABEL_RTP_SALT        = 0x02
+	SRTP_LABEL_RTCP_ENCRYPTION = 0x03
+	SRTP_LABEL_RTCP_AUTH       = 0x04
+	SRTP_LABEL_RTCP_SALT       = 0x05
+)
+
+// SrtpKeyDerive derive session key with key_derivation_rate 0
+// @param[in] masterKey master key, 16/24/32 bytes
+// @param[in] masterSalt master salt, 14 bytes(12 bytes AEAD salt is padded with zeros on the right)
+// @param[in] label SRTP_LABEL_XXX
+// @param[in] n session key length in bytes
+func SrtpKeyDerive(masterKey, masterSalt []byte, label byte, n int) ([]byte, error) {
+	if len(masterSalt) > SrtpAesCmSaltLength {
+		return nil, errors.New("srtp master salt length error.")
+	}
+
+	var x [SrtpAesCmSaltLength]byte
+	copy(x[:], masterSalt)
+	x[7] ^= label // r = 0, key_id = <label> || 0x000000000000
+
+	key := make([]byte, n)
+	if err := SrtpAesCmXor(masterKey, x[:], 0, 0, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,365 @@
+// RFC3711 The Secure Real-time Transport Protocol (SRTP)
+// 3.1. SRTP Packet Format (p5)
+/*
+     0                   1                   2                   3
+     0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+    +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+<+
+    |V=2|P|X|  CC   |M|     PT      |       sequence number         | |
+    +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+    |                           timestamp                           | |
+    +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+    |           synchronization source (SSRC) identifier            | |
+    +=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+=+ |
+    |            contributing source (CSRC) identifiers             | |
+    |                               ....                            | |
+    +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+    |                   RTP extension (OPTIONAL)                    | |
+  +>+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+  | |                          payload  ...                         | |
+  | |                               +-------------------------------+ |
+  | |                               | RTP padding   | RTP pad count | |
+  +>+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+<+
+  | ~                     SRTP MKI (OPTIONAL)                       ~ |
+  | +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+  | :                 authentication tag (RECOMMENDED)              : |
+  | +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+ |
+  |                                                                   |
+  +- Encrypted Portion*                      Authenticated Portion ---+
+*/
+
+package srtp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"errors"
+	"github.com/services-go/librtp/rtp"
+	"hash"
+)
+
+// RFC5764 4.1.2. SRTP Protection Profiles (p7)
+const (
+	SRTP_AES128_CM_HMAC_SHA1_80 = 0x0001
+	SRTP_AES128_CM_HMAC_SHA1_32 = 0x0002
+	SRTP_AEAD_AES_128_GCM       = 0x0007 // RFC7714 14.2
+	SRTP_AEAD_AES_256_GCM       = 0x0008 // RFC7714 14.2
+)
+
+const (
+	SrtpAesCmSaltLength = 14 // 112 bits session salt
+	SrtpHmacKeyLength   = 20 // 160 bits HMAC-SHA1 session authentication key
+)
+
+var (
+	ErrSrtpAuth   = errors.New("srtp authentication failed.")
+	ErrSrtpReplay = errors.New("srtp packet replayed.")
+)
+
+type srtpProfile struct {
+	keyLength     int // master/session key length
+	saltLength    int // master/session salt length
+	tagLength     int // SRTP authentication tag length
+	rtcpTagLength int // SRTCP authentication tag length
+	aead          bool
+}
+
+func srtpProfileFind(profile int) (*srtpProfile, error) {
+	switch profile {
+	case SRTP_AES128_CM_HMAC_SHA1_80:
+		return &srtpProfile{keyLength: 16, saltLength: SrtpAesCmSaltLength, tagLength: 10, rtcpTagLength: 10}, nil
+	case SRTP_AES128_CM_HMAC_SHA1_32:
+		// RFC5764 4.1.2: SRTCP auth_tag_length is 80 bits
+		return &srtpProfile{keyLength: 16, saltLength: SrtpAesCmSaltLength, tagLength: 4, rtcpTagLength: 10}, nil
+	case SRTP_AEAD_AES_128_GCM:
+		return &srtpProfile{keyLength: 16, saltLength: SrtpAeadSaltLength, tagLength: SrtpAeadTagLength, rtcpTagLength: SrtpAeadTagLength, aead: true}, nil
+	case SRTP_AEAD_AES_256_GCM:
+		return &srtpProfile{keyLength: 32, saltLength: SrtpAeadSaltLength, tagLength: SrtpAeadTagLength, rtcpTagLength: SrtpAeadTagLength, aead: true}, nil
+	default:
+		return nil, errors.New("srtp profile not supported.")
+	}
+}
+
+// srtpStream per-SSRC cryptographic context state
+type srtpStream struct {
+	started bool
+	roc     uint32 // rollover counter
+	seq     uint16 // s_l, highest sequence number
+	replay  srtpReplay
+
+	rtcpIndex  uint32 // next SRTCP index to send
+	rtcpReplay srtpReplay
+}
+
+// SrtpContext SRTP cryptographic context of one direction(sender or receiver)
+// with the same master key for all SSRCs, key_derivation_rate 0 and no MKI
+type SrtpContext struct {
+	profile *srtpProfile
+	block   cipher.Block // session encryption key
+	salt    []byte       // session salt
+	mac     hash.Hash    // session authentication key
+
+	rtcpBlock cipher.Block // SRTCP session encryption key
+	rtcpSalt  []byte       // SRTCP session salt
+	rtcpMac   hash.Hash    // SRTCP session authentication key
+
+	gcm     cipher.AEAD // AEAD profiles, instead of block and mac
+	rtcpGcm cipher.AEAD
+
+	streams map[uint32]*srtpStream
+}
+
+// NewSrtpContext create SRTP context, derive SRTP and SRTCP session keys (RFC3711 4.3)
+// @param[in] profile SRTP_XXX protection profile
+// @param[in] masterKey master key, 16 bytes for AES-128, 32 bytes for AES-256
+// @param[in] masterSalt master salt, 14 bytes, 12 bytes for AEAD
+func NewSrtpContext(profile int, masterKey, masterSalt []byte) (*SrtpContext, error) {
+	p, err := srtpProfileFind(profile)
+	if err != nil {
+		return nil, err
+	}
+	if len(masterKey) != p.keyLength || len(masterSalt) != p.saltLength {
+		return nil, errors.New("srtp master key length error.")
+	}
+
+	c := &SrtpContext{profile: p, streams: make(map[uint32]*srtpStream)}
+	if p.aead {
+		if c.gcm, c.salt, err = srtpAeadSessionKeys(p, masterKey, masterSalt, SRTP_LABEL_RTP_ENCRYPTION); err != nil {
+			return nil, err
+		}
+		if c.rtcpGcm, c.rtcpSalt, err = srtpAeadSessionKeys(p, masterKey, masterSalt, SRTP_LABEL_RTCP_ENCRYPTION); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	if c.block, c.salt, c.mac, err = srtpSessionKeys(p, masterKey, masterSalt, SRTP_LABEL_RTP_ENCRYPTION); err != nil {
+		return nil, err
+	}
+	if c.rtcpBlock, c.rtcpSalt, c.rtcpMac, err = srtpSessionKeys(p, masterKey, masterSalt, SRTP_LABEL_RTCP_ENCRYPTION); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// srtpSessionKeys derive encryption key, salt and authentication key
+// @param[in] label SRTP_LABEL_RTP_ENCRYPTION or SRTP_LABEL_RTCP_ENCRYPTION, auth and salt labels follow
+func srtpSessionKeys(p *srtpProfile, masterKey, masterSalt []byte, label byte) (cipher.Block, []byte, hash.Hash, error) {
+	key, err := SrtpKeyDerive(masterKey, masterSalt, label, p.keyLength)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	salt, err := SrtpKeyDerive(masterKey, masterSalt, label+2, p.saltLength)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	auth, err := SrtpKeyDerive(masterKey, masterSalt, label+1, SrtpHmacKeyLength)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return block, salt, hmac.New(sha1.New, auth), nil
+}
+
+// Overhead return bytes appended to RTP packet by Protect
+func (c *SrtpContext) Overhead() int {
+	return c.profile.tagLength
+}
+
+// ROC return rollover counter of ssrc, e.g. for RFC4771 or rekeying
+func (c *SrtpContext) ROC(ssrc uint32) uint32 {
+	if s, ok := c.streams[ssrc]; ok {
+		return s.roc
+	}
+	return 0
+}
+
+// SetROC set rollover counter of ssrc, e.g. join a stream in progress
+func (c *SrtpContext) SetROC(ssrc uint32, roc uint32) {
+	s := c.stream(ssrc)
+	s.roc = roc
+}
+
+func (c *SrtpContext) stream(ssrc uint32) *srtpStream {
+	s, ok := c.streams[ssrc]
+	if !ok {
+		s = &srtpStream{}
+		c.streams[ssrc] = s
+	}
+	return s
+}
+
+// lookup return stream state of ssrc without adding a new one to streams,
+// receiver add it after the packet authenticated, so forged SSRCs can't grow streams
+func (c *SrtpContext) lookup(ssrc uint32) (*srtpStream, bool) {
+	if s, ok := c.streams[ssrc]; ok {
+		return s, true
+	}
+	return &srtpStream{}, false
+}
+
+// Protect encrypt RTP packet in place and append authentication tag
+// @param[in] data RTP packet from RtpPacketSerialize, capacity bytes+Overhead() at least
+// @param[in] bytes RTP packet length in bytes
+// @return SRTP packet length in bytes
+func (c *SrtpContext) Protect(data []byte, bytes int) (int, error) {
+	header, err := srtpHeaderSize(data, bytes)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < bytes+c.profile.tagLength {
+		return 0, errors.New("srtp buffer too small.")
+	}
+
+	// 3.3.1 Packet Index Determination, and ROC, s_l Update (p15)
+	// the sender increments ROC when SEQ wraps
+	ssrc := rtp.RtpReadUint32(data[rtp.RtpHeader_SsrcOffset:])
+	seq := rtp.RtpReadUint16(data[rtp.RtpHeader_SeqNumOffset:])
+	s := c.stream(ssrc)
+	if !s.started {
+		s.started = true
+		s.seq = seq
+	} else if int16(seq-s.seq) > 0 {
+		if seq < s.seq {
+			s.roc++
+		}
+		s.seq = seq
+	}
+	roc := s.roc
+	if int16(seq-s.seq) < 0 && seq > s.seq {
+		roc-- // retransmission of packet before the wrap
+	}
+
+	if c.gcm != nil {
+		c.gcm.Seal(data[header:header], SrtpAeadIV(c.salt, ssrc, roc, seq), data[header:bytes], data[:header])
+		return bytes + c.profile.tagLength, nil
+	}
+	if err = srtpAesCmXor(c.block, c.salt, ssrc, uint64(roc)<<16|uint64(seq), data[header:bytes]); err != nil {
+		return 0, err
+	}
+	srtpSign(c.mac, data[:bytes], roc, data[bytes:bytes+c.profile.tagLength])
+	return bytes + c.profile.tagLength, nil
+}
+
+// Unprotect authenticate and decrypt SRTP packet in place
+// @param[in] data SRTP packet
+// @param[in] bytes SRTP packet length in bytes
+// @return RTP packet length in bytes for RtpPacketDeserialize, ErrSrtpAuth/ErrSrtpReplay-packet should be discarded
+func (c *SrtpContext) Unprotect(data []byte, bytes int) (int, error) {
+	if bytes < rtp.RtpFixedHeader+c.profile.tagLength || bytes > len(data) {
+		return 0, errors.New("srtp packet too short.")
+	}
+	n := bytes - c.profile.tagLength
+	header, err := srtpHeaderSize(data, n)
+	if err != nil {
+		return 0, err
+	}
+
+	ssrc := rtp.RtpReadUint32(data[rtp.RtpHeader_SsrcOffset:])
+	seq := rtp.RtpReadUint16(data[rtp.RtpHeader_SeqNumOffset:])
+	s, found := c.lookup(ssrc)
+	roc := s.estimate(seq)
+	index := uint64(roc)<<16 | uint64(seq)
+	if !s.replay.check(index) {
+		return 0, ErrSrtpReplay
+	}
+
+	if c.gcm != nil {
+		if _, err = c.gcm.Open(data[header:header], SrtpAeadIV(c.salt, ssrc, roc, seq), data[header:bytes], data[:header]); err != nil {
+			return 0, ErrSrtpAuth
+		}
+	} else {
+		var tag [sha1.Size]byte
+		srtpSign(c.mac, data[:n], roc, tag[:c.profile.tagLength])
+		if !hmac.Equal(tag[:c.profile.tagLength], data[n:bytes]) {
+			return 0, ErrSrtpAuth
+		}
+
+		if err = srtpAesCmXor(c.block, c.salt, ssrc, index, data[header:n]); err != nil {
+			return 0, err
+		}
+	}
+	if !found {
+		c.streams[ssrc] = s
+	}
+	s.update(roc, seq)
+	s.replay.update(index)
+	return n, nil
+}
+
+// srtpSign HMAC-SHA1(authenticated portion || ROC), truncated to tag length (RFC3711 4.2)
+func srtpSign(mac hash.Hash, data []byte, roc uint32, tag []byte) {
+	var v [4]byte
+	rtp.RtpWriteUint32(v[:], roc)
+	srtpMac(mac, tag, data, v[:])
+}
+
+// srtpMac HMAC of the concatenation of data, truncated to tag length
+func srtpMac(mac hash.Hash, tag []byte, data ...[]byte) {
+	mac.Reset()
+	for _, d := range data {
+		mac.Write(d)
+	}
+	var sum [sha1.Size]byte
+	copy(tag, mac.Sum(sum[:0]))
+}
+
+// estimate ROC of seq (RFC3711 Appendix A)
+func (s *srtpStream) estimate(seq uint16) uint32 {
+	if !s.started {
+		return s.roc
+	}
+
+	if s.seq < 0x8000 {
+		if seq > s.seq && seq-s.seq > 0x8000 {
+			return s.roc - 1
+		}
+	} else if s.seq-0x8000 > seq {
+		return s.roc + 1
+	}
+	return s.roc
+}
+
+// update ROC and s_l after authentication
+func (s *srtpStream) update(roc uint32, seq uint16) {
+	if !s.started {
+		s.started = true
+		s.seq = seq
+		return
+	}
+
+	if roc == s.roc+1 {
+		s.roc = roc
+		s.seq = seq
+	} else if roc == s.roc && seq > s.seq {
+		s.seq = seq
+	}
+}
+
+// srtpHeaderSize RTP header length include CSRC and header extension,
+// the padding is encrypted so RtpPacketViewParse can't be used
+func srtpHeaderSize(data []byte, bytes int) (int, error) {
+	if bytes < rtp.RtpFixedHeader || bytes > len(data) {
+		return 0, errors.New("rtp header need 12 bytes.")
+	}
+
+	h := rtp.RtpReadUint32(data)
+	if rtp.RTP_V(h) != rtp.RtpVersion {
+		return 0, errors.New("rtp version error.")
+	}
+	n := rtp.RtpFixedHeader + int(rtp.RTP_CC(h))*4
+	if rtp.RTP_X(h) > 0 {
+		if n+4 > bytes {
+			return 0, errors.New("no enough bytes.")
+		}
+		n += 4 + int(rtp.RtpReadUint16(data[n+2:]))*4
+	}
+	if n > bytes {
+		return 0, errors.New("no enough bytes.")
+	}
+	return n, nil
+}
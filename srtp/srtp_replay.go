@@ -0,0 +1,63 @@
+package srtp
+
+// RFC3711 3.3.2 Replay Protection (p17)
+/*
+   Secure replay protection is only possible when integrity protection
+   is present.  It is RECOMMENDED to use replay protection, both for RTP
+   and RTCP, as integrity protection alone cannot assure security
+   against replay attacks.
+
+   A packet is "replayed" when it is stored by an adversary, and then
+   re-injected into the network.  When message authentication is
+   provided, SRTP protects against such attacks through a Replay List.
+   Each SRTP receiver maintains a Replay List, which conceptually
+   contains the indices of all of the packets which have been received
+   and authenticated.  In practice, the list can use a "sliding window"
+   approach, so that a fixed amount of storage suffices for replay
+   protection.
+*/
+const (
+	SrtpReplayWindowSize = 64 // RFC3711 3.3.2 minimum window size
+)
+
+type srtpReplay struct {
+	started bool
+	highest uint64 // highest index authenticated
+	bitmap  uint64 // bit i set if index highest-i received
+}
+
+// check return false if index replayed or too old
+func (r *srtpReplay) check(index uint64) bool {
+	if !r.started || index > r.highest {
+		return true
+	}
+
+	delta := r.highest - index
+	if delta >= SrtpReplayWindowSize {
+		return false
+	}
+	return r.bitmap&(1<<delta) == 0
+}
+
+// update mark index received, call after authentication
+func (r *srtpReplay) update(index uint64) {
+	if !r.started {
+		r.started = true
+		r.highest = index
+		r.bitmap = 1
+		return
+	}
+
+	if index > r.highest {
+		delta := index - r.highest
+		if delta >= SrtpReplayWindowSize {
+			r.bitmap = 0
+		} else {
+			r.bitmap <<= delta
+		}
+		r.bitmap |= 1
+		r.highest = index
+	} else {
+		r.bitmap |= 1 << (r.highest - index)
+	}
+}
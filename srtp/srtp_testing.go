@@ -0,0 +1,35 @@
+package srtp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+)
+
+// SetSessionKeys use AEAD session key and salt as is instead of deriving them from the master key,
+// known answer tests only, e.g. RFC7714 16/17 test vectors give the session key and salt
+// for both SRTP and SRTCP
+func (c *SrtpContext) SetSessionKeys(key, salt []byte) error {
+	if !c.profile.aead {
+		return errors.New("srtp session keys aead only.")
+	}
+	if len(key) != c.profile.keyLength || len(salt) != c.profile.saltLength {
+		return errors.New("srtp session key length error.")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	c.gcm, c.salt = gcm, salt
+	c.rtcpGcm, c.rtcpSalt = gcm, salt
+	return nil
+}
+
+// SetRtcpIndex set the next SRTCP index to send of ssrc, known answer tests only
+func (c *SrtpContext) SetRtcpIndex(ssrc uint32, index uint32) {
+	c.stream(ssrc).rtcpIndex = index & SrtcpIndexMask
+}
@@ -0,0 +1,180 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/services-go/librtp/capture"
+	"github.com/services-go/librtp/payload"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func pcapTestNalu(size int, seed byte) []byte {
+	nalu := make([]byte, size)
+	nalu[0] = 0x65
+	for i := 1; i < size; i++ {
+		nalu[i] = byte(i) + seed
+	}
+	return nalu
+}
+
+func TestPcapRtp(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	src := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 5000}
+	video := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 6000}
+	other := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 6002}
+
+	var buf bytes.Buffer
+	w, err := capture.NewPcapWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h1 := capture.NewPcapRtpHandler(w, src, video, 90000, start)
+	h2 := capture.NewPcapRtpHandler(w, src, other, 90000, start)
+	var p1, p2 payload.RtpPackH264
+	p1.Init(1200, 96, 100, 0x1234, h1, nil)
+	p2.Init(1200, 96, 200, 0x5678, h2, nil)
+
+	var nalus [][]byte
+	for i := 0; i < 3; i++ {
+		nalu := pcapTestNalu(3000, byte(i))
+		nalus = append(nalus, nalu)
+		p1.Input(append([]byte{0, 0, 0, 1}, nalu...), len(nalu)+4, uint32(3000*i))
+		p2.Input(append([]byte{0, 0, 0, 1}, nalu[:100]...), 104, uint32(3000*i))
+	}
+	rtcp := []byte{0x80, 0xC9, 0x00, 0x01, 0x00, 0x00, 0x12, 0x34}
+	w.WritePacket(start, src, video, rtcp, len(rtcp))
+	if h1.Err != nil || h2.Err != nil {
+		t.Fatal(h1.Err, h2.Err)
+	}
+	data := buf.Bytes()
+
+	// feed capture to RtpUnpackH264
+	r, err := capture.NewPcapReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sink paddingPayload
+	var unpacker payload.RtpUnpackH264
+	unpacker.Init(&sink, &sink)
+	packets := 0
+	for {
+		pkt, err := r.ReadRtp(&capture.PcapFilter{SSRC: []uint32{0x1234}})
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if !pkt.Src.IP.Equal(src.IP) || pkt.Dst.Port != video.Port {
+			t.Fatalf("address %v -> %v", pkt.Src, pkt.Dst)
+		}
+		if expected := start.Add(time.Duration(packets/3) * time.Second / 30).Truncate(time.Microsecond); !pkt.Timestamp.Equal(expected) {
+			t.Fatalf("packet %d timestamp %v", packets, pkt.Timestamp)
+		}
+		unpacker.Input(pkt.Data, len(pkt.Data))
+		packets++
+	}
+	if packets != 9 || len(sink.frames) != len(nalus) {
+		t.Fatalf("packets %d, frames %d", packets, len(sink.frames))
+	}
+	for i := range nalus {
+		if !bytes.Equal(sink.frames[i], nalus[i]) {
+			t.Fatalf("frame %d", i)
+		}
+	}
+
+	// 5-tuple filter, RTCP skipped
+	r, _ = capture.NewPcapReader(bytes.NewReader(data))
+	filter := &capture.PcapFilter{Dst: &net.UDPAddr{Port: other.Port}}
+	for packets = 0; ; packets++ {
+		if _, err = r.ReadRtp(filter); err != nil {
+			break
+		}
+	}
+	if err != io.EOF || packets != 3 {
+		t.Fatalf("filter packets %d, %v", packets, err)
+	}
+	r, _ = capture.NewPcapReader(bytes.NewReader(data))
+	for packets = 0; ; packets++ {
+		if _, err = r.ReadPacket(); err != nil {
+			break
+		}
+	}
+	if packets != 13 {
+		t.Fatalf("udp packets %d", packets)
+	}
+}
+
+func TestPcapng(t *testing.T) {
+	src := &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 5000}
+	dst := &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 6000}
+	rtp := srtpTestPacket(t, 7, []byte("pcapng"))
+
+	var buf bytes.Buffer
+	w, _ := capture.NewPcapWriter(&buf)
+	if err := w.WritePacket(time.Unix(0, 0), src, dst, rtp, len(rtp)); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := capture.NewPcapReader(bytes.NewReader(buf.Bytes()))
+	linktype, _, frame, err := r.ReadFrame()
+	if err != nil || linktype != capture.PCAP_LINKTYPE_ETHERNET {
+		t.Fatal(linktype, err)
+	}
+
+	le := binary.LittleEndian
+	block := func(typ uint32, body []byte) []byte {
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+		b := make([]byte, 8, 12+len(body))
+		le.PutUint32(b, typ)
+		le.PutUint32(b[4:], uint32(12+len(body)))
+		b = append(b, body...)
+		return le.AppendUint32(b, uint32(12+len(body)))
+	}
+	pcapng := func(tsresol byte, ts uint64) []byte {
+		var ng []byte
+		shb := le.AppendUint32(nil, capture.PcapngByteOrderMagic)
+		shb = append(shb, 1, 0, 0, 0, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+		ng = append(ng, block(capture.PcapngBlockSHB, shb)...)
+		idb := []byte{capture.PCAP_LINKTYPE_ETHERNET, 0, 0, 0, 0, 0, 0, 0, 9, 0, 1, 0, tsresol, 0, 0, 0, 0, 0, 0, 0} // if_tsresol
+		ng = append(ng, block(capture.PcapngBlockIDB, idb)...)
+		ng = append(ng, block(5, []byte{1, 2, 3, 4})...) // Interface Statistics, skipped
+		epb := le.AppendUint32(nil, 0)
+		epb = le.AppendUint32(epb, uint32(ts>>32))
+		epb = le.AppendUint32(epb, uint32(ts))
+		epb = le.AppendUint32(epb, uint32(len(frame)))
+		epb = le.AppendUint32(epb, uint32(len(frame)))
+		return append(ng, block(capture.PcapngBlockEPB, append(epb, frame...))...)
+	}
+	ts := uint64(1700000000123456789)
+	ng := pcapng(9, ts)
+
+	r, err = capture.NewPcapReader(bytes.NewReader(ng))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkt, err := r.ReadRtp(nil)
+	if err != nil || !bytes.Equal(pkt.Data, rtp) || pkt.Timestamp.UnixNano() != int64(ts) || !pkt.Src.IP.Equal(src.IP) || pkt.Dst.Port != 6000 {
+		t.Fatalf("pcapng %+v, %v", pkt, err)
+	}
+	if _, err = r.ReadRtp(nil); err != io.EOF {
+		t.Fatalf("eof %v", err)
+	}
+
+	// 2^-40 seconds resolution, 5.5s
+	r, _ = capture.NewPcapReader(bytes.NewReader(pcapng(0x80|40, 5<<40|1<<39)))
+	if pkt, err = r.ReadRtp(nil); err != nil || pkt.Timestamp.UnixNano() != 5500000000 {
+		t.Fatalf("pcapng 2^-40 %+v, %v", pkt, err)
+	}
+
+	// resolution out of range
+	for _, tsresol := range []byte{0x80 | 64, 20} {
+		r, _ = capture.NewPcapReader(bytes.NewReader(pcapng(tsresol, ts)))
+		if _, _, _, err = r.ReadFrame(); err == nil || err == io.EOF {
+			t.Fatalf("pcapng tsresol %x, %v", tsresol, err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package test
+
+import (
+	"github.com/services-go/librtp/rtp"
+	"testing"
+	"time"
+)
+
+func TestRtcpScheduler(t *testing.T) {
+	now := time.Unix(3000, 0)
+	table := rtp.NewRtpMemberTable(0x1234, []byte("self"), now)
+	half := func() float64 { return 0.5 }
+	s := rtp.NewRtcpScheduler(table, 8000, half, now)
+
+	// initial interval: Tmin/2 randomized by 1.0 and divided by e-3/2
+	tmin := 2.5
+	initial := time.Duration(tmin / rtp.RtcpCompensation * float64(time.Second))
+	if d := s.Next().Sub(now) - initial; d > time.Millisecond || d < -time.Millisecond {
+		t.Fatalf("initial interval %v, expect %v", s.Next().Sub(now), initial)
+	}
+	if s.OnExpire(now.Add(time.Second)) {
+		t.Fatal("expire too early")
+	}
+	if !s.OnExpire(s.Next()) {
+		t.Fatal("expect send")
+	}
+	sent := s.Next()
+	s.OnSent(sent, 100)
+	if s.Next().Sub(sent) < rtp.RtcpMinInterval/2 {
+		t.Fatalf("interval %v less than Tmin", s.Next().Sub(sent))
+	}
+
+	// reverse reconsideration: members drop from 3 to 2 halves remaining time
+	var pkt rtp.RtpPacket
+	for _, ssrc := range []uint32{1, 2} {
+		pkt.Header.SSRC = ssrc
+		for seq := uint16(0); seq < 2; seq++ {
+			pkt.Header.SequenceNumber = seq
+			table.OnRtp(&pkt, "10.0.0.1:5000", sent)
+		}
+	}
+	s.OnExpire(sent.Add(time.Second))
+	tc := sent.Add(2 * time.Second)
+	remain := s.Next().Sub(tc)
+	table.OnRtcp([]rtp.RtcpPacket{&rtp.RtcpBye{SSRC: []uint32{1}}}, "10.0.0.1:5001", tc)
+	s.OnBye(tc, 1)
+	if got := s.Next().Sub(tc); got != time.Duration(float64(remain)*2/3) {
+		t.Fatalf("reverse reconsideration %v, expect %v", got, remain*2/3)
+	}
+}
@@ -0,0 +1,51 @@
+package test
+
+import (
+	"bytes"
+	"github.com/services-go/librtp/rtp"
+	"testing"
+)
+
+func TestRtcpCompound(t *testing.T) {
+	sr := &rtp.RtcpSR{SSRC: 0x11223344, NTPMSW: 0xE0000000, NTPLSW: 0x80000000, RTPTime: 90000, Packets: 10, Octets: 12000}
+	sr.Reports = append(sr.Reports, rtp.RtcpReport{SSRC: 0x55667788, Fraction: 25, Lost: -3, ExtSeq: 0x10005, Jitter: 120, LSR: 0x12345678, DLSR: 65536})
+	sdes := &rtp.RtcpSdes{Chunks: []rtp.RtcpSdesChunk{{SSRC: 0x11223344, Items: []rtp.RtcpSdesItem{{Type: rtp.RTCP_SDES_CNAME, Text: []byte("cam@10.0.0.1")}}}}}
+	bye := &rtp.RtcpBye{SSRC: []uint32{0x11223344}, Reason: []byte("shutdown")}
+	app := &rtp.RtcpApp{Subtype: 3, SSRC: 0x11223344, Name: [4]byte{'t', 'e', 's', 't'}, Data: []byte{1, 2, 3, 4}}
+
+	buf := make([]byte, 1500)
+	n, err := rtp.RtcpCompoundSerialize([]rtp.RtcpPacket{sr, sdes, bye, app}, buf, len(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != sr.PacketSize()+sdes.PacketSize()+bye.PacketSize()+app.PacketSize() || n%4 != 0 {
+		t.Fatalf("compound size %d", n)
+	}
+
+	pkts, err := rtp.RtcpCompoundDeserialize(buf, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkts) != 4 {
+		t.Fatalf("packets %d", len(pkts))
+	}
+
+	sr2 := pkts[0].(*rtp.RtcpSR)
+	if sr2.SSRC != sr.SSRC || sr2.RTPTime != sr.RTPTime || len(sr2.Reports) != 1 || sr2.Reports[0] != sr.Reports[0] {
+		t.Fatalf("sr mismatch %+v", sr2)
+	}
+	if cname := pkts[1].(*rtp.RtcpSdes).CNAME(0x11223344); !bytes.Equal(cname, []byte("cam@10.0.0.1")) {
+		t.Fatalf("cname %q", cname)
+	}
+	if bye2 := pkts[2].(*rtp.RtcpBye); bye2.SSRC[0] != 0x11223344 || string(bye2.Reason) != "shutdown" {
+		t.Fatalf("bye mismatch %+v", bye2)
+	}
+	if app2 := pkts[3].(*rtp.RtcpApp); app2.Subtype != 3 || app2.Name != app.Name || !bytes.Equal(app2.Data, app.Data) {
+		t.Fatalf("app mismatch %+v", app2)
+	}
+
+	// truncated compound packet
+	if _, err = rtp.RtcpCompoundDeserialize(buf, n-4); err == nil {
+		t.Fatal("expect length error")
+	}
+}
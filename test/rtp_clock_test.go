@@ -0,0 +1,53 @@
+package test
+
+import (
+	"github.com/services-go/librtp/rtp"
+	"testing"
+	"time"
+)
+
+func TestRtpNtp(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 500000000, time.UTC)
+	ntp := rtp.RtpTimeToNtp(now)
+	if ntp>>32 != uint64(now.Unix())+rtp.RtpNtpOffset || uint32(ntp) != 0x80000000 {
+		t.Fatalf("ntp %x", ntp)
+	}
+	if d := rtp.RtpNtpToTime(ntp).Sub(now); d != 0 {
+		t.Fatalf("ntp to time %v", d)
+	}
+}
+
+func TestRtpClockRecovery(t *testing.T) {
+	base := time.Unix(1600000000, 0)
+	clock := rtp.NewRtpClock(90000, base, func() uint32 { return 0xFFFF0000 })
+	if ts := clock.Timestamp(base.Add(time.Second)); ts != 90000-0x10000 {
+		t.Fatalf("timestamp %x", ts)
+	}
+
+	var sr rtp.RtcpSR
+	clock.SenderInfo(&sr, base)
+	recovery := rtp.NewRtpClockRecovery(90000)
+	if _, ok := recovery.Time(sr.RTPTime); ok {
+		t.Fatal("synced without SR")
+	}
+	recovery.OnSR(&sr)
+
+	// timestamp wraparound after SR
+	for _, d := range []time.Duration{time.Second, 40 * time.Millisecond, -time.Second, 20000 * time.Second, 40000 * time.Second, 60000 * time.Second} {
+		capture, ok := recovery.Time(clock.Timestamp(base.Add(d)))
+		if !ok || !capture.Equal(base.Add(d)) {
+			t.Fatalf("capture %v, expected %v", capture, base.Add(d))
+		}
+	}
+
+	recovery = rtp.NewRtpClockRecovery(90000)
+	if ext := recovery.Extend(0xFFFF0000); ext != 0xFFFF0000 {
+		t.Fatalf("extended %x", ext)
+	}
+	if ext := recovery.Extend(0x10); ext != 0x100000010 {
+		t.Fatalf("extended %x", ext)
+	}
+	if ext := recovery.Extend(0xFFFFFFF0); ext != 0xFFFFFFF0 {
+		t.Fatalf("reordered %x", ext)
+	}
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"bytes"
+	"github.com/services-go/librtp/rtp"
+	"testing"
+)
+
+func TestRtpExtension(t *testing.T) {
+	var pkt rtp.RtpPacket
+	pkt.Header.Version = rtp.RtpVersion
+	pkt.Header.SSRC = 0x1234
+	pkt.Payload = []byte{1, 2, 3}
+	pkt.PayloadLen = 3
+
+	if err := pkt.SetExtension(1, []byte{0x80}); err != nil { // audio level
+		t.Fatal(err)
+	}
+	if err := pkt.SetExtension(3, []byte{0x12, 0x34, 0x56}); err != nil { // abs-send-time
+		t.Fatal(err)
+	}
+	if pkt.Reserved != rtp.RtpExtensionOneByte || pkt.Extlen != 8 {
+		t.Fatalf("one-byte profile %x len %d", pkt.Reserved, pkt.Extlen)
+	}
+
+	buf := make([]byte, 64)
+	n, err := rtp.RtpPacketSerialize(&pkt, buf, len(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pkt2 rtp.RtpPacket
+	if err = rtp.RtpPacketDeserialize(&pkt2, buf, n); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pkt2.GetExtension(3), []byte{0x12, 0x34, 0x56}) || !bytes.Equal(pkt2.Payload[:pkt2.PayloadLen], pkt.Payload) {
+		t.Fatalf("extension %x payload %x", pkt2.GetExtension(3), pkt2.Payload[:pkt2.PayloadLen])
+	}
+
+	// 17 bytes MID switch to two-byte header
+	mid := []byte("0123456789abcdefg")
+	if err = pkt2.SetExtension(5, mid); err != nil {
+		t.Fatal(err)
+	}
+	if pkt2.Reserved&rtp.RtpExtensionTwoByteMask != rtp.RtpExtensionTwoByte || !bytes.Equal(pkt2.GetExtension(5), mid) || pkt2.GetExtension(1)[0] != 0x80 {
+		t.Fatalf("two-byte profile %x", pkt2.Reserved)
+	}
+
+	for _, id := range []byte{1, 3, 5} {
+		if err = pkt2.RemoveExtension(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if pkt2.Header.Extension != 0 || pkt2.Extlen != 0 {
+		t.Fatal("extension bit not cleared")
+	}
+
+	// one-byte element length beyond extension block
+	if _, err = rtp.RtpExtensionDeserialize(rtp.RtpExtensionOneByte, []byte{0x13, 0x00, 0x00, 0x00}); err == nil {
+		t.Fatal("expect length error")
+	}
+}
@@ -0,0 +1,222 @@
+package test
+
+import (
+	"bytes"
+	"github.com/services-go/librtp/payload"
+	"github.com/services-go/librtp/rtp"
+	"github.com/services-go/librtp/sdp"
+	"testing"
+	"time"
+)
+
+// fecTestJitter feed FEC decoder output to jitter buffer, recovered packets arrive out of order
+type fecTestJitter struct {
+	jb  *payload.RtpJitterBuffer
+	now time.Time
+}
+
+func (j *fecTestJitter) Init(handler payload.RtpPayload, param interface{}) {
+}
+
+func (j *fecTestJitter) Destroy() {
+}
+
+func (j *fecTestJitter) Input(packet []byte, bytes int) (int, error) {
+	return 1, j.jb.Input(packet, bytes, j.now)
+}
+
+// fecTestNetwork drop packets by index, deliver others to the decoder
+type fecTestNetwork struct {
+	decoder *payload.RtpFecDecoder
+	drop    map[int]bool
+	packets [][]byte
+}
+
+func (n *fecTestNetwork) Alloc(param interface{}, bytes int) []byte {
+	return make([]byte, bytes)
+}
+
+func (n *fecTestNetwork) Free(param interface{}, packet []byte) {
+}
+
+func (n *fecTestNetwork) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
+	i := len(n.packets)
+	n.packets = append(n.packets, packet[:bytes])
+	if !n.drop[i] {
+		n.decoder.Input(packet, bytes)
+	}
+}
+
+func fecTestReceiver(sink *paddingPayload) *fecTestJitter {
+	unpacker := &payload.RtpUnpackH264{}
+	unpacker.Init(sink, sink)
+	return &fecTestJitter{jb: payload.NewRtpJitterBuffer(unpacker, time.Second, 0), now: time.Unix(1000, 0)}
+}
+
+func TestRtpFecMask(t *testing.T) {
+	if m := payload.RtpFecMaskInterleaved(5, 2); len(m) != 2 || m[0] != 0x15 || m[1] != 0x0A {
+		t.Fatalf("interleaved %X", m)
+	}
+	if m := payload.RtpFecMaskConsecutive(5, 2); len(m) != 2 || m[0] != 0x07 || m[1] != 0x18 {
+		t.Fatalf("consecutive %X", m)
+	}
+}
+
+func TestRtpFecUlpfecRed(t *testing.T) {
+	var sink paddingPayload
+	jitter := fecTestReceiver(&sink)
+	decoder := payload.NewRtpUlpfecDecoder(jitter, 117, 116)
+	// 5 media packets + 2 FEC packets per frame
+	network := &fecTestNetwork{decoder: decoder, drop: map[int]bool{1: true, 2: true, 8: true}}
+	encoder := payload.NewRtpUlpfecEncoder(network, 117, 116)
+	encoder.SetProtection(2, nil)
+	var packer payload.RtpPackH264
+	packer.Init(1000, 96, 1000, 0x1234, encoder, nil)
+
+	var nalus [][]byte
+	for i := 0; i < 2; i++ {
+		nalu := pcapTestNalu(4500, byte(i))
+		nalus = append(nalus, nalu)
+		if err := packer.Input(append([]byte{0, 0, 0, 1}, nalu...), len(nalu)+4, uint32(3000*i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(network.packets) != 14 || encoder.Packets != 4 {
+		t.Fatalf("packets %d, fec %d", len(network.packets), encoder.Packets)
+	}
+	if seq, ts := encoder.GetInfo(); seq != 1014 || ts != 3000 {
+		t.Fatalf("info %d %d", seq, ts)
+	}
+
+	// RED encapsulated, media and FEC share sequence numbers
+	for i, pkt := range network.packets {
+		var v rtp.RtpPacketView
+		if err := rtp.RtpPacketViewParse(&v, pkt, len(pkt)); err != nil {
+			t.Fatal(err)
+		}
+		blocks, err := payload.RtpRedParse(v.Payload())
+		if err != nil || len(blocks) != 1 {
+			t.Fatal(err)
+		}
+		fec := i%7 >= 5
+		if v.PayloadType() != 116 || v.SequenceNumber() != uint16(1000+i) || v.SSRC() != 0x1234 || (blocks[0].Payload == 117) != fec {
+			t.Fatalf("packet %d header %+v, block %d", i, v.Header(), blocks[0].Payload)
+		}
+	}
+
+	if jitter.jb.Flush(); decoder.Recovered != 3 || len(sink.frames) != 2 {
+		t.Fatalf("recovered %d, frames %d", decoder.Recovered, len(sink.frames))
+	}
+	for i := range nalus {
+		if !bytes.Equal(sink.frames[i], nalus[i]) {
+			t.Fatalf("frame %d", i)
+		}
+	}
+}
+
+// packer -> ULPFEC -> history -> network, NACK wire sequence numbers of renumbered media
+func TestRtpFecHistory(t *testing.T) {
+	var sink paddingPayload
+	jitter := fecTestReceiver(&sink)
+	decoder := payload.NewRtpUlpfecDecoder(jitter, 117, -1)
+	network := &fecTestNetwork{decoder: decoder, drop: map[int]bool{8: true}}
+	history := payload.NewRtpPacketHistory(network, 32)
+	encoder := payload.NewRtpUlpfecEncoder(history, 117, -1)
+	encoder.SetProtection(2, nil)
+	var packer payload.RtpPackH264
+	packer.Init(1000, 96, 1000, 0x1234, encoder, nil)
+	for i := 0; i < 2; i++ {
+		nalu := pcapTestNalu(4500, byte(i))
+		if err := packer.Input(append([]byte{0, 0, 0, 1}, nalu...), len(nalu)+4, uint32(3000*i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the 2nd media packet of frame 2, packer seq 1006, wire seq 1008
+	if rtp.RtpReadUint16(network.packets[8][rtp.RtpHeader_SeqNumOffset:]) != 1008 {
+		t.Fatalf("seq %d", rtp.RtpReadUint16(network.packets[8][rtp.RtpHeader_SeqNumOffset:]))
+	}
+	if history.OnNack(&rtp.RtcpNack{Lost: []uint16{1008}}, time.Unix(1000, 0), 0) != 1 ||
+		len(network.packets) != 15 || !bytes.Equal(network.packets[14], network.packets[8]) {
+		t.Fatalf("retransmitted %d, packets %d", history.Retransmitted, len(network.packets))
+	}
+}
+
+func TestRtpFecFlexfec(t *testing.T) {
+	var sink paddingPayload
+	jitter := fecTestReceiver(&sink)
+	decoder := payload.NewRtpFlexfecDecoder(jitter, 118, 0x5678)
+	// frame 0: 52 media packets, FEC after 48 and after marker; frame 1/2: 4 media packets
+	network := &fecTestNetwork{decoder: decoder, drop: map[int]bool{47: true, 50: true, 55: true}}
+	encoder := payload.NewRtpFlexfecEncoder(network, 118, 500, 0x5678)
+	encoder.SetProtection(1, payload.RtpFecMaskConsecutive)
+	var packer payload.RtpPackH264
+	packer.Init(112, 96, 65530, 0x1234, encoder, nil)
+
+	nalus := [][]byte{pcapTestNalu(5000, 0), pcapTestNalu(300, 1), pcapTestNalu(300, 2)}
+	for i, nalu := range nalus {
+		if i == 2 {
+			encoder.SetProtection(0, nil) // frame without FEC
+		}
+		if err := packer.Input(append([]byte{0, 0, 0, 1}, nalu...), len(nalu)+4, uint32(3000*i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if encoder.Packets != 3 || len(network.packets) != 63 {
+		t.Fatalf("fec %d, packets %d", encoder.Packets, len(network.packets))
+	}
+
+	var v rtp.RtpPacketView
+	fec := network.packets[48]
+	if err := rtp.RtpPacketViewParse(&v, fec, len(fec)); err != nil {
+		t.Fatal(err)
+	}
+	if v.PayloadType() != 118 || v.SequenceNumber() != 500 || v.SSRC() != 0x5678 || v.CC() != 1 || v.CSRC(0) != 0x1234 ||
+		rtp.RtpReadUint16(v.Payload()[8:]) != 65530 {
+		t.Fatalf("flexfec header %+v", v.Header())
+	}
+
+	// FlexFEC payload type from unknown SSRC is not media
+	other := append([]byte(nil), fec...)
+	rtp.RtpWriteUint32(other[rtp.RtpHeader_SsrcOffset:], 0x9999)
+	if n, err := decoder.Input(other, len(other)); n != 0 || err != nil || decoder.Discard != 1 {
+		t.Fatalf("other ssrc %d, discard %d, %v", n, decoder.Discard, err)
+	}
+
+	if jitter.jb.Flush(); decoder.Recovered != 3 || len(sink.frames) != 3 {
+		t.Fatalf("recovered %d, frames %d", decoder.Recovered, len(sink.frames))
+	}
+	for i := range nalus {
+		if !bytes.Equal(sink.frames[i], nalus[i]) {
+			t.Fatalf("frame %d", i)
+		}
+	}
+}
+
+func TestRtpFecSdp(t *testing.T) {
+	de, err := payload.RtpPayloadCreate(96, "H264", 0, 0x1234, 1000, &nopPayload{}, &nopPayload{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := de.RtpPayloadPackerSdp(5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoder := payload.NewRtpFlexfecEncoder(&nopPayload{}, 118, 0, 0x5678)
+	encoder.SetRepairWindow(200000)
+	if err = encoder.Sdp(m); err != nil {
+		t.Fatal(err)
+	}
+	m.Attributes = append(m.Attributes, sdp.SdpAttribute{Name: "ssrc-group", Value: "FEC-FR 4660 22136"})
+	text := m.String()
+	if !bytes.Contains([]byte(text), []byte("a=rtpmap:118 flexfec/90000\r\na=fmtp:118 repair-window=200000\r\n")) {
+		t.Fatalf("sdp %s", text)
+	}
+	medias, err := sdp.SdpParse("v=0\r\n" + text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoder, err := payload.RtpFecDecoderSdp(medias[0], &seqUnpacker{}); err != nil || decoder == nil {
+		t.Fatal(err)
+	}
+}
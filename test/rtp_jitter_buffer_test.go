@@ -0,0 +1,147 @@
+package test
+
+import (
+	"github.com/services-go/librtp/payload"
+	"github.com/services-go/librtp/rtp"
+	"testing"
+	"time"
+)
+
+// seqUnpacker record sequence numbers in release order
+type seqUnpacker struct {
+	seqs []uint16
+}
+
+func (up *seqUnpacker) Init(handler payload.RtpPayload, param interface{}) {
+}
+
+func (up *seqUnpacker) Destroy() {
+}
+
+func (up *seqUnpacker) Input(packet []byte, bytes int) (int, error) {
+	up.seqs = append(up.seqs, rtp.RtpReadUint16(packet[rtp.RtpHeader_SeqNumOffset:]))
+	return 1, nil
+}
+
+func jitterTestPacket(seq uint16) []byte {
+	var pkt rtp.RtpPacket
+	pkt.Header.Version = rtp.RtpVersion
+	pkt.Header.PayloadType = 96
+	pkt.Header.SequenceNumber = seq
+	pkt.Header.SSRC = 0x1234
+	pkt.Payload = []byte{0x01}
+	pkt.PayloadLen = 1
+	data := make([]byte, 64)
+	n, _ := rtp.RtpPacketSerialize(&pkt, data, len(data))
+	return data[:n]
+}
+
+func jitterTestCheck(t *testing.T, up *seqUnpacker, seqs ...uint16) {
+	t.Helper()
+	if len(up.seqs) != len(seqs) {
+		t.Fatalf("released %v, expected %v", up.seqs, seqs)
+	}
+	for i := range seqs {
+		if up.seqs[i] != seqs[i] {
+			t.Fatalf("released %v, expected %v", up.seqs, seqs)
+		}
+	}
+}
+
+func TestRtpJitterBufferReorder(t *testing.T) {
+	var up seqUnpacker
+	jb := payload.NewRtpJitterBuffer(&up, 100*time.Millisecond, 0)
+	now := time.Unix(1000, 0)
+	input := func(seq uint16) {
+		pkt := jitterTestPacket(seq)
+		if err := jb.Input(pkt, len(pkt), now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// wraparound and reorder
+	input(65534)
+	input(0)
+	input(65535)
+	input(65535) // already released
+	jitterTestCheck(t, &up, 65534, 65535, 0)
+	if jb.Late != 1 || jb.Len() != 0 {
+		t.Fatalf("late %d, held %d", jb.Late, jb.Len())
+	}
+
+	// 1 missing, wait latency
+	input(2)
+	input(3)
+	input(2)
+	if jb.Duplicate != 1 {
+		t.Fatalf("duplicate %d", jb.Duplicate)
+	}
+	if jb.Len() != 2 || !jb.Next().Equal(now.Add(100*time.Millisecond)) {
+		t.Fatalf("held %d, next %v", jb.Len(), jb.Next())
+	}
+	now = now.Add(99 * time.Millisecond)
+	jb.Poll(now)
+	jitterTestCheck(t, &up, 65534, 65535, 0)
+	now = now.Add(time.Millisecond)
+	jb.Poll(now)
+	jitterTestCheck(t, &up, 65534, 65535, 0, 2, 3)
+	if jb.Lost != 1 {
+		t.Fatalf("lost %d", jb.Lost)
+	}
+
+	input(1) // too late
+	if jb.Late != 2 {
+		t.Fatalf("late %d", jb.Late)
+	}
+}
+
+func TestRtpJitterBufferDepth(t *testing.T) {
+	var up seqUnpacker
+	jb := payload.NewRtpJitterBuffer(&up, 0, 2)
+	now := time.Unix(1000, 0)
+	for _, seq := range []uint16{10, 12, 13, 14, 11} {
+		pkt := jitterTestPacket(seq)
+		jb.Input(pkt, len(pkt), now)
+	}
+	jitterTestCheck(t, &up, 10, 12, 13, 14)
+	if jb.Lost != 1 || jb.Late != 1 {
+		t.Fatalf("lost %d, late %d", jb.Lost, jb.Late)
+	}
+
+	// sequence number jump, re-sync after two sequential packets
+	for _, seq := range []uint16{30000, 30001, 30002} {
+		pkt := jitterTestPacket(seq)
+		jb.Input(pkt, len(pkt), now)
+	}
+	jitterTestCheck(t, &up, 10, 12, 13, 14, 30001, 30002)
+	if jb.Bad != 1 {
+		t.Fatalf("bad %d", jb.Bad)
+	}
+}
+
+func TestRtpJitterBufferRestart(t *testing.T) {
+	var sink paddingPayload
+	var unpacker payload.RtpCommUnpack
+	unpacker.Init(&sink, &sink)
+	jb := payload.NewRtpJitterBuffer(&unpacker, 0, 0)
+	now := time.Unix(1000, 0)
+	for _, seq := range []uint16{10, 11, 30000, 30001, 30002} {
+		pkt := jitterTestPacket(seq)
+		if err := jb.Input(pkt, len(pkt), now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 30000 discarded by the jitter buffer, 30001 restart the unpacker
+	if len(sink.frames) != 4 || jb.Bad != 1 {
+		t.Fatalf("frames %d, bad %d", len(sink.frames), jb.Bad)
+	}
+	for i, lost := range []bool{false, false, true, false} {
+		if (sink.flags[i]&payload.RTP_PAYLOAD_FLAG_PACKET_LOST != 0) != lost {
+			t.Fatalf("frame %d flags %d", i, sink.flags[i])
+		}
+	}
+	if unpacker.Source().MaxSeq() != 30002 {
+		t.Fatalf("unpacker max seq %d", unpacker.Source().MaxSeq())
+	}
+}
@@ -0,0 +1,64 @@
+package test
+
+import (
+	"github.com/services-go/librtp/rtp"
+	"testing"
+	"time"
+)
+
+func TestRtpMemberTable(t *testing.T) {
+	now := time.Unix(1000, 0)
+	table := rtp.NewRtpMemberTable(0x1234, []byte("self@host"), now)
+
+	var pkt rtp.RtpPacket
+	pkt.Header.SSRC = 0xABCD
+	pkt.Header.SequenceNumber = 100
+	if _, err := table.OnRtp(&pkt, "10.0.0.2:5000", now); err != nil {
+		t.Fatal(err)
+	}
+	if table.Members() != 1 || table.Senders() != 0 {
+		t.Fatalf("probation: members %d senders %d", table.Members(), table.Senders())
+	}
+	pkt.Header.SequenceNumber++
+	if _, err := table.OnRtp(&pkt, "10.0.0.2:5000", now); err != nil {
+		t.Fatal(err)
+	}
+	if table.Members() != 2 || table.Senders() != 1 {
+		t.Fatalf("validated: members %d senders %d", table.Members(), table.Senders())
+	}
+
+	// third-party collision
+	if _, err := table.OnRtp(&pkt, "10.0.0.3:5000", now); err != rtp.ErrRtpSsrcCollision {
+		t.Fatalf("expect collision: %v", err)
+	}
+
+	// own ssrc from network: collision first, then loop
+	pkt.Header.SSRC = 0x1234
+	if _, err := table.OnRtp(&pkt, "10.0.0.9:5000", now); err != rtp.ErrRtpSsrcCollision {
+		t.Fatalf("expect collision: %v", err)
+	}
+	if _, err := table.OnRtp(&pkt, "10.0.0.9:5000", now); err != rtp.ErrRtpSsrcLoop {
+		t.Fatalf("expect loop: %v", err)
+	}
+
+	sdes := &rtp.RtcpSdes{Chunks: []rtp.RtcpSdesChunk{{SSRC: 0x5555, Items: []rtp.RtcpSdesItem{{Type: rtp.RTCP_SDES_CNAME, Text: []byte("peer")}}}}}
+	if err := table.OnRtcp([]rtp.RtcpPacket{&rtp.RtcpRR{SSRC: 0x5555}, sdes}, "10.0.0.4:5001", now); err != nil {
+		t.Fatal(err)
+	}
+	if m := table.Find(0x5555); m == nil || string(m.CNAME()) != "peer" || table.Members() != 3 {
+		t.Fatalf("sdes member %+v", m)
+	}
+
+	if err := table.OnRtcp([]rtp.RtcpPacket{&rtp.RtcpBye{SSRC: []uint32{0x5555}}}, "10.0.0.4:5001", now); err != nil {
+		t.Fatal(err)
+	}
+	if table.Find(0x5555) != nil || table.Members() != 2 {
+		t.Fatal("bye member not removed")
+	}
+
+	// sender timeout after 2T, member timeout after 5Td
+	removed := table.Timeout(now.Add(30*time.Second), 5*time.Second, 5*time.Second)
+	if len(removed) != 1 || removed[0] != 0xABCD || table.Members() != 1 || table.Senders() != 0 {
+		t.Fatalf("timeout removed %v members %d senders %d", removed, table.Members(), table.Senders())
+	}
+}
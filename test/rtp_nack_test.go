@@ -0,0 +1,200 @@
+package test
+
+import (
+	"bytes"
+	"github.com/services-go/librtp/payload"
+	"github.com/services-go/librtp/rtp"
+	"testing"
+	"time"
+)
+
+func nackTestEqual(a, b []uint16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRtcpNack(t *testing.T) {
+	nack := &rtp.RtcpNack{SenderSSRC: 0x1111, MediaSSRC: 0x2222, Lost: []uint16{65530, 65535, 0, 5, 30}}
+	if nack.PacketSize() != 4+8+2*4 {
+		t.Fatalf("size %d", nack.PacketSize())
+	}
+
+	rr := &rtp.RtcpRR{SSRC: 0x1111}
+	buf := make([]byte, 256)
+	n, err := rtp.RtcpCompoundSerialize([]rtp.RtcpPacket{rr, nack}, buf, len(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[rr.PacketSize():rr.PacketSize()+4], []byte{0x81, rtp.RTCP_RTPFB, 0x00, 0x04}) {
+		t.Fatalf("header %X", buf[rr.PacketSize():rr.PacketSize()+4])
+	}
+	pkts, err := rtp.RtcpCompoundDeserialize(buf, n)
+	if err != nil || len(pkts) != 2 {
+		t.Fatal(pkts, err)
+	}
+	nack2, ok := pkts[1].(*rtp.RtcpNack)
+	if !ok || nack2.SenderSSRC != 0x1111 || nack2.MediaSSRC != 0x2222 || !nackTestEqual(nack2.Lost, nack.Lost) {
+		t.Fatalf("nack %+v", pkts[1])
+	}
+
+	if _, err = (&rtp.RtcpNack{}).Serialize(buf, len(buf)); err == nil {
+		t.Fatal("empty nack")
+	}
+}
+
+func TestRtpNackGenerator(t *testing.T) {
+	g := rtp.NewRtpNackGenerator()
+	g.MaxRetries = 2
+	now := time.Unix(1000, 0)
+	rtt := 50 * time.Millisecond
+	for _, seq := range []uint16{65534, 65535, 2} {
+		g.Input(seq, now)
+	}
+	if lost := g.Nack(now, rtt); !nackTestEqual(lost, []uint16{0, 1}) {
+		t.Fatalf("lost %v", lost)
+	}
+	if lost := g.Nack(now.Add(10*time.Millisecond), rtt); lost != nil {
+		t.Fatalf("within rtt %v", lost)
+	}
+	if !g.Input(0, now.Add(20*time.Millisecond)) || g.Input(0, now.Add(20*time.Millisecond)) {
+		t.Fatal("recovered")
+	}
+
+	nack := g.Feedback(0x1111, 0x2222, now.Add(60*time.Millisecond), rtt)
+	if nack == nil || !nackTestEqual(nack.Lost, []uint16{1}) {
+		t.Fatalf("retry %v", nack)
+	}
+	if lost := g.Nack(now.Add(120*time.Millisecond), rtt); lost != nil || g.Missing() != 0 || g.Expired != 1 || g.Recovered != 1 || g.Requested != 3 {
+		t.Fatalf("max retries %v, missing %d, expired %d", lost, g.Missing(), g.Expired)
+	}
+
+	// too old
+	g.Input(5, now)
+	if g.Missing() != 2 || g.Nack(now.Add(2*time.Second), rtt) != nil || g.Missing() != 0 {
+		t.Fatalf("max age %d", g.Missing())
+	}
+}
+
+func TestRtpNackGeneratorLate(t *testing.T) {
+	g := rtp.NewRtpNackGenerator()
+	now := time.Unix(1000, 0)
+	g.Input(849, now)
+	for seq := uint16(851); seq <= 1000; seq++ {
+		g.Input(seq, now)
+	}
+	if lost := g.Nack(now, 0); !nackTestEqual(lost, []uint16{850}) {
+		t.Fatalf("lost %v", lost)
+	}
+
+	// retransmission 150 packets behind
+	if !g.Input(850, now.Add(300*time.Millisecond)) || g.Recovered != 1 || g.Missing() != 0 {
+		t.Fatalf("recovered %d, missing %d", g.Recovered, g.Missing())
+	}
+	if g.Input(1001, now.Add(300*time.Millisecond)) || g.Missing() != 0 {
+		t.Fatalf("missing %d", g.Missing())
+	}
+
+	// a single stray late packet, no resync
+	g.Input(700, now)
+	g.Input(1003, now)
+	if lost := g.Nack(now.Add(time.Second), 0); !nackTestEqual(lost, []uint16{1002}) {
+		t.Fatalf("stray %v", lost)
+	}
+
+	// two sequential out-of-window packets, source restarted
+	g.Input(30000, now)
+	if g.Missing() != 1 {
+		t.Fatalf("missing %d", g.Missing())
+	}
+	g.Input(30001, now)
+	g.Input(30003, now)
+	if lost := g.Nack(now.Add(time.Second), 0); g.Expired != 1 || !nackTestEqual(lost, []uint16{30002}) {
+		t.Fatalf("restart %v, expired %d", lost, g.Expired)
+	}
+}
+
+type nackTestNetwork struct {
+	jb     *payload.RtpJitterBuffer
+	gen    *rtp.RtpNackGenerator
+	now    time.Time
+	drop   map[uint16]bool
+	sent   int
+	resent int
+}
+
+func (n *nackTestNetwork) Alloc(param interface{}, bytes int) []byte {
+	return make([]byte, bytes)
+}
+
+func (n *nackTestNetwork) Free(param interface{}, packet []byte) {
+}
+
+func (n *nackTestNetwork) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
+	n.sent++
+	if flags&payload.RTP_PAYLOAD_FLAG_RETRANSMISSION != 0 {
+		n.resent++
+	}
+	seq := rtp.RtpReadUint16(packet[rtp.RtpHeader_SeqNumOffset:])
+	if n.drop[seq] {
+		delete(n.drop, seq) // lost once
+		return
+	}
+	n.gen.Input(seq, n.now)
+	n.jb.Input(packet, bytes, n.now)
+}
+
+func TestRtpPacketHistory(t *testing.T) {
+	var sink paddingPayload
+	var unpacker payload.RtpUnpackH264
+	unpacker.Init(&sink, &sink)
+	network := &nackTestNetwork{
+		jb:   payload.NewRtpJitterBuffer(&unpacker, 200*time.Millisecond, 0),
+		gen:  rtp.NewRtpNackGenerator(),
+		now:  time.Unix(1000, 0),
+		drop: map[uint16]bool{2: true, 3: true, 7: true},
+	}
+	history := payload.NewRtpPacketHistory(network, 8)
+	var packer payload.RtpPackH264
+	packer.Init(1000, 96, 0, 0x1234, history, nil)
+
+	var nalus [][]byte
+	for i := 0; i < 3; i++ {
+		nalu := pcapTestNalu(3500, byte(i)) // 4 packets
+		nalus = append(nalus, nalu)
+		if err := packer.Input(append([]byte{0, 0, 0, 1}, nalu...), len(nalu)+4, uint32(3000*i)); err != nil {
+			t.Fatal(err)
+		}
+
+		// sender answer receiver feedback
+		network.now = network.now.Add(10 * time.Millisecond)
+		if nack := network.gen.Feedback(0x5678, 0x1234, network.now, 20*time.Millisecond); nack != nil {
+			history.OnNack(nack, network.now, 20*time.Millisecond)
+		}
+	}
+	if network.jb.Flush(); len(sink.frames) != 3 || network.gen.Recovered != 3 || network.resent != 3 {
+		t.Fatalf("frames %d, recovered %d, resent %d", len(sink.frames), network.gen.Recovered, network.resent)
+	}
+	for i := range nalus {
+		if !bytes.Equal(sink.frames[i], nalus[i]) {
+			t.Fatalf("frame %d", i)
+		}
+	}
+
+	// throttle and bounded history
+	if history.OnNack(&rtp.RtcpNack{Lost: []uint16{7}}, network.now, 20*time.Millisecond) != 0 || history.Throttled != 1 {
+		t.Fatalf("throttled %d", history.Throttled)
+	}
+	if _, _, ok := history.Get(0); ok || history.OnNack(&rtp.RtcpNack{Lost: []uint16{0}}, network.now, 0) != 0 || history.Missed != 1 {
+		t.Fatalf("missed %d", history.Missed)
+	}
+	if data, _, ok := history.Get(11); !ok || rtp.RtpReadUint16(data[2:]) != 11 {
+		t.Fatal("history get")
+	}
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"bytes"
+	"github.com/services-go/librtp/payload"
+	"github.com/services-go/librtp/rtp"
+	"testing"
+)
+
+// packTestPayload keep a copy of each packed RTP packet
+type packTestPayload struct {
+	packets [][]byte
+}
+
+func (p *packTestPayload) Alloc(param interface{}, bytes int) []byte {
+	return make([]byte, bytes)
+}
+
+func (p *packTestPayload) Free(param interface{}, packet []byte) {
+}
+
+func (p *packTestPayload) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
+	p.packets = append(p.packets, append([]byte(nil), packet[:bytes]...))
+}
+
+func TestRtpPackH264FuA(t *testing.T) {
+	nalu := make([]byte, 1000)
+	nalu[0] = 0x65 // IDR slice
+	for i := 1; i < len(nalu); i++ {
+		nalu[i] = byte(i)
+	}
+
+	var network packTestPayload
+	var packer payload.RtpPackH264
+	packer.Init(400, 96, 100, 0x1234, &network, nil)
+	if err := packer.Input(append([]byte{0, 0, 0, 1}, nalu...), 4+len(nalu), 3000); err != nil {
+		t.Fatal(err)
+	}
+	if len(network.packets) < 3 {
+		t.Fatalf("packets %d", len(network.packets))
+	}
+
+	// FU header follow FU indicator, RTP header M/PT untouched
+	data := nalu[:1]
+	for i, pkt := range network.packets {
+		marker := byte(0)
+		fuHeader := byte(0x05)
+		if i == 0 {
+			fuHeader |= payload.FU_START_264
+		}
+		if i == len(network.packets)-1 {
+			fuHeader |= payload.FU_END_264
+			marker = 0x80
+		}
+		if pkt[1] != marker|96 || pkt[rtp.RtpFixedHeader] != 0x7C || pkt[rtp.RtpFixedHeader+1] != fuHeader {
+			t.Fatalf("packet %d: %x %x %x", i, pkt[1], pkt[rtp.RtpFixedHeader], pkt[rtp.RtpFixedHeader+1])
+		}
+		data = append(data, pkt[rtp.RtpFixedHeader+payload.N_FU_HEADER_264:]...)
+	}
+	if !bytes.Equal(data, nalu) {
+		t.Fatal("fu-a payload mismatch")
+	}
+}
+
+func TestRtpCommPack(t *testing.T) {
+	data := make([]byte, 300)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	// handler from Init
+	var network packTestPayload
+	var packer payload.RtpCommPack
+	packer.Init(200, 8, 10, 0x1234, &network, nil)
+	if err := packer.Input(data, len(data), 160); err != nil {
+		t.Fatal(err)
+	}
+	if len(network.packets) != 2 {
+		t.Fatalf("packets %d", len(network.packets))
+	}
+	var out []byte
+	for _, pkt := range network.packets {
+		out = append(out, pkt[rtp.RtpFixedHeader:]...)
+	}
+	if !bytes.Equal(out, data) {
+		t.Fatal("payload mismatch")
+	}
+}
@@ -0,0 +1,130 @@
+package test
+
+import (
+	"bytes"
+	"github.com/services-go/librtp/payload"
+	"github.com/services-go/librtp/rtp"
+	"testing"
+)
+
+type paddingPayload struct {
+	packets [][]byte
+	frames  [][]byte
+	flags   []int
+}
+
+func (h *paddingPayload) Alloc(param interface{}, bytes int) []byte {
+	return make([]byte, bytes)
+}
+
+func (h *paddingPayload) Free(param interface{}, packet []byte) {
+}
+
+func (h *paddingPayload) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
+	if param != nil {
+		h.frames = append(h.frames, append([]byte(nil), packet[:bytes]...))
+		h.flags = append(h.flags, flags)
+	} else {
+		h.packets = append(h.packets, packet[:bytes])
+	}
+}
+
+func TestRtpPacketPadding(t *testing.T) {
+	var packer payload.RtpPackH264
+	var sink paddingPayload
+	packer.Init(200, 96, 1000, 0x1234, &sink, nil)
+	if err := packer.SetPadding(16); err != nil {
+		t.Fatal(err)
+	}
+
+	nalu := make([]byte, 1000)
+	nalu[0] = 0x65
+	for i := 1; i < len(nalu); i++ {
+		nalu[i] = byte(i)
+	}
+	if err := packer.Input(append([]byte{0, 0, 0, 1}, nalu...), len(nalu)+4, 3000); err != nil {
+		t.Fatal(err)
+	}
+	if err := packer.Probe(300); err != nil {
+		t.Fatal(err)
+	}
+	if err := packer.Input(append([]byte{0, 0, 0, 1}, nalu...), len(nalu)+4, 6000); err != nil {
+		t.Fatal(err)
+	}
+
+	var unpacker payload.RtpUnpackH264
+	unpacker.Init(&sink, &sink)
+	probes := 0
+	for _, pkt := range sink.packets {
+		if len(pkt) > 200 {
+			t.Fatalf("packet size %d", len(pkt))
+		}
+
+		var v rtp.RtpPacketView
+		if err := rtp.RtpPacketViewParse(&v, pkt, len(pkt)); err != nil {
+			t.Fatal(err)
+		}
+		if len(v.Payload()) == 0 {
+			probes++
+		} else if len(pkt)%16 != 0 {
+			t.Fatalf("packet size %d not aligned", len(pkt))
+		}
+		if _, err := unpacker.Input(pkt, len(pkt)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// probes consume sequence numbers, not a packet loss
+	if probes != 2 || len(sink.frames) != 2 || !bytes.Equal(sink.frames[0], nalu) || !bytes.Equal(sink.frames[1], nalu) ||
+		sink.flags[1]&payload.RTP_PAYLOAD_FLAG_PACKET_LOST != 0 {
+		t.Fatalf("probes %d frames %d flags %v", probes, len(sink.frames), sink.flags)
+	}
+
+	// probe between audio frames
+	var comm payload.RtpCommPack
+	var audio paddingPayload
+	comm.Init(200, 0, 65535, 0x1234, &audio, nil)
+	for i, ts := range []uint32{160, 320} {
+		if err := comm.Input(nalu[:160], 160, ts); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if err := comm.Probe(100); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	var commUnpacker payload.RtpCommUnpack
+	commUnpacker.Init(&audio, &audio)
+	for _, pkt := range audio.packets {
+		if _, err := commUnpacker.Input(pkt, len(pkt)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(audio.packets) != 3 || len(audio.frames) != 2 || audio.flags[1]&payload.RTP_PAYLOAD_FLAG_PACKET_LOST != 0 {
+		t.Fatalf("packets %d frames %d flags %v", len(audio.packets), len(audio.frames), audio.flags)
+	}
+
+	// serializer honor the P bit
+	var pkt rtp.RtpPacket
+	pkt.Header.Version = rtp.RtpVersion
+	pkt.Header.Padding = 1
+	pkt.Padlen = 4
+	pkt.Payload = []byte{1, 2, 3}
+	pkt.PayloadLen = 3
+	buf := make([]byte, 64)
+	n, err := rtp.RtpPacketSerialize(&pkt, buf, len(buf))
+	if err != nil || n != 12+3+4 || buf[n-1] != 4 {
+		t.Fatalf("serialize padding %d %v", n, err)
+	}
+	var pkt2 rtp.RtpPacket
+	if err = rtp.RtpPacketDeserialize(&pkt2, buf, n); err != nil || pkt2.PayloadLen != 3 || pkt2.Padlen != 4 {
+		t.Fatalf("deserialize padding %+v %v", pkt2, err)
+	}
+
+	// P bit without padding length
+	pkt.Padlen = 0
+	if n, err = rtp.RtpPacketSerialize(&pkt, buf, len(buf)); err == nil {
+		t.Fatalf("serialize P bit without padding %d", n)
+	}
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"github.com/services-go/librtp/payload"
+	"github.com/services-go/librtp/rtp"
+	"testing"
+	"time"
+)
+
+type syncFrame struct {
+	ssrc  uint32
+	pts   time.Duration
+	flags int
+}
+
+type syncPayload struct {
+	frames []syncFrame
+}
+
+func (h *syncPayload) Handle(param interface{}, ssrc uint32, frame []byte, bytes int, pts time.Duration, timestamp uint32, flags int) {
+	h.frames = append(h.frames, syncFrame{ssrc: ssrc, pts: pts, flags: flags})
+}
+
+func TestRtpLipSync(t *testing.T) {
+	const video, audio = 0x1111, 0x2222
+	base := time.Unix(1600000000, 0)
+	videoClock := rtp.NewRtpClock(90000, base, func() uint32 { return 0xFFFFF000 })
+	audioClock := rtp.NewRtpClock(44100, base, func() uint32 { return 1000 })
+
+	sync := payload.NewRtpLipSync()
+	sync.AddStream(video, 90000)
+	sync.AddStream(audio, 44100)
+
+	var h syncPayload
+	vh := sync.Handler(video, &h)
+	ah := sync.Handler(audio, &h)
+	frame := []byte{0x65}
+
+	// no SR yet, stream local timeline
+	vh.Handle(nil, frame, 1, videoClock.Timestamp(base), 0)
+	vh.Handle(nil, frame, 1, videoClock.Timestamp(base.Add(40*time.Millisecond)), 0)
+	if f := h.frames[1]; f.flags&payload.RTP_PAYLOAD_FLAG_NOT_SYNCED == 0 || f.pts != 40*time.Millisecond {
+		t.Fatalf("unsynced %+v", f)
+	}
+
+	// both streams report at the same wall-clock
+	var vsr, asr rtp.RtcpSR
+	vsr.SSRC, asr.SSRC = video, audio
+	videoClock.SenderInfo(&vsr, base.Add(time.Second))
+	audioClock.SenderInfo(&asr, base.Add(time.Second))
+	cname := []rtp.RtcpSdesItem{{Type: rtp.RTCP_SDES_CNAME, Text: []byte("camera@example.com")}}
+	sdes := &rtp.RtcpSdes{Chunks: []rtp.RtcpSdesChunk{{SSRC: video, Items: cname}, {SSRC: audio, Items: cname}}}
+	sync.OnRtcp([]rtp.RtcpPacket{&vsr, &asr})
+
+	// audio is 300ms behind video
+	h.frames = nil
+	vh.Handle(nil, frame, 1, videoClock.Timestamp(base.Add(2*time.Second)), 0)
+	ah.Handle(nil, frame, 1, audioClock.Timestamp(base.Add(1700*time.Millisecond)), 0)
+	if len(h.frames) != 2 || h.frames[0].flags != 0 || h.frames[0].pts != 0 || h.frames[1].pts != -300*time.Millisecond {
+		t.Fatalf("frames %+v", h.frames)
+	}
+	if _, err := sync.Offset(audio, video); err == nil {
+		t.Fatal("offset without cname")
+	}
+	sync.OnRtcp([]rtp.RtcpPacket{sdes})
+	offset, err := sync.Offset(audio, video)
+	if err != nil || offset != 300*time.Millisecond {
+		t.Fatalf("offset %v, %v", offset, err)
+	}
+
+	sync.AddStream(0x3333, 8000)
+	if _, err = sync.Offset(audio, 0x3333); err == nil {
+		t.Fatal("cname mismatch")
+	}
+}
@@ -3,6 +3,7 @@ package test
 import (
 	"fmt"
 	"github.com/services-go/librtp/payload"
+	"github.com/services-go/librtp/transport"
 	"log"
 	"os"
 	"strings"
@@ -18,6 +19,7 @@ type RtpPayloadTest struct {
 
 	frtp2    *os.File
 	fsource2 *os.File
+	wrtp2    *transport.RtpTcpWriter
 
 	payloadDe *payload.RtpPayloadDelegate
 
@@ -38,13 +40,8 @@ func (p *payloadPacker) Free(param interface{}, packet []byte) {
 }
 
 func (p *payloadPacker) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
-	size := make([]byte, 2)
-	size[0] = byte(bytes >> 8)
-	size[1] = byte(bytes)
-
 	ctx, _ := param.(*RtpPayloadTest)
-	ctx.frtp2.Write(size)
-	ctx.frtp2.Write(packet)
+	ctx.wrtp2.WritePacket(packet, bytes)
 }
 
 func (up *payloadUnpacker) Alloc(param interface{}, bytes int) []byte {
@@ -117,6 +114,7 @@ func rtpPayloadTest(pt int, encoding string, seq uint16, ssrc uint32, rtpfile st
 
 	}
 	defer ctx.frtp2.Close()
+	ctx.wrtp2 = transport.NewRtpTcpWriter(ctx.frtp2)
 
 	ctx.fsource2, err = os.OpenFile("out.media", os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
 	if err != nil {
@@ -133,23 +131,16 @@ func rtpPayloadTest(pt int, encoding string, seq uint16, ssrc uint32, rtpfile st
 		return
 	}
 
-	ctx.packet = make([]byte, 1024*1024)
+	r := transport.NewRtpTcpReader(ctx.frtp)
 	for {
-		sz := make([]byte, 2)
-		n, err := ctx.frtp.Read(sz)
+		ctx.packet, err = r.ReadPacket()
 		if err != nil {
 			log.Println(err.Error())
 			break
 		}
 
-		ctx.size = int(sz[0])<<8 | int(sz[1])
-
-		fmt.Printf("size = %d, sz[0] = %x sz[1] = %x\n", ctx.size, sz[0], sz[1])
-		n, err = ctx.frtp.Read(ctx.packet[:ctx.size])
-		if err != nil || n != ctx.size {
-			log.Println(err)
-			break
-		}
+		ctx.size = len(ctx.packet)
+		fmt.Printf("size = %d\n", ctx.size)
 		_, err = ctx.payloadDe.RtpPayloadUnpackerInput(ctx.packet, ctx.size)
 		if err != nil {
 			log.Println(err)
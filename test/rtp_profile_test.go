@@ -0,0 +1,55 @@
+package test
+
+import (
+	"github.com/services-go/librtp/payload"
+	"github.com/services-go/librtp/rtp"
+	"testing"
+	"time"
+)
+
+func TestRtpProfileTable(t *testing.T) {
+	if p := rtp.RtpProfileFind(rtp.RTP_PAYLOAD_PCMA); p == nil || p.EncodingName() != "PCMA" || p.Frequency != 8000 {
+		t.Fatalf("static PCMA %+v", p)
+	}
+	if p := rtp.RtpProfileFind(19); p != nil {
+		t.Fatalf("reserved payload %+v", p)
+	}
+	if p := rtp.RtpProfileFindByName("pcmu"); p == nil || p.Payload != 0 {
+		t.Fatalf("case insensitive name %+v", p)
+	}
+
+	profiles := rtp.NewRtpProfileTable()
+	if err := profiles.Register(111, "opus", 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := profiles.Register(120, "MPEG4-GENERIC", 44100, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := profiles.Register(121, "mpeg4-generic", 0, 0); err == nil {
+		t.Fatal("aac registered without clock rate")
+	}
+	if p := rtp.RtpProfileFindByName("AAC"); p == nil || p.Frequency != 0 {
+		t.Fatalf("aac default clock rate %+v", p)
+	}
+	if err := profiles.Register(8, "PCMA", 8000, 1); err == nil {
+		t.Fatal("static payload registered")
+	}
+	if p := profiles.Find(111); p == nil || p.Frequency != 48000 || p.Channels != 2 || p.Avtype != rtp.RTP_AVTYPE_AUDIO {
+		t.Fatalf("opus %+v", p)
+	}
+	if p := profiles.FindByName("h264"); p != nil {
+		t.Fatalf("unregistered dynamic %+v", p)
+	}
+
+	var h nopPayload
+	de, err := payload.RtpPayloadCreateProfile(profiles, 120, 0, 0x1234, 1400, &h, &h, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := de.Packer.(*payload.RtpPackMpeg4Generic); !ok {
+		t.Fatalf("packer %T", de.Packer)
+	}
+	if ts := de.RtpPayloadTimestamp(20 * time.Millisecond); ts != 882 {
+		t.Fatalf("timestamp %d", ts)
+	}
+}
@@ -0,0 +1,157 @@
+package test
+
+import (
+	"bytes"
+	"github.com/services-go/librtp/payload"
+	"github.com/services-go/librtp/rtp"
+	"github.com/services-go/librtp/sdp"
+	"testing"
+)
+
+// redTestFrame audio frames from RtpCommUnpack
+type redTestFrame struct {
+	timestamp uint32
+	flags     int
+	data      []byte
+}
+
+type redTestSink struct {
+	frames []redTestFrame
+}
+
+func (s *redTestSink) Alloc(param interface{}, bytes int) []byte {
+	return make([]byte, bytes)
+}
+
+func (s *redTestSink) Free(param interface{}, packet []byte) {
+}
+
+func (s *redTestSink) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
+	s.frames = append(s.frames, redTestFrame{timestamp: timestamp, flags: flags, data: append([]byte(nil), packet[:bytes]...)})
+}
+
+func redTestAudio(i int) []byte {
+	frame := make([]byte, 160)
+	for j := range frame {
+		frame[j] = byte(i + j)
+	}
+	return frame
+}
+
+func TestRtpRed(t *testing.T) {
+	var network paddingPayload
+	red := payload.NewRtpRedPacker(&network, 121, 2)
+	var packer payload.RtpCommPack
+	packer.Init(1200, 0, 100, 0x1234, red, nil)
+	for i := 0; i < 8; i++ {
+		frame := redTestAudio(i)
+		if err := packer.Input(frame, len(frame), uint32(160*(i+1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(network.packets) != 8 {
+		t.Fatalf("packets %d", len(network.packets))
+	}
+
+	var v rtp.RtpPacketView
+	if err := rtp.RtpPacketViewParse(&v, network.packets[2], len(network.packets[2])); err != nil {
+		t.Fatal(err)
+	}
+	blocks, err := payload.RtpRedParse(v.Payload())
+	if err != nil || v.PayloadType() != 121 || v.SequenceNumber() != 102 || len(blocks) != 3 {
+		t.Fatalf("red header %+v, blocks %d, %v", v.Header(), len(blocks), err)
+	}
+	if blocks[0].TimestampOffset != 320 || blocks[1].TimestampOffset != 160 || blocks[2].Payload != 0 ||
+		!bytes.Equal(blocks[0].Data, redTestAudio(0)) || !bytes.Equal(blocks[2].Data, redTestAudio(2)) {
+		t.Fatalf("red blocks %+v", blocks[:2])
+	}
+
+	// 2 lost: restored; 3 lost: the oldest one lost
+	var sink redTestSink
+	unpacker := payload.NewRtpRedUnpacker(&payload.RtpCommUnpack{}, 121)
+	unpacker.Init(&sink, nil)
+	for _, i := range []int{0, 3, 7} {
+		if _, err = unpacker.Input(network.packets[i], len(network.packets[i])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if unpacker.Recovered != 4 || len(sink.frames) != 7 {
+		t.Fatalf("recovered %d, frames %d", unpacker.Recovered, len(sink.frames))
+	}
+	for j, i := range []int{0, 1, 2, 3, 5, 6, 7} {
+		f := sink.frames[j]
+		lost := i == 5
+		if f.timestamp != uint32(160*(i+1)) || !bytes.Equal(f.data, redTestAudio(i)) || (f.flags&payload.RTP_PAYLOAD_FLAG_PACKET_LOST != 0) != lost {
+			t.Fatalf("frame %d timestamp %d, flags %d", i, f.timestamp, f.flags)
+		}
+	}
+
+	// late packet, redundant blocks not used
+	if _, err = unpacker.Input(network.packets[4], len(network.packets[4])); err != nil || unpacker.Recovered != 4 {
+		t.Fatalf("recovered %d, %v", unpacker.Recovered, err)
+	}
+}
+
+func TestRtpRedSkippedBlock(t *testing.T) {
+	var network paddingPayload
+	red := payload.NewRtpRedPacker(&network, 121, 2)
+	var packer payload.RtpCommPack
+	packer.Init(1500, 0, 10, 0x1234, red, nil)
+	frames := [][]byte{redTestAudio(0), make([]byte, 1100), redTestAudio(2)} // B too large for a redundant block
+	for i, frame := range frames {
+		if err := packer.Input(frame, len(frame), uint32(160*(i+1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A not carried in C once B is skipped
+	var v rtp.RtpPacketView
+	rtp.RtpPacketViewParse(&v, network.packets[2], len(network.packets[2]))
+	if blocks, err := payload.RtpRedParse(v.Payload()); err != nil || len(blocks) != 1 {
+		t.Fatalf("blocks %d, %v", len(blocks), err)
+	}
+
+	// B lost, C carry A(e.g. other sender): A not delivered again
+	blocks := []payload.RtpRedBlock{{Payload: 0, TimestampOffset: 320, Data: frames[0]}, {Payload: 0, Data: frames[2]}}
+	pkt := make([]byte, rtp.RtpFixedHeader+payload.RtpRedSize(blocks))
+	copy(pkt, network.packets[2][:rtp.RtpFixedHeader])
+	payload.RtpRedWrite(blocks, pkt[rtp.RtpFixedHeader:])
+
+	var sink redTestSink
+	unpacker := payload.NewRtpRedUnpacker(&payload.RtpCommUnpack{}, 121)
+	unpacker.Init(&sink, nil)
+	for _, p := range [][]byte{network.packets[0], pkt} {
+		if _, err := unpacker.Input(p, len(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if unpacker.Recovered != 0 || len(sink.frames) != 2 || sink.frames[1].timestamp != 480 ||
+		sink.frames[1].flags&payload.RTP_PAYLOAD_FLAG_PACKET_LOST == 0 {
+		t.Fatalf("recovered %d, frames %+v", unpacker.Recovered, sink.frames)
+	}
+}
+
+func TestRtpRedSdp(t *testing.T) {
+	de, err := payload.RtpPayloadCreate(0, "PCMU", 0, 0x1234, 1200, &nopPayload{}, &nopPayload{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := de.RtpPayloadPackerSdp(5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = payload.NewRtpRedPacker(&nopPayload{}, 121, 2).Sdp(m); err != nil {
+		t.Fatal(err)
+	}
+	text := m.String()
+	if !bytes.Contains([]byte(text), []byte("RTP/AVP 121 0\r\n")) || !bytes.Contains([]byte(text), []byte("a=rtpmap:121 red/8000\r\na=fmtp:121 0/0/0\r\n")) {
+		t.Fatalf("sdp %s", text)
+	}
+	medias, err := sdp.SdpParse("v=0\r\n" + text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = payload.RtpRedUnpackerSdp(medias[0], &seqUnpacker{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"bytes"
+	"github.com/services-go/librtp/payload"
+	"github.com/services-go/librtp/rtp"
+	"github.com/services-go/librtp/sdp"
+	"testing"
+	"time"
+)
+
+func TestRtpRtx(t *testing.T) {
+	var media, rtx paddingPayload
+	history := payload.NewRtpPacketHistory(&media, 64)
+	de, err := payload.RtpPayloadCreate(96, "H264", 1000, 0x1234, 1000, history, &media, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rtxPacker := de.RtpPayloadRtxPacker(97, 500, 0x5678, &rtx)
+	rtxPacker.SetRtxTime(3000)
+
+	nalu := pcapTestNalu(2500, 0)
+	if err = de.RtpPayloadPackerInput(append([]byte{0, 0, 0, 1}, nalu...), len(nalu)+4, 3000); err != nil {
+		t.Fatal(err)
+	}
+	if len(media.packets) != 3 {
+		t.Fatalf("packets %d", len(media.packets))
+	}
+	if n := history.Retransmit([]uint16{1002, 1001}, time.Unix(1000, 0), 0, rtxPacker); n != 2 {
+		t.Fatalf("retransmit %d", n)
+	}
+	if seq, ts := rtxPacker.GetInfo(); seq != 502 || ts != 3000 || len(rtx.packets) != 2 {
+		t.Fatalf("rtx info %d %d, packets %d", seq, ts, len(rtx.packets))
+	}
+
+	var v rtp.RtpPacketView
+	if err = rtp.RtpPacketViewParse(&v, rtx.packets[0], len(rtx.packets[0])); err != nil {
+		t.Fatal(err)
+	}
+	if v.PayloadType() != 97 || v.SequenceNumber() != 500 || v.SSRC() != 0x5678 || v.Timestamp() != 3000 || v.Marker() != 1 ||
+		rtp.RtpReadUint16(v.Payload()) != 1002 || !bytes.Equal(v.Payload()[2:], media.packets[2][rtp.RtpFixedHeader:]) {
+		t.Fatalf("rtx header %+v", v.Header())
+	}
+
+	// SDP rtpmap/apt association
+	m, err := de.RtpPayloadPackerSdp(5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = rtxPacker.Sdp(m); err != nil {
+		t.Fatal(err)
+	}
+	m.Attributes = append(m.Attributes, sdp.SdpAttribute{Name: "ssrc-group", Value: "FID 4660 22136"})
+	text := m.String()
+	if !bytes.Contains([]byte(text), []byte("a=rtpmap:97 rtx/90000\r\na=fmtp:97 apt=96;rtx-time=3000\r\n")) {
+		t.Fatalf("sdp %s", text)
+	}
+	medias, err := sdp.SdpParse("v=0\r\n" + text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var up seqUnpacker
+	unpacker, err := payload.RtpRtxUnpackerSdp(medias[0], &up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkt := range [][]byte{media.packets[0], rtx.packets[1], rtx.packets[0]} {
+		if _, err = unpacker.Input(pkt, len(pkt)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jitterTestCheck(t, &up, 1000, 1001, 1002)
+	data, n, err := unpacker.Unwrap(rtx.packets[0], len(rtx.packets[0]))
+	if err != nil || !bytes.Equal(data[:n], media.packets[2]) || unpacker.Unwrapped != 3 {
+		t.Fatalf("unwrap %X, %v", data[:n], err)
+	}
+
+	// unknown RTX SSRC
+	unpacker.Associate(0x5678, 0x1234)
+	rtp.RtpWriteUint32(rtx.packets[0][8:], 0x9999)
+	if n, err := unpacker.Input(rtx.packets[0], len(rtx.packets[0])); n != 0 || err != nil || unpacker.Discard != 1 {
+		t.Fatalf("discard %d, %v", unpacker.Discard, err)
+	}
+}
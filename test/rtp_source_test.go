@@ -0,0 +1,150 @@
+package test
+
+import (
+	"bytes"
+	"github.com/services-go/librtp/payload"
+	"github.com/services-go/librtp/rtp"
+	"testing"
+)
+
+// sourceTestPayload keep packed RTP packets(param nil) and unpacked frames
+type sourceTestPayload struct {
+	packets [][]byte
+	frames  [][]byte
+	flags   []int
+}
+
+func (h *sourceTestPayload) Alloc(param interface{}, bytes int) []byte {
+	return make([]byte, bytes)
+}
+
+func (h *sourceTestPayload) Free(param interface{}, packet []byte) {
+}
+
+func (h *sourceTestPayload) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
+	if param != nil {
+		h.frames = append(h.frames, append([]byte(nil), packet[:bytes]...))
+		h.flags = append(h.flags, flags)
+	} else {
+		h.packets = append(h.packets, packet[:bytes])
+	}
+}
+
+func TestRtpSourceSequence(t *testing.T) {
+	var s rtp.RtpSource
+	s.Start(65534, 0)
+
+	seqs := []uint16{65534, 65535, 0, 2, 1, 3, 3}
+	expect := []int{rtp.RTP_SEQ_OK, rtp.RTP_SEQ_OK, rtp.RTP_SEQ_OK, rtp.RTP_SEQ_LOST, rtp.RTP_SEQ_LATE, rtp.RTP_SEQ_OK, rtp.RTP_SEQ_LATE}
+	for i, seq := range seqs {
+		if r := s.Update(seq); r != expect[i] {
+			t.Fatalf("seq %d: got %d, expect %d", seq, r, expect[i])
+		}
+	}
+	if s.ExtendedMax() != 65536+3 || s.Expected() != 6 || s.Received() != 7 || s.Lost() != -1 {
+		t.Fatalf("extmax %d expected %d received %d lost %d", s.ExtendedMax(), s.Expected(), s.Received(), s.Lost())
+	}
+
+	// two sequential packets after a large jump restart the source
+	if r := s.Update(20000); r != rtp.RTP_SEQ_BAD {
+		t.Fatalf("expect bad: %d", r)
+	}
+	if r := s.Update(20001); r != rtp.RTP_SEQ_RESTART {
+		t.Fatalf("expect restart: %d", r)
+	}
+
+	// probation
+	s.Start(100, rtp.RtpMinSequential)
+	if s.Update(100) != rtp.RTP_SEQ_PROBATION || s.Update(101) != rtp.RTP_SEQ_OK || !s.Valid() {
+		t.Fatal("probation failed")
+	}
+}
+
+func TestRtpSourceStartZero(t *testing.T) {
+	var s rtp.RtpSource
+	s.Start(0, 0)
+	for _, seq := range []uint16{0, 1, 2} {
+		if r := s.Update(seq); r != rtp.RTP_SEQ_OK {
+			t.Fatalf("seq %d: got %d", seq, r)
+		}
+	}
+	if s.ExtendedMax() != 2 || s.Expected() != 3 || s.Lost() != 0 {
+		t.Fatalf("extmax %d expected %d lost %d", s.ExtendedMax(), s.Expected(), s.Lost())
+	}
+
+	// first packet after a gap still counted in the current cycle
+	s.Start(0, 0)
+	if r := s.Update(2); r != rtp.RTP_SEQ_LOST || s.ExtendedMax() != 2 || s.Lost() != 2 {
+		t.Fatalf("got %d, extmax %d lost %d", r, s.ExtendedMax(), s.Lost())
+	}
+}
+
+func TestRtpUnpackH264Sequence(t *testing.T) {
+	nalu := make([]byte, 1000)
+	nalu[0] = 0x65
+	for i := 1; i < len(nalu); i++ {
+		nalu[i] = byte(i)
+	}
+	slice := []byte{0x41, 1, 2, 3}
+
+	// FU-A 65534, 65535, 0 and single NAL unit 1
+	var network sourceTestPayload
+	var packer payload.RtpPackH264
+	packer.Init(400, 96, 65534, 0x1234, &network, nil)
+	if err := packer.Input(append([]byte{0, 0, 0, 1}, nalu...), len(nalu)+4, 3000); err != nil {
+		t.Fatal(err)
+	}
+	if err := packer.Input(append([]byte{0, 0, 0, 1}, slice...), len(slice)+4, 6000); err != nil {
+		t.Fatal(err)
+	}
+	if len(network.packets) != 4 {
+		t.Fatalf("packets %d", len(network.packets))
+	}
+
+	// duplicate 65535 inside the wrapped FU-A, late 65535 after 1
+	var sink sourceTestPayload
+	var unpacker payload.RtpUnpackH264
+	unpacker.Init(&sink, &sink)
+	for _, i := range []int{0, 1, 1, 2, 3, 1} {
+		if _, err := unpacker.Input(network.packets[i], len(network.packets[i])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(sink.frames) != 2 || !bytes.Equal(sink.frames[0], nalu) || !bytes.Equal(sink.frames[1], slice) ||
+		sink.flags[0] != 0 || sink.flags[1] != 0 {
+		t.Fatalf("frames %d flags %v", len(sink.frames), sink.flags)
+	}
+	if s := unpacker.Source(); s.ExtendedMax() != 65536+1 || s.Lost() != -2 {
+		t.Fatalf("extmax %d lost %d", s.ExtendedMax(), s.Lost())
+	}
+}
+
+func TestRtpUnpackCommSequence(t *testing.T) {
+	var network sourceTestPayload
+	var packer payload.RtpCommPack
+	packer.Init(200, 0, 65535, 0x1234, &network, nil)
+	for i := 1; i <= 5; i++ {
+		frame := bytes.Repeat([]byte{byte(i)}, 160)
+		if err := packer.Input(frame, len(frame), uint32(160*i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 65535, 0, duplicate 0, late 65535, 1, 2 lost, 3
+	var sink sourceTestPayload
+	var unpacker payload.RtpCommUnpack
+	unpacker.Init(&sink, &sink)
+	for _, i := range []int{0, 1, 1, 0, 2, 4} {
+		if _, err := unpacker.Input(network.packets[i], len(network.packets[i])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(sink.frames) != 4 || sink.frames[2][0] != 3 || sink.frames[3][0] != 5 {
+		t.Fatalf("frames %d", len(sink.frames))
+	}
+	for i, flags := range sink.flags {
+		if lost := flags&payload.RTP_PAYLOAD_FLAG_PACKET_LOST != 0; lost != (i == 3) {
+			t.Fatalf("frame %d flags %d", i, flags)
+		}
+	}
+}
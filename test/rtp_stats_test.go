@@ -0,0 +1,51 @@
+package test
+
+import (
+	"github.com/services-go/librtp/rtp"
+	"testing"
+	"time"
+)
+
+func TestRtpReceiverStats(t *testing.T) {
+	r := rtp.NewRtpReceiver(8000)
+	now := time.Unix(2000, 0)
+
+	var pkt rtp.RtpPacket
+	pkt.Header.SSRC = 0x1111
+	// 20ms packets, every 10th packet lost, arrival jitter alternates 0/+5ms
+	for i := 0; i < 100; i++ {
+		if i%10 == 5 {
+			continue
+		}
+		pkt.Header.SequenceNumber = uint16(65500 + i)
+		pkt.Header.Timestamp = uint32(i * 160)
+		arrival := now.Add(time.Duration(i) * 20 * time.Millisecond)
+		if i%2 == 1 {
+			arrival = arrival.Add(5 * time.Millisecond)
+		}
+		r.Input(&pkt, arrival)
+	}
+
+	sr := &rtp.RtcpSR{SSRC: 0x1111, NTPMSW: 0x00012345, NTPLSW: 0x67890000}
+	r.OnRtcp([]rtp.RtcpPacket{sr}, now.Add(time.Second))
+
+	reports := r.Reports(now.Add(3*time.Second), rtp.RtcpMaxReportSize)
+	if len(reports) != 1 {
+		t.Fatalf("reports %d", len(reports))
+	}
+	report := reports[0]
+	if report.ExtSeq != 65536+63 || report.Lost != 10 || report.Fraction != 10*256/99 {
+		t.Fatalf("report %+v", report)
+	}
+	if report.Jitter < 30 || report.Jitter > 40 {
+		t.Fatalf("jitter %d, expect about 40(5ms@8kHz)", report.Jitter)
+	}
+	if report.LSR != 0x23456789 || report.DLSR != 2*65536 {
+		t.Fatalf("lsr %x dlsr %d", report.LSR, report.DLSR)
+	}
+
+	// no packet since last report
+	if reports = r.Reports(now.Add(4*time.Second), rtp.RtcpMaxReportSize); len(reports) != 0 {
+		t.Fatalf("unexpected reports %d", len(reports))
+	}
+}
@@ -0,0 +1,97 @@
+package test
+
+import (
+	"bytes"
+	"github.com/services-go/librtp/transport"
+	"io"
+	"testing"
+)
+
+func TestRtpTcpFraming(t *testing.T) {
+	var buf bytes.Buffer
+	w := transport.NewRtpTcpWriter(&buf)
+	packets := [][]byte{{0x80, 0x60, 0x00, 0x01}, {}, bytes.Repeat([]byte{0xAB}, 1500)}
+	for _, pkt := range packets {
+		if err := w.WritePacket(pkt, len(pkt)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.WritePacket(make([]byte, 0x10000), 0x10000); err == nil {
+		t.Fatal("oversize packet")
+	}
+	if buf.Len() != 3*transport.RtpTcpHeaderLength+4+1500 {
+		t.Fatalf("framed %d", buf.Len())
+	}
+
+	r := transport.NewRtpTcpReader(bytes.NewReader(buf.Bytes()))
+	for i, pkt := range packets {
+		data, err := r.ReadPacket()
+		if err != nil || !bytes.Equal(data, pkt) {
+			t.Fatalf("packet %d: %v", i, err)
+		}
+	}
+	if _, err := r.ReadPacket(); err != io.EOF {
+		t.Fatalf("eof %v", err)
+	}
+
+	r = transport.NewRtpTcpReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	r.ReadPacket()
+	r.ReadPacket()
+	if _, err := r.ReadPacket(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("truncated %v", err)
+	}
+}
+
+type rtspInterleavedHandler struct {
+	rtp, rtcp, rtsp []string
+}
+
+func (h *rtspInterleavedHandler) OnRtp(param interface{}, channel int, packet []byte, bytes int) {
+	h.rtp = append(h.rtp, string(packet[:bytes]))
+}
+
+func (h *rtspInterleavedHandler) OnRtcp(param interface{}, channel int, packet []byte, bytes int) {
+	h.rtcp = append(h.rtcp, string(packet[:bytes]))
+}
+
+func (h *rtspInterleavedHandler) OnRtsp(param interface{}, message []byte) {
+	h.rtsp = append(h.rtsp, string(message))
+}
+
+func TestRtspInterleaved(t *testing.T) {
+	rtp := []byte{0x80, 0x60, 0x00, 0x01, 0, 0, 0, 0, 0, 0, 0, 1}
+	rtcp := []byte{0x80, 0xC9, 0x00, 0x01, 0, 0, 0, 1}
+	response := "RTSP/1.0 200 OK\r\nCSeq: 5\r\nContent-Length: 4\r\n\r\nbody"
+
+	var buf bytes.Buffer
+	w := transport.NewRtspInterleavedWriter(&buf)
+	w.WriteFrame(0, rtp, len(rtp))
+	w.WriteFrame(1, rtcp, len(rtcp))
+	buf.WriteString(response)
+	w.WriteFrame(2, rtp, len(rtp))   // rtcp-mux
+	w.WriteFrame(2, rtcp, len(rtcp)) // rtcp-mux
+	w.WriteFrame(4, rtp, len(rtp))   // unbound
+	buf.WriteString("RTSP/1.0 200 OK\r\nCSeq: 6\r\n\r\n")
+	if err := w.WriteFrame(256, rtp, len(rtp)); err == nil {
+		t.Fatal("invalid channel")
+	}
+
+	var h rtspInterleavedHandler
+	d := transport.NewRtspInterleavedDemuxer(bytes.NewReader(buf.Bytes()), &h, nil)
+	d.Bind(0, 1)
+	d.Bind(2, 2)
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.rtp) != 2 || h.rtp[1] != string(rtp) || len(h.rtcp) != 2 || h.rtcp[1] != string(rtcp) || d.Discard != 1 {
+		t.Fatalf("rtp %d, rtcp %d, discard %d", len(h.rtp), len(h.rtcp), d.Discard)
+	}
+	if len(h.rtsp) != 2 || h.rtsp[0] != response {
+		t.Fatalf("rtsp %q", h.rtsp)
+	}
+
+	d = transport.NewRtspInterleavedDemuxer(bytes.NewReader(buf.Bytes()[:6]), &h, nil)
+	if err := d.Run(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("truncated %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package test
+
+import (
+	"bytes"
+	"github.com/services-go/librtp/payload"
+	"github.com/services-go/librtp/transport"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type rtpUdpCollector struct {
+	mutex  sync.Mutex
+	frames [][]byte
+	rtp    int
+	rtcp   int
+}
+
+func (c *rtpUdpCollector) Alloc(param interface{}, bytes int) []byte {
+	return make([]byte, bytes)
+}
+
+func (c *rtpUdpCollector) Free(param interface{}, packet []byte) {
+}
+
+func (c *rtpUdpCollector) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
+	c.mutex.Lock()
+	c.frames = append(c.frames, append([]byte(nil), packet[:bytes]...))
+	c.mutex.Unlock()
+}
+
+func (c *rtpUdpCollector) OnRtp(param interface{}, packet []byte, bytes int, from net.Addr) {
+	c.mutex.Lock()
+	c.rtp++
+	c.mutex.Unlock()
+}
+
+func (c *rtpUdpCollector) OnRtcp(param interface{}, packet []byte, bytes int, from net.Addr) {
+	c.mutex.Lock()
+	c.rtcp++
+	c.mutex.Unlock()
+}
+
+func (c *rtpUdpCollector) wait(frames, rtcp int) bool {
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		c.mutex.Lock()
+		done := len(c.frames) >= frames && c.rtcp >= rtcp
+		c.mutex.Unlock()
+		if done {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRtpUdpTransport(t *testing.T) {
+	for _, mux := range []bool{false, true} {
+		sender, err := transport.NewRtpUdpTransport("127.0.0.1", 0, mux)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sender.Close()
+		receiver, err := transport.NewRtpUdpTransport("127.0.0.1", 0, mux)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rtp, rtcp := receiver.LocalAddr()
+		if (!mux && (rtp.Port%2 != 0 || rtcp.Port != rtp.Port+1)) || (mux && rtcp.Port != rtp.Port) {
+			t.Fatalf("mux %v port pair %d/%d", mux, rtp.Port, rtcp.Port)
+		}
+		if err = sender.SetDSCP(46); err != nil {
+			t.Fatal(err)
+		}
+		sender.SetRemote(rtp, nil)
+
+		var c rtpUdpCollector
+		tx, err := payload.RtpPayloadCreate(96, "H264", 0, 0x1234, 1200, sender, &c, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rx, _ := payload.RtpPayloadCreate(96, "H264", 0, 0x1234, 1200, &c, &c, nil)
+		done := make(chan error, 1)
+		go func() {
+			done <- receiver.Serve(&transport.RtpUdpPayloadHandler{Delegate: rx, Rtcp: &c}, nil)
+		}()
+
+		nalu := pcapTestNalu(3000, 1)
+		for i := 0; i < 2; i++ {
+			tx.RtpPayloadPackerInput(append([]byte{0, 0, 0, 1}, nalu...), len(nalu)+4, uint32(3000*i))
+		}
+		report := []byte{0x80, 0xC9, 0x00, 0x01, 0x00, 0x00, 0x12, 0x34}
+		if err = sender.WriteRtcp(report, len(report)); err != nil || sender.Err != nil {
+			t.Fatal(err, sender.Err)
+		}
+		if !c.wait(2, 1) || !bytes.Equal(c.frames[0], nalu) {
+			t.Fatalf("mux %v frames %d rtcp %d", mux, len(c.frames), c.rtcp)
+		}
+
+		receiver.Close()
+		if err = <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := transport.NewRtpUdpTransport("127.0.0.1", 5001, false); err == nil {
+		t.Fatal("odd rtp port")
+	}
+}
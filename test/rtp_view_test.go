@@ -0,0 +1,105 @@
+package test
+
+import (
+	"github.com/services-go/librtp/payload"
+	"github.com/services-go/librtp/rtp"
+	"testing"
+)
+
+type nopPayload struct {
+	frames int
+}
+
+func (h *nopPayload) Alloc(param interface{}, bytes int) []byte {
+	return nil
+}
+
+func (h *nopPayload) Free(param interface{}, packet []byte) {
+}
+
+func (h *nopPayload) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
+	h.frames++
+}
+
+func rtpViewTestPacket() []byte {
+	var pkt rtp.RtpPacket
+	pkt.Header.Version = rtp.RtpVersion
+	pkt.Header.PayloadType = 96
+	pkt.Header.SSRC = 0x12345678
+	pkt.Header.CSRC = 2
+	pkt.CSRC = []uint32{1, 2}
+	pkt.SetExtension(1, []byte{0x80})
+	pkt.Payload = make([]byte, 1000)
+	pkt.Payload[0] = 0x65 // IDR slice
+	pkt.PayloadLen = len(pkt.Payload)
+
+	buf := make([]byte, 1500)
+	n, _ := rtp.RtpPacketSerialize(&pkt, buf, len(buf))
+	return buf[:n]
+}
+
+func TestRtpPacketView(t *testing.T) {
+	data := rtpViewTestPacket()
+
+	var v rtp.RtpPacketView
+	if err := rtp.RtpPacketViewParse(&v, data, len(data)); err != nil {
+		t.Fatal(err)
+	}
+	var pkt rtp.RtpPacket
+	if err := rtp.RtpPacketDeserialize(&pkt, data, len(data)); err != nil {
+		t.Fatal(err)
+	}
+	if v.Header() != pkt.Header || v.CSRC(1) != 2 || len(v.Payload()) != pkt.PayloadLen || v.ExtensionProfile() != rtp.RtpExtensionOneByte {
+		t.Fatalf("view header %+v, packet header %+v", v.Header(), pkt.Header)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		rtp.RtpPacketViewParse(&v, data, len(data))
+	})
+	if allocs != 0 {
+		t.Fatalf("RtpPacketViewParse allocs %v", allocs)
+	}
+
+	var h nopPayload
+	var up payload.RtpUnpackH264
+	up.Init(&h, nil)
+	seq := uint16(0)
+	allocs = testing.AllocsPerRun(100, func() {
+		rtp.RtpWriteUint16(data[2:], seq)
+		seq++
+		up.Input(data, len(data))
+	})
+	if allocs != 0 || h.frames == 0 {
+		t.Fatalf("RtpUnpackH264 allocs %v frames %d", allocs, h.frames)
+	}
+}
+
+func BenchmarkRtpPacketViewParse(b *testing.B) {
+	data := rtpViewTestPacket()
+	var v rtp.RtpPacketView
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rtp.RtpPacketViewParse(&v, data, len(data))
+	}
+}
+
+func BenchmarkRtpPacketDeserialize(b *testing.B) {
+	data := rtpViewTestPacket()
+	var pkt rtp.RtpPacket
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rtp.RtpPacketDeserialize(&pkt, data, len(data))
+	}
+}
+
+func BenchmarkRtpUnpackH264(b *testing.B) {
+	data := rtpViewTestPacket()
+	var h nopPayload
+	var up payload.RtpUnpackH264
+	up.Init(&h, nil)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rtp.RtpWriteUint16(data[2:], uint16(i))
+		up.Input(data, len(data))
+	}
+}
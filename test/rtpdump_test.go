@@ -0,0 +1,61 @@
+package test
+
+import (
+	"bytes"
+	"github.com/services-go/librtp/capture"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRtpDump(t *testing.T) {
+	start := time.Unix(1700000000, 250000000)
+	header := &capture.RtpDumpHeader{Start: start, Address: net.IPv4(224, 2, 0, 1), Port: 3456}
+
+	var buf bytes.Buffer
+	w, err := capture.NewRtpDumpWriter(&buf, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rtp := srtpTestPacket(t, 1, []byte("rtpdump"))
+	rtcp := []byte{0x80, 0xC9, 0x00, 0x01, 0, 0, 0, 1}
+	if err = w.WritePacket(rtp, len(rtp), false, start.Add(20*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.WritePacket(rtcp, len(rtcp), true, start.Add(1500*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.WritePacket(rtp, len(rtp), false, start.Add(-time.Second)); err == nil {
+		t.Fatal("packet before start")
+	}
+
+	data := buf.Bytes()
+	text := "#!rtpplay1.0 224.2.0.1/3456\n"
+	if string(data[:len(text)]) != text || !bytes.Equal(data[len(text):len(text)+16], []byte{0x65, 0x53, 0xF1, 0x00, 0x00, 0x03, 0xD0, 0x90, 224, 2, 0, 1, 0x0D, 0x80, 0, 0}) {
+		t.Fatalf("header %X", data[:len(text)+16])
+	}
+
+	r, err := capture.NewRtpDumpReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Header().Start.Equal(start) || !r.Header().Address.Equal(header.Address) || r.Header().Port != 3456 {
+		t.Fatalf("header %v", r.Header())
+	}
+	pkt, err := r.ReadPacket()
+	if err != nil || pkt.Rtcp || pkt.Offset != 20*time.Millisecond || pkt.Length != len(rtp) || !bytes.Equal(pkt.Data, rtp) {
+		t.Fatalf("rtp %v, %v", pkt, err)
+	}
+	pkt, err = r.ReadPacket()
+	if err != nil || !pkt.Rtcp || pkt.Offset != 1500*time.Millisecond || !bytes.Equal(pkt.Data, rtcp) {
+		t.Fatalf("rtcp %v, %v", pkt, err)
+	}
+	if _, err = r.ReadPacket(); err != io.EOF {
+		t.Fatalf("eof %v", err)
+	}
+
+	if _, err = capture.NewRtpDumpReader(bytes.NewReader([]byte("RTSP/1.0 200 OK\r\n"))); err == nil {
+		t.Fatal("invalid magic")
+	}
+}
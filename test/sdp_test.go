@@ -0,0 +1,195 @@
+package test
+
+import (
+	"bytes"
+	"github.com/services-go/librtp/payload"
+	"github.com/services-go/librtp/sdp"
+	"testing"
+)
+
+const sdpTestText = "v=0\r\n" +
+	"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+	"s=test\r\n" +
+	"t=0 0\r\n" +
+	"a=extmap:1 urn:ietf:params:rtp-hdrext:sdes:mid\r\n" +
+	"m=video 5004 RTP/AVP 96\r\n" +
+	"a=rtpmap:96 H264/90000\r\n" +
+	"a=fmtp:96 packetization-mode=0; profile-level-id=42e01f\r\n" +
+	"a=ssrc:305419896 cname:user@example.com\r\n" +
+	"m=audio 5006 RTP/AVP 97 0\r\n" +
+	"a=rtpmap:97 mpeg4-generic/44100/2\r\n" +
+	"a=fmtp:97 streamtype=5;mode=AAC-hbr;SizeLength=16;IndexLength=0;IndexDeltaLength=0;config=1210\r\n" +
+	"a=ptime:20\r\n" +
+	"a=sendrecv\r\n" +
+	"m=audio 5008/2 RTP/SAVPF 111\r\n" +
+	"a=rtpmap:111 opus/48000/2\r\n" +
+	"a=extmap:2/sendonly http://www.webrtc.org/experiments/rtp-hdrext/abs-send-time\r\n"
+
+func TestSdpParse(t *testing.T) {
+	medias, err := sdp.SdpParse(sdpTestText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(medias) != 3 {
+		t.Fatalf("medias %d", len(medias))
+	}
+
+	video := medias[0]
+	if video.Media != "video" || video.Port != 5004 || video.Proto != "RTP/AVP" || len(video.Formats) != 1 {
+		t.Fatalf("video %+v", video)
+	}
+	if len(video.Ssrcs) != 1 || video.Ssrcs[0].SSRC != 0x12345678 || video.Ssrcs[0].Attribute != "cname" || video.Ssrcs[0].Value != "user@example.com" {
+		t.Fatalf("ssrc %+v", video.Ssrcs)
+	}
+	if v, ok := video.Fmtp(96).Get("Profile-Level-Id"); !ok || v != "42e01f" {
+		t.Fatalf("fmtp %+v", video.Fmtp(96))
+	}
+
+	audio := medias[1]
+	if audio.Ptime != 20 || len(audio.Formats) != 2 || audio.Rtpmap(0) != nil {
+		t.Fatalf("audio %+v", audio)
+	}
+	if r := audio.Rtpmap(97); r == nil || r.Encoding != "mpeg4-generic" || r.Frequency != 44100 || r.Channels != 2 {
+		t.Fatalf("rtpmap %+v", r)
+	}
+	if _, ok := audio.Attribute("sendrecv"); !ok {
+		t.Fatalf("attributes %+v", audio.Attributes)
+	}
+
+	opus := medias[2]
+	if opus.NumPorts != 2 || len(opus.Extmaps) != 2 {
+		t.Fatalf("opus %+v", opus)
+	}
+	if e := opus.Extmaps[1]; e.ID != 2 || e.Direction != "sendonly" {
+		t.Fatalf("extmap %+v", e)
+	}
+	if e := opus.Extmaps[0]; e.ID != 1 || e.URI != "urn:ietf:params:rtp-hdrext:sdes:mid" {
+		t.Fatalf("session extmap %+v", e)
+	}
+
+	if _, err = sdp.SdpParse("m=audio x RTP/AVP 0\r\n"); err == nil {
+		t.Fatal("bad port accepted")
+	}
+	if _, err = sdp.SdpParse("m=audio 0 RTP/AVP 96\r\na=rtpmap:96 opus\r\n"); err == nil {
+		t.Fatal("bad rtpmap accepted")
+	}
+}
+
+func TestRtpPayloadCreateSdp(t *testing.T) {
+	medias, err := sdp.SdpParse(sdpTestText)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tx, rx paddingPayload
+	opus, err := payload.RtpPayloadCreateSdp(medias[2], 111, 0, 0x1234, 1400, &tx, &rx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opus.Profile.Channels != 2 || opus.Profile.Frequency != 48000 {
+		t.Fatalf("opus %+v", opus.Profile)
+	}
+	if _, err = payload.RtpPayloadCreateSdp(medias[2], 96, 0, 0x1234, 1400, &tx, &rx, nil); err == nil {
+		t.Fatal("payload not in media")
+	}
+
+	// single NAL unit mode can't fragment
+	h264, err := payload.RtpPayloadCreateSdp(medias[0], 96, 0, 0x1234, 200, &tx, &rx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nalu := append([]byte{0, 0, 0, 1, 0x65}, make([]byte, 300)...)
+	nalu[len(nalu)-1] = 0xFF
+	if err = h264.RtpPayloadPackerInput(nalu, len(nalu), 3000); err == nil {
+		t.Fatal("single nal unit mode fragmented")
+	}
+
+	// 16-bits AU-size without AU-Index
+	aac, err := payload.RtpPayloadCreateSdp(medias[1], 97, 0, 0x1234, 1400, &tx, &rx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up := aac.Unpacker.(*payload.RtpUnpackMpeg4Generic); !bytes.Equal(up.Config(), []byte{0x12, 0x10}) {
+		t.Fatalf("config %x", up.Config())
+	}
+	tx.packets = nil
+	frame := bytes.Repeat([]byte{0x21}, 300)
+	if err = aac.RtpPayloadPackerInput(frame, len(frame), 1024); err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.packets) != 1 || tx.packets[0][12] != 0 || tx.packets[0][13] != 16 || tx.packets[0][14] != 0x01 || tx.packets[0][15] != 0x2C {
+		t.Fatalf("au header % x", tx.packets[0][12:16])
+	}
+	if _, err = aac.RtpPayloadUnpackerInput(tx.packets[0], len(tx.packets[0])); err != nil {
+		t.Fatal(err)
+	}
+	if len(rx.packets) != 1 || !bytes.Equal(rx.packets[0], frame) {
+		t.Fatalf("unpack %d", len(rx.packets))
+	}
+}
+
+func TestRtpPayloadPackerSdp(t *testing.T) {
+	var tx, rx paddingPayload
+	h264, err := payload.RtpPayloadCreate(96, "H264", 0, 0x1234, 1400, &tx, &rx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	au := []byte{0, 0, 0, 1, 0x67, 0x42, 0xe0, 0x1f, 0xda, 0x05, 0x07, 0xe4,
+		0, 0, 0, 1, 0x68, 0xce, 0x30, 0xa4, 0x80,
+		0, 0, 0, 1, 0x65, 0x88, 0x84, 0x21}
+	if err = h264.RtpPayloadPackerInput(au, len(au), 3000); err != nil {
+		t.Fatal(err)
+	}
+	m, err := h264.RtpPayloadPackerSdp(5004)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := m.String()
+	expected := "m=video 5004 RTP/AVP 96\r\n" +
+		"a=rtpmap:96 H264/90000\r\n" +
+		"a=fmtp:96 packetization-mode=1;profile-level-id=42e01f;sprop-parameter-sets=Z0LgH9oFB+Q=,aM4wpIA=\r\n"
+	if text != expected {
+		t.Fatalf("h264 sdp %q", text)
+	}
+
+	aac, err := payload.RtpPayloadCreate(97, "mpeg4-generic", 0, 0x1234, 1400, &tx, &rx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = aac.RtpPayloadPackerSdp(5006); err == nil {
+		t.Fatal("aac sdp without clock rate")
+	}
+
+	// ADTS: AAC LC, 44100Hz, 2 channels
+	frame := make([]byte, 7+100)
+	copy(frame, []byte{0xFF, 0xF1, 0x50, 0x80 | byte(len(frame)>>11), byte(len(frame) >> 3), byte(len(frame)&0x07)<<5 | 0x1F, 0xFC})
+	if err = aac.RtpPayloadPackerInput(frame, len(frame), 1024); err != nil {
+		t.Fatal(err)
+	}
+	if m, err = aac.RtpPayloadPackerSdp(5006); err != nil {
+		t.Fatal(err)
+	}
+	medias, err := sdp.SdpParse(m.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := medias[0].Rtpmap(97); r == nil || r.String() != "97 mpeg4-generic/44100/2" {
+		t.Fatalf("rtpmap %+v", r)
+	}
+	fmtp := medias[0].Fmtp(97)
+	if v, _ := fmtp.Get("mode"); v != "AAC-hbr" {
+		t.Fatalf("fmtp %s", fmtp.String())
+	}
+	if fmtp.String() != "97 streamType=5;profile-level-id=41;mode=AAC-hbr;sizeLength=13;indexLength=3;indexDeltaLength=3;config=1210" {
+		t.Fatalf("fmtp %s", fmtp.String())
+	}
+
+	// the receiver configured from the generated SDP
+	receiver, err := payload.RtpPayloadCreateSdp(medias[0], 97, 0, 0x5678, 1400, &tx, &rx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up := receiver.Unpacker.(*payload.RtpUnpackMpeg4Generic); !bytes.Equal(up.Config(), []byte{0x12, 0x10}) {
+		t.Fatalf("config %x", up.Config())
+	}
+}
@@ -0,0 +1,373 @@
+package test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"github.com/services-go/librtp/rtp"
+	"github.com/services-go/librtp/srtp"
+	"testing"
+)
+
+func srtpTestHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// RFC3711 B.2. AES-CM Test Vectors (p45)
+func TestSrtpAesCm(t *testing.T) {
+	key := srtpTestHex(t, "2B7E151628AED2A6ABF7158809CF4F3C")
+	salt := srtpTestHex(t, "F0F1F2F3F4F5F6F7F8F9FAFBFCFD")
+	keystream := make([]byte, 0x10000*16)
+	if err := srtp.SrtpAesCmXor(key, salt, 0, 0, keystream); err != nil {
+		t.Fatal(err)
+	}
+
+	vectors := []struct {
+		block  int
+		output string
+	}{
+		{0x0000, "E03EAD0935C95E80E166B16DD92B4EB4"},
+		{0x0001, "D23513162B02D0F72A43A2FE4A5F97AB"},
+		{0x0002, "41E95B3BB0A2E8DD477901E4FCA894C0"},
+		{0xFEFF, "EC8CDF7398607CB0F2D21675EA9EA1E4"},
+		{0xFF00, "362B7C3C6773516318A077D7FC5073AE"},
+		{0xFF01, "6A2CC3787889374FBEB4C81B17BA6C44"},
+	}
+	for _, v := range vectors {
+		if !bytes.Equal(keystream[v.block*16:v.block*16+16], srtpTestHex(t, v.output)) {
+			t.Fatalf("block %x: %X", v.block, keystream[v.block*16:v.block*16+16])
+		}
+	}
+}
+
+// RFC3711 B.3. Key Derivation Test Vectors (p46)
+func TestSrtpKeyDerive(t *testing.T) {
+	masterKey := srtpTestHex(t, "E1F97A0D3E018BE0D64FA32C06DE4139")
+	masterSalt := srtpTestHex(t, "0EC675AD498AFEEBB6960B3AABE6")
+
+	vectors := []struct {
+		label byte
+		key   string
+	}{
+		{srtp.SRTP_LABEL_RTP_ENCRYPTION, "C61E7A93744F39EE10734AFE3FF7A087"},
+		{srtp.SRTP_LABEL_RTP_SALT, "30CBBC08863D8C85D49DB34A9AE1"},
+		{srtp.SRTP_LABEL_RTP_AUTH, "CEBE321F6FF7716B6FD4AB49AF256A156D38BAA4"},
+	}
+	for _, v := range vectors {
+		expected := srtpTestHex(t, v.key)
+		key, err := srtp.SrtpKeyDerive(masterKey, masterSalt, v.label, len(expected))
+		if err != nil || !bytes.Equal(key, expected) {
+			t.Fatalf("label %d: %X, %v", v.label, key, err)
+		}
+	}
+}
+
+func srtpTestPacket(t *testing.T, seq uint16, payload []byte) []byte {
+	var pkt rtp.RtpPacket
+	pkt.Header.Version = rtp.RtpVersion
+	pkt.Header.PayloadType = 96
+	pkt.Header.SequenceNumber = seq
+	pkt.Header.Timestamp = uint32(seq) * 3000
+	pkt.Header.SSRC = 0xCAFEBABE
+	pkt.Payload = payload
+	pkt.PayloadLen = len(payload)
+	data := make([]byte, 1500)
+	n, err := rtp.RtpPacketSerialize(&pkt, data, len(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data[:n]
+}
+
+func TestSrtpProtect(t *testing.T) {
+	masterKey := srtpTestHex(t, "E1F97A0D3E018BE0D64FA32C06DE4139")
+	masterSalt := srtpTestHex(t, "0EC675AD498AFEEBB6960B3AABE6")
+	for _, profile := range []int{srtp.SRTP_AES128_CM_HMAC_SHA1_80, srtp.SRTP_AES128_CM_HMAC_SHA1_32} {
+		sender, err := srtp.NewSrtpContext(profile, masterKey, masterSalt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receiver, err := srtp.NewSrtpContext(profile, masterKey, masterSalt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		payload := []byte("srtp payload")
+		for _, seq := range []uint16{65534, 65535, 0, 1} {
+			plain := srtpTestPacket(t, seq, payload)
+			data := make([]byte, len(plain)+sender.Overhead())
+			copy(data, plain)
+			n, err := sender.Protect(data, len(plain))
+			if err != nil || n != len(plain)+sender.Overhead() {
+				t.Fatalf("protect %d, %v", n, err)
+			}
+			if bytes.Equal(data[rtp.RtpFixedHeader:len(plain)], payload) {
+				t.Fatal("payload not encrypted")
+			}
+			protected := append([]byte(nil), data[:n]...)
+
+			n, err = receiver.Unprotect(data, n)
+			if err != nil || !bytes.Equal(data[:n], plain) {
+				t.Fatalf("seq %d unprotect %v", seq, err)
+			}
+
+			// replay
+			copy(data, protected)
+			if _, err = receiver.Unprotect(data, len(protected)); err != srtp.ErrSrtpReplay {
+				t.Fatalf("replay %v", err)
+			}
+		}
+		if sender.ROC(0xCAFEBABE) != 1 || receiver.ROC(0xCAFEBABE) != 1 {
+			t.Fatalf("roc %d %d", sender.ROC(0xCAFEBABE), receiver.ROC(0xCAFEBABE))
+		}
+
+		// tampered
+		plain := srtpTestPacket(t, 2, payload)
+		data := make([]byte, len(plain)+sender.Overhead())
+		copy(data, plain)
+		n, _ := sender.Protect(data, len(plain))
+		data[rtp.RtpFixedHeader] ^= 0x01
+		if _, err = receiver.Unprotect(data, n); err != srtp.ErrSrtpAuth {
+			t.Fatalf("tampered %v", err)
+		}
+	}
+}
+
+// known answer with RFC3711 B.3 master key, same as libsrtp srtp_driver
+func TestSrtpKnownAnswer(t *testing.T) {
+	masterKey := srtpTestHex(t, "E1F97A0D3E018BE0D64FA32C06DE4139")
+	masterSalt := srtpTestHex(t, "0EC675AD498AFEEBB6960B3AABE6")
+	sender, err := srtp.NewSrtpContext(srtp.SRTP_AES128_CM_HMAC_SHA1_80, masterKey, masterSalt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver, err := srtp.NewSrtpContext(srtp.SRTP_AES128_CM_HMAC_SHA1_80, masterKey, masterSalt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := srtpTestHex(t, "800F1234DECAFBADCAFEBABEABABABABABABABABABABABABABABABAB")
+	data := make([]byte, 64)
+	copy(data, plain)
+	n, err := sender.Protect(data, len(plain))
+	if err != nil || !bytes.Equal(data[:n], srtpTestHex(t, "800F1234DECAFBADCAFEBABE4E55DC4CE79978D88CA4D215949D2402B78D6ACC99EA179B8DBB")) {
+		t.Fatalf("srtp %X, %v", data[:n], err)
+	}
+
+	// SRTCP index 1
+	plain = srtpTestHex(t, "81C8000BCAFEBABEABABABABABABABABABABABABABABABAB")
+	copy(data, plain)
+	sender.ProtectRtcp(data, len(plain))
+	copy(data, plain)
+	n, err = sender.ProtectRtcp(data, len(plain))
+	protected := srtpTestHex(t, "81C8000BCAFEBABE7128035BE487B9BDBEF89041F977A5A880000001993E08CD54D6C1230798")
+	if err != nil || !bytes.Equal(data[:n], protected) {
+		t.Fatalf("srtcp %X, %v", data[:n], err)
+	}
+
+	n, err = receiver.UnprotectRtcp(data, n)
+	if err != nil || !bytes.Equal(data[:n], plain) {
+		t.Fatalf("unprotect %X, %v", data[:n], err)
+	}
+	copy(data, protected)
+	if _, err = receiver.UnprotectRtcp(data, len(protected)); err != srtp.ErrSrtpReplay {
+		t.Fatalf("replay %v", err)
+	}
+	copy(data, protected)
+	data[len(protected)-srtp.SrtcpIndexLength-10-1] ^= 0x01
+	if _, err = receiver.UnprotectRtcp(data, len(protected)); err != srtp.ErrSrtpAuth {
+		t.Fatalf("tampered %v", err)
+	}
+}
+
+func TestSrtcpCompound(t *testing.T) {
+	masterKey := srtpTestHex(t, "E1F97A0D3E018BE0D64FA32C06DE4139")
+	masterSalt := srtpTestHex(t, "0EC675AD498AFEEBB6960B3AABE6")
+	sender, _ := srtp.NewSrtpContext(srtp.SRTP_AES128_CM_HMAC_SHA1_32, masterKey, masterSalt)
+	receiver, _ := srtp.NewSrtpContext(srtp.SRTP_AES128_CM_HMAC_SHA1_32, masterKey, masterSalt)
+
+	rr := &rtp.RtcpRR{SSRC: 0x1234, Reports: []rtp.RtcpReport{{SSRC: 0x5678, ExtSeq: 100}}}
+	bye := &rtp.RtcpBye{SSRC: []uint32{0x1234}}
+	data := make([]byte, 256)
+	n, err := rtp.RtcpCompoundSerialize([]rtp.RtcpPacket{rr, bye}, data, len(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err = sender.ProtectRtcp(data, n); err != nil {
+		t.Fatal(err)
+	}
+	if sender.RtcpOverhead() != 14 {
+		t.Fatalf("overhead %d", sender.RtcpOverhead())
+	}
+	if n, err = receiver.UnprotectRtcp(data, n); err != nil {
+		t.Fatal(err)
+	}
+	pkts, err := rtp.RtcpCompoundDeserialize(data, n)
+	if err != nil || len(pkts) != 2 || pkts[0].(*rtp.RtcpRR).Reports[0].ExtSeq != 100 {
+		t.Fatalf("compound %v, %v", pkts, err)
+	}
+}
+
+// RFC7714 16.1.1. SRTP AEAD_AES_128_GCM Encryption (p35)
+func TestSrtpAeadVector(t *testing.T) {
+	key := srtpTestHex(t, "000102030405060708090a0b0c0d0e0f")
+	salt := srtpTestHex(t, "517569642070726f2071756f")
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iv := srtp.SrtpAeadIV(salt, 0x5501a0b2, 0, 0xf17b)
+	if !bytes.Equal(iv, srtpTestHex(t, "51753c6580c2726f20718414")) {
+		t.Fatalf("iv %X", iv)
+	}
+	header := srtpTestHex(t, "8040f17b8041f8d35501a0b2")
+	plain := []byte("Gallia est omnis divisa in partes tres")
+	out := gcm.Seal(nil, iv, plain, header)
+	if !bytes.Equal(out, srtpTestHex(t, "f24de3a3fb34de6cacba861c9d7e4bcabe633bd50d294e6f42a5f47a51c7d19b36de3adf8833899d7f27beb16a9152cf765ee4390cce")) {
+		t.Fatalf("srtp %X", out)
+	}
+
+	// RFC7714 17.1.1. SRTCP AEAD_AES_128_GCM Encryption (p48)
+	rtcp := srtpTestHex(t, "81c8000d4d6172734e5450314e545032525450200000042a0000e9304c756e61deadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	iv = srtp.SrtcpAeadIV(salt, 0x4d617273, 0x5d4)
+	aad := append(append([]byte(nil), rtcp[:srtp.SrtcpHeaderLength]...), 0x80, 0x00, 0x05, 0xd4)
+	out = gcm.Seal(nil, iv, rtcp[srtp.SrtcpHeaderLength:], aad)
+	if !bytes.Equal(out, srtpTestHex(t, "63e94885dcdab67ca727d7662f6b7e997ff5c0f76c06f32dc676a5f1730d6fda4ce09b4686303ded0bb9275bc84aa45896cf4d2fc5abf87245d9eade")) {
+		t.Fatalf("srtcp %X", out)
+	}
+
+	// Protect/ProtectRtcp with the vector session keys
+	sender, err := srtp.NewSrtpContext(srtp.SRTP_AEAD_AES_128_GCM, key, salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver, _ := srtp.NewSrtpContext(srtp.SRTP_AEAD_AES_128_GCM, key, salt)
+	if err = sender.SetSessionKeys(key, salt); err != nil {
+		t.Fatal(err)
+	}
+	if err = receiver.SetSessionKeys(key, salt); err != nil {
+		t.Fatal(err)
+	}
+	packet := append(append([]byte(nil), header...), plain...)
+	data := make([]byte, len(packet)+sender.Overhead())
+	copy(data, packet)
+	n, err := sender.Protect(data, len(packet))
+	if err != nil || !bytes.Equal(data[:n], append(append([]byte(nil), header...), srtpTestHex(t, "f24de3a3fb34de6cacba861c9d7e4bcabe633bd50d294e6f42a5f47a51c7d19b36de3adf8833899d7f27beb16a9152cf765ee4390cce")...)) {
+		t.Fatalf("protect %X, %v", data[:n], err)
+	}
+	if n, err = receiver.Unprotect(data, n); err != nil || !bytes.Equal(data[:n], packet) {
+		t.Fatalf("unprotect %X, %v", data[:n], err)
+	}
+
+	sender.SetRtcpIndex(0x4d617273, 0x5d4)
+	data = make([]byte, len(rtcp)+sender.RtcpOverhead())
+	copy(data, rtcp)
+	expected := append(append(append([]byte(nil), rtcp[:srtp.SrtcpHeaderLength]...), out...), 0x80, 0x00, 0x05, 0xd4)
+	if n, err = sender.ProtectRtcp(data, len(rtcp)); err != nil || !bytes.Equal(data[:n], expected) {
+		t.Fatalf("protect rtcp %X, %v", data[:n], err)
+	}
+	if n, err = receiver.UnprotectRtcp(data, n); err != nil || !bytes.Equal(data[:n], rtcp) {
+		t.Fatalf("unprotect rtcp %X, %v", data[:n], err)
+	}
+
+	// unencrypted SRTCP(E=0), the whole RTCP packet and index are associated data
+	index := []byte{0x00, 0x00, 0x05, 0xd5}
+	tag := gcm.Seal(nil, srtp.SrtcpAeadIV(salt, 0x4d617273, 0x5d5), nil, append(append([]byte(nil), rtcp...), index...))
+	packet = append(append(append([]byte(nil), rtcp...), tag...), index...)
+	data = append([]byte(nil), packet...)
+	data[srtp.SrtcpHeaderLength] ^= 0x01
+	if _, err = receiver.UnprotectRtcp(data, len(data)); err != srtp.ErrSrtpAuth {
+		t.Fatalf("unencrypted tampered %v", err)
+	}
+	copy(data, packet)
+	if n, err = receiver.UnprotectRtcp(data, len(data)); err != nil || !bytes.Equal(data[:n], rtcp) {
+		t.Fatalf("unencrypted rtcp %X, %v", data[:n], err)
+	}
+}
+
+func TestSrtpAeadProtect(t *testing.T) {
+	masterSalt := srtpTestHex(t, "517569642070726f2071756f")
+	for _, profile := range []int{srtp.SRTP_AEAD_AES_128_GCM, srtp.SRTP_AEAD_AES_256_GCM} {
+		masterKey := make([]byte, 16)
+		if profile == srtp.SRTP_AEAD_AES_256_GCM {
+			masterKey = make([]byte, 32)
+		}
+		for i := range masterKey {
+			masterKey[i] = byte(i)
+		}
+		sender, err := srtp.NewSrtpContext(profile, masterKey, masterSalt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receiver, err := srtp.NewSrtpContext(profile, masterKey, masterSalt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = srtp.NewSrtpContext(profile, masterKey[:8], masterSalt); err == nil {
+			t.Fatal("invalid key length")
+		}
+
+		payload := []byte("srtp aead payload")
+		for _, seq := range []uint16{65535, 0} {
+			plain := srtpTestPacket(t, seq, payload)
+			data := make([]byte, len(plain)+sender.Overhead())
+			copy(data, plain)
+			n, err := sender.Protect(data, len(plain))
+			if err != nil || n != len(plain)+srtp.SrtpAeadTagLength {
+				t.Fatalf("protect %d, %v", n, err)
+			}
+			protected := append([]byte(nil), data[:n]...)
+			n, err = receiver.Unprotect(data, n)
+			if err != nil || !bytes.Equal(data[:n], plain) {
+				t.Fatalf("seq %d unprotect %v", seq, err)
+			}
+			copy(data, protected)
+			if _, err = receiver.Unprotect(data, len(protected)); err != srtp.ErrSrtpReplay {
+				t.Fatalf("replay %v", err)
+			}
+		}
+
+		// tampered, header is associated data
+		plain := srtpTestPacket(t, 1, payload)
+		data := make([]byte, len(plain)+sender.Overhead())
+		copy(data, plain)
+		n, _ := sender.Protect(data, len(plain))
+		data[1] ^= 0x80
+		if _, err = receiver.Unprotect(data, n); err != srtp.ErrSrtpAuth {
+			t.Fatalf("tampered %v", err)
+		}
+
+		plain = srtpTestHex(t, "81C8000BCAFEBABEABABABABABABABABABABABABABABABAB")
+		data = make([]byte, len(plain)+sender.RtcpOverhead())
+		copy(data, plain)
+		n, err = sender.ProtectRtcp(data, len(plain))
+		if err != nil || n != len(data) || rtp.RtpReadUint32(data[n-srtp.SrtcpIndexLength:]) != srtp.SrtcpEFlag {
+			t.Fatalf("protect rtcp %d, %v", n, err)
+		}
+		protected := append([]byte(nil), data...)
+		n, err = receiver.UnprotectRtcp(data, n)
+		if err != nil || !bytes.Equal(data[:n], plain) {
+			t.Fatalf("unprotect rtcp %X, %v", data[:n], err)
+		}
+		copy(data, protected)
+		if _, err = receiver.UnprotectRtcp(data, len(protected)); err != srtp.ErrSrtpReplay {
+			t.Fatalf("rtcp replay %v", err)
+		}
+		copy(data, protected)
+		data[len(protected)-1] ^= 0x02 // index is associated data
+		if _, err = receiver.UnprotectRtcp(data, len(protected)); err != srtp.ErrSrtpAuth {
+			t.Fatalf("rtcp tampered %v", err)
+		}
+	}
+}
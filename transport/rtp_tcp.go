@@ -0,0 +1,93 @@
+package transport
+
+import (
+	"errors"
+	"github.com/services-go/librtp/rtp"
+	"io"
+)
+
+// RFC4571 Framing Real-time Transport Protocol (RTP) and RTP Control Protocol (RTCP) Packets over Connection-Oriented Transport
+// 2. Framing Method (p3)
+/*
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   ---------------------------------------------------------------
+   |             LENGTH            |  RTP or RTCP packet ...       |
+   ---------------------------------------------------------------
+
+   The bit field (LENGTH) is an unsigned integer, most significant byte first,
+   that encodes the length of the RTP or RTCP packet that follows.
+   A LENGTH field with a value of zero indicates a zero-length packet.
+*/
+const (
+	RtpTcpHeaderLength = 2
+	RtpTcpMaxPacket    = 0xFFFF // 16-bit LENGTH field
+)
+
+// RtpTcpReader RFC4571 framed RTP/RTCP packet reader
+type RtpTcpReader struct {
+	r      io.Reader
+	header [RtpTcpHeaderLength]byte
+	buf    []byte
+}
+
+func NewRtpTcpReader(r io.Reader) *RtpTcpReader {
+	return &RtpTcpReader{r: r, buf: make([]byte, RtpTcpMaxPacket)}
+}
+
+// ReadPacket read next RTP/RTCP packet
+// @return packet data, valid until next ReadPacket call, io.EOF-end of stream, io.ErrUnexpectedEOF-truncated frame
+func (r *RtpTcpReader) ReadPacket() ([]byte, error) {
+	if _, err := io.ReadFull(r.r, r.header[:]); err != nil {
+		return nil, err
+	}
+
+	n := int(rtp.RtpReadUint16(r.header[:]))
+	if _, err := io.ReadFull(r.r, r.buf[:n]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return r.buf[:n], nil
+}
+
+// RtpTcpWriter RFC4571 framed RTP/RTCP packet writer
+type RtpTcpWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func NewRtpTcpWriter(w io.Writer) *RtpTcpWriter {
+	return &RtpTcpWriter{w: w}
+}
+
+// WritePacket write LENGTH and RTP/RTCP packet with one Write call
+// @param[in] data RTP/RTCP packet
+// @param[in] bytes packet length in bytes
+func (w *RtpTcpWriter) WritePacket(data []byte, bytes int) error {
+	if bytes < 0 || bytes > len(data) || bytes > RtpTcpMaxPacket {
+		return errors.New("rtp tcp packet length error.")
+	}
+
+	w.buf = append(w.buf[:0], byte(bytes>>8), byte(bytes))
+	w.buf = append(w.buf, data[:bytes]...)
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+// RFC5761 4. Distinguishable RTP and RTCP Packets (p5)
+/*
+   When RTP and RTCP packets are multiplexed onto a single port, the RTCP
+   packet type field occupies the same position in the packet as the
+   combination of the RTP marker (M) bit and the RTP payload type (PT).
+   This field can be used to distinguish RTP and RTCP packets when two
+   restrictions are observed: 1) the RTP payload type values used are
+   distinct from the RTCP packet types used; and 2) for each RTP payload
+   type (PT), PT+128 is distinct from the RTCP packet types used.
+*/
+
+// RtcpMuxIsRtcp return true if packet is RTCP, RTCP packet type in range 192-223
+func RtcpMuxIsRtcp(data []byte, bytes int) bool {
+	return bytes >= 2 && data[1] >= 192 && data[1] <= 223
+}
@@ -0,0 +1,201 @@
+package transport
+
+import (
+	"errors"
+	"github.com/services-go/librtp/payload"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// RFC3550 11. RTP over Network and Transport Protocols (p56)
+/*
+   For UDP and similar protocols, RTP SHOULD use an even destination port
+   number and the corresponding RTCP stream SHOULD use the next higher
+   (odd) destination port number.
+*/
+const (
+	RtpUdpMaxPacket = 1500 * 2 // receive buffer, larger than path MTU
+	rtpUdpBindRetry = 32
+)
+
+// RtpUdpHandler receive RTP/RTCP packets, never called concurrently
+type RtpUdpHandler interface {
+	OnRtp(param interface{}, packet []byte, bytes int, from net.Addr)
+	OnRtcp(param interface{}, packet []byte, bytes int, from net.Addr)
+}
+
+// RtpUdpTransport RTP/RTCP UDP socket pair, or single socket with RFC5761 rtcp-mux
+type RtpUdpTransport struct {
+	Err error // first send error of Handle
+
+	rtp        net.PacketConn
+	rtcp       net.PacketConn // nil if rtcp-mux
+	remoteRtp  net.Addr
+	remoteRtcp net.Addr
+	mutex      sync.Mutex // serialize handler callback
+}
+
+// NewRtpUdpTransport bind RTP/RTCP port pair
+// @param[in] ip local address, "" for all interfaces
+// @param[in] port local RTP port, must be even without rtcp-mux, 0 to choose an even/odd free port pair
+// @param[in] mux RFC5761 rtcp-mux, RTP and RTCP on the same port
+func NewRtpUdpTransport(ip string, port int, mux bool) (*RtpUdpTransport, error) {
+	if port < 0 || port > 0xFFFF || (!mux && port%2 != 0) {
+		return nil, errors.New("rtp port must be even.")
+	}
+
+	var err error
+	for i := 0; i < rtpUdpBindRetry; i++ {
+		t := &RtpUdpTransport{}
+		if t.rtp, err = net.ListenPacket("udp", net.JoinHostPort(ip, strconv.Itoa(port))); err != nil {
+			return nil, err
+		}
+		if mux {
+			return t, nil
+		}
+
+		p := t.rtp.LocalAddr().(*net.UDPAddr).Port
+		if p%2 == 0 && p < 0xFFFF {
+			if t.rtcp, err = net.ListenPacket("udp", net.JoinHostPort(ip, strconv.Itoa(p+1))); err == nil {
+				return t, nil
+			}
+		}
+		t.rtp.Close()
+		if port != 0 {
+			break // RTCP port in use
+		}
+	}
+	if err == nil {
+		err = errors.New("rtp no free port pair.")
+	}
+	return nil, err
+}
+
+// LocalAddr return local RTP and RTCP address, same address if rtcp-mux
+func (t *RtpUdpTransport) LocalAddr() (rtp, rtcp *net.UDPAddr) {
+	rtp = t.rtp.LocalAddr().(*net.UDPAddr)
+	if t.rtcp == nil {
+		return rtp, rtp
+	}
+	return rtp, t.rtcp.LocalAddr().(*net.UDPAddr)
+}
+
+// SetRemote set peer RTP/RTCP address, e.g. from SDP c= and m= lines
+// @param[in] rtcp peer RTCP address, nil for RTP port + 1, or RTP address if rtcp-mux
+func (t *RtpUdpTransport) SetRemote(rtp, rtcp *net.UDPAddr) {
+	if rtcp == nil {
+		rtcp = rtp
+		if t.rtcp != nil {
+			rtcp = &net.UDPAddr{IP: rtp.IP, Port: rtp.Port + 1, Zone: rtp.Zone}
+		}
+	}
+	t.remoteRtp, t.remoteRtcp = rtp, rtcp
+}
+
+// WriteRtp send RTP packet to remote RTP address
+func (t *RtpUdpTransport) WriteRtp(data []byte, bytes int) error {
+	if t.remoteRtp == nil {
+		return errors.New("rtp remote address not set.")
+	}
+	_, err := t.rtp.WriteTo(data[:bytes], t.remoteRtp)
+	return err
+}
+
+// WriteRtcp send RTCP compound packet from RTCP port(RTP port if rtcp-mux) to remote RTCP address
+func (t *RtpUdpTransport) WriteRtcp(data []byte, bytes int) error {
+	if t.remoteRtcp == nil {
+		return errors.New("rtp remote address not set.")
+	}
+	conn := t.rtcp
+	if conn == nil {
+		conn = t.rtp
+	}
+	_, err := conn.WriteTo(data[:bytes], t.remoteRtcp)
+	return err
+}
+
+// Alloc/Free/Handle RtpPayload packer handler, send packets to remote RTP address
+// e.g. payload.RtpPayloadCreate(96, "H264", seq, ssrc, 1400, transport, unpackhandler, nil)
+func (t *RtpUdpTransport) Alloc(param interface{}, bytes int) []byte {
+	return make([]byte, bytes)
+}
+
+func (t *RtpUdpTransport) Free(param interface{}, packet []byte) {
+}
+
+func (t *RtpUdpTransport) Handle(param interface{}, packet []byte, bytes int, timestamp uint32, flags int) {
+	if err := t.WriteRtp(packet, bytes); err != nil && t.Err == nil {
+		t.Err = err
+	}
+}
+
+// Serve receive packets until Close, demultiplex RTCP by payload type if rtcp-mux
+// @return nil-transport closed
+func (t *RtpUdpTransport) Serve(handler RtpUdpHandler, param interface{}) error {
+	if t.rtcp == nil {
+		return t.serve(t.rtp, true, handler, param)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- t.serve(t.rtcp, false, handler, param)
+	}()
+	err := t.serve(t.rtp, false, handler, param)
+	t.Close() // stop RTCP receiver
+	if err2 := <-done; err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (t *RtpUdpTransport) serve(conn net.PacketConn, mux bool, handler RtpUdpHandler, param interface{}) error {
+	buf := make([]byte, RtpUdpMaxPacket)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		t.mutex.Lock()
+		if conn == t.rtcp || (mux && RtcpMuxIsRtcp(buf, n)) {
+			handler.OnRtcp(param, buf, n, from)
+		} else {
+			handler.OnRtp(param, buf, n, from)
+		}
+		t.mutex.Unlock()
+	}
+}
+
+// Close close RTP/RTCP sockets, Serve return
+func (t *RtpUdpTransport) Close() error {
+	err := t.rtp.Close()
+	if t.rtcp != nil {
+		if err2 := t.rtcp.Close(); err == nil {
+			err = err2
+		}
+	}
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// RtpUdpPayloadHandler RtpUdpHandler feed RTP packets to delegate unpacker, RTCP packets to Rtcp handler
+type RtpUdpPayloadHandler struct {
+	Delegate *payload.RtpPayloadDelegate
+	Rtcp     RtpUdpHandler // optional
+}
+
+func (h *RtpUdpPayloadHandler) OnRtp(param interface{}, packet []byte, bytes int, from net.Addr) {
+	h.Delegate.RtpPayloadUnpackerInput(packet, bytes)
+}
+
+func (h *RtpUdpPayloadHandler) OnRtcp(param interface{}, packet []byte, bytes int, from net.Addr) {
+	if h.Rtcp != nil {
+		h.Rtcp.OnRtcp(param, packet, bytes, from)
+	}
+}
@@ -0,0 +1,50 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package transport
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// SetDSCP set RFC2474 Differentiated Services Code Point of RTP/RTCP sockets
+// e.g. RFC4594 EF(46) for audio, AF41(34) for video
+func (t *RtpUdpTransport) SetDSCP(dscp int) error {
+	if dscp < 0 || dscp > 63 {
+		return errors.New("dscp must be 0-63.")
+	}
+	for _, conn := range []net.PacketConn{t.rtp, t.rtcp} {
+		if conn == nil {
+			continue
+		}
+		if err := rtpUdpSetTos(conn, dscp<<2); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rtpUdpSetTos set IPv4 TOS and IPv6 Traffic Class, dual-stack socket carry both
+func rtpUdpSetTos(conn net.PacketConn, tos int) error {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return errors.New("dscp not supported.")
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var err4, err6 error
+	if err = raw.Control(func(fd uintptr) {
+		err4 = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS, tos)
+		err6 = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, tos)
+	}); err != nil {
+		return err
+	}
+	if err4 != nil && err6 != nil {
+		return err4
+	}
+	return nil
+}
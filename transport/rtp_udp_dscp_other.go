@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package transport
+
+import (
+	"errors"
+)
+
+// SetDSCP set RFC2474 Differentiated Services Code Point of RTP/RTCP sockets
+func (t *RtpUdpTransport) SetDSCP(dscp int) error {
+	return errors.New("dscp not supported.")
+}
@@ -0,0 +1,229 @@
+package transport
+
+import (
+	"bufio"
+	"errors"
+	"github.com/services-go/librtp/rtp"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// RFC2326 10.12 Embedded (Interleaved) Binary Data (p40)
+/*
+   Stream data such as RTP packets is encapsulated by an ASCII dollar
+   sign (24 hexadecimal), followed by a one-byte channel identifier,
+   followed by the length of the encapsulated binary data as a binary,
+   two-byte integer in network byte order. The stream data follows
+   immediately afterwards, without a CRLF, but including the upper-layer
+   protocol headers. Each $ block contains exactly one upper-layer
+   protocol data unit, e.g., one RTP packet.
+
+     S->C: $\000{2 byte length}{"length" bytes data, w/RTP header}
+     S->C: $\000{2 byte length}{"length" bytes data, w/RTP header}
+     S->C: $\001{2 byte length}{"length" bytes  RTCP packet}
+*/
+const (
+	RtspInterleavedMagic        = '$'
+	RtspInterleavedHeaderLength = 4
+	RtspInterleavedMessage      = -1 // ReadFrame channel of RTSP request/response
+	rtspMaxMessageLength        = 64 * 1024
+)
+
+// RtspInterleavedReader read $-framed binary data and RTSP messages from RTSP TCP connection
+type RtspInterleavedReader struct {
+	r      *bufio.Reader
+	header [RtspInterleavedHeaderLength]byte
+	buf    []byte
+}
+
+func NewRtspInterleavedReader(r io.Reader) *RtspInterleavedReader {
+	return &RtspInterleavedReader{r: bufio.NewReader(r), buf: make([]byte, RtpTcpMaxPacket)}
+}
+
+// ReadFrame read next interleaved frame or RTSP message
+// @return channel interleaved channel id, RtspInterleavedMessage-RTSP message(headers and body)
+// @return data frame data, valid until next ReadFrame call
+func (r *RtspInterleavedReader) ReadFrame() (int, []byte, error) {
+	b, err := r.r.Peek(1)
+	if err != nil {
+		return 0, nil, err
+	}
+	if b[0] != RtspInterleavedMagic {
+		data, err := r.readMessage()
+		return RtspInterleavedMessage, data, err
+	}
+
+	if _, err = io.ReadFull(r.r, r.header[:]); err != nil {
+		return 0, nil, eof(err)
+	}
+	n := int(rtp.RtpReadUint16(r.header[2:]))
+	if _, err = io.ReadFull(r.r, r.buf[:n]); err != nil {
+		return 0, nil, eof(err)
+	}
+	return int(r.header[1]), r.buf[:n], nil
+}
+
+// readMessage read RTSP request/response, body length from Content-Length header
+func (r *RtspInterleavedReader) readMessage() ([]byte, error) {
+	data := r.buf[:0]
+	length := 0
+	for {
+		line, err := r.r.ReadSlice('\n')
+		if err != nil {
+			if err == bufio.ErrBufferFull {
+				err = errors.New("rtsp header line too long.")
+			}
+			return nil, eof(err)
+		}
+		if len(data)+len(line) > rtspMaxMessageLength {
+			return nil, errors.New("rtsp message too long.")
+		}
+		data = append(data, line...)
+
+		header := strings.TrimRight(string(line), "\r\n")
+		if len(header) == 0 {
+			if len(data) == len(line) {
+				data = data[:0] // skip leading empty line
+				continue
+			}
+			break
+		}
+		if i := strings.IndexByte(header, ':'); i > 0 && strings.EqualFold(strings.TrimSpace(header[:i]), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(header[i+1:])); err != nil || length < 0 {
+				return nil, errors.New("rtsp content-length error.")
+			}
+		}
+	}
+
+	if len(data)+length > rtspMaxMessageLength {
+		return nil, errors.New("rtsp message too long.")
+	}
+	n := len(data)
+	data = append(data, make([]byte, length)...)
+	if _, err := io.ReadFull(r.r, data[n:]); err != nil {
+		return nil, eof(err)
+	}
+	r.buf = data[:cap(data)]
+	return data, nil
+}
+
+// RtspInterleavedWriter write $-framed binary data, safe to mix with RTSP messages between WriteFrame calls
+type RtspInterleavedWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func NewRtspInterleavedWriter(w io.Writer) *RtspInterleavedWriter {
+	return &RtspInterleavedWriter{w: w}
+}
+
+// WriteFrame write one interleaved frame with one Write call
+// @param[in] channel interleaved channel id, from Transport header interleaved parameter
+// @param[in] data RTP/RTCP packet
+// @param[in] bytes packet length in bytes
+func (w *RtspInterleavedWriter) WriteFrame(channel int, data []byte, bytes int) error {
+	if channel < 0 || channel > 255 {
+		return errors.New("rtsp interleaved channel error.")
+	}
+	if bytes < 0 || bytes > len(data) || bytes > RtpTcpMaxPacket {
+		return errors.New("rtsp interleaved packet length error.")
+	}
+
+	w.buf = append(w.buf[:0], RtspInterleavedMagic, byte(channel), byte(bytes>>8), byte(bytes))
+	w.buf = append(w.buf, data[:bytes]...)
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+// RtspInterleavedHandler receive demultiplexed RTP/RTCP packets and RTSP messages
+type RtspInterleavedHandler interface {
+	OnRtp(param interface{}, channel int, packet []byte, bytes int)
+	OnRtcp(param interface{}, channel int, packet []byte, bytes int)
+	OnRtsp(param interface{}, message []byte)
+}
+
+type rtspChannelPair struct {
+	rtp  int
+	rtcp int
+}
+
+// RtspInterleavedDemuxer dispatch interleaved frames by channel pairs bound from SETUP Transport header
+// e.g. Transport: RTP/AVP/TCP;unicast;interleaved=0-1 => Bind(0, 1)
+type RtspInterleavedDemuxer struct {
+	Discard int // frames on unbound channel
+
+	reader   *RtspInterleavedReader
+	channels map[int]rtspChannelPair // RTP or RTCP channel => pair
+	handler  RtspInterleavedHandler
+	param    interface{}
+}
+
+func NewRtspInterleavedDemuxer(r io.Reader, handler RtspInterleavedHandler, param interface{}) *RtspInterleavedDemuxer {
+	return &RtspInterleavedDemuxer{
+		reader:   NewRtspInterleavedReader(r),
+		channels: make(map[int]rtspChannelPair),
+		handler:  handler,
+		param:    param,
+	}
+}
+
+// Bind add RTP/RTCP channel pair, rtcpChannel == rtpChannel for RFC5761 rtcp-mux
+func (d *RtspInterleavedDemuxer) Bind(rtpChannel, rtcpChannel int) {
+	pair := rtspChannelPair{rtp: rtpChannel, rtcp: rtcpChannel}
+	d.channels[rtpChannel] = pair
+	d.channels[rtcpChannel] = pair
+}
+
+// Unbind remove channel pair by RTP channel
+func (d *RtspInterleavedDemuxer) Unbind(rtpChannel int) {
+	if pair, ok := d.channels[rtpChannel]; ok {
+		delete(d.channels, pair.rtp)
+		delete(d.channels, pair.rtcp)
+	}
+}
+
+// Input read and dispatch one frame
+// @return io.EOF-connection closed
+func (d *RtspInterleavedDemuxer) Input() error {
+	channel, data, err := d.reader.ReadFrame()
+	if err != nil {
+		return err
+	}
+
+	if channel == RtspInterleavedMessage {
+		d.handler.OnRtsp(d.param, data)
+		return nil
+	}
+
+	pair, ok := d.channels[channel]
+	if !ok {
+		d.Discard++
+		return nil
+	}
+	if channel == pair.rtcp && (pair.rtp != pair.rtcp || RtcpMuxIsRtcp(data, len(data))) {
+		d.handler.OnRtcp(d.param, channel, data, len(data))
+	} else {
+		d.handler.OnRtp(d.param, channel, data, len(data))
+	}
+	return nil
+}
+
+// Run dispatch frames until error
+func (d *RtspInterleavedDemuxer) Run() error {
+	for {
+		if err := d.Input(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func eof(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}